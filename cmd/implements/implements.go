@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cbcstars/go/internal/typeload"
+)
+
+// 接口实现关系
+/*
+1:候选类型是所有加载的包中声明的具名非接口类型，接口是方法集非空的具名接口，只做约束用的接口（含类型集）不参与。
+  泛型类型用自己的类型参数实例化（List[T]）后参与比较。泛型接口对每个候选类型单独实例化：
+  按方法签名从候选类型的同名方法推断类型实参，例如 IntList 的 Append(int) 得到 Appender[int]，
+  推断不出所有类型实参或者不满足约束时这个候选类型不参与，报告中用 as 标出实例化后的接口。
+2:T 的方法集只有值接收者的方法，*T 的方法集还包括指针接收者的方法，所以 T 实现的接口 *T 一定实现，反过来不一定。
+3:near miss：*T 的方法集中和接口相比只差一处——缺一个方法，或者有同名方法但签名不同，
+  并且至少有一个方法名对得上，避免单方法接口把所有类型都列出来。
+4:接口有未导出的方法时，其他包的类型不可能实现它，不参与比较。
+*/
+
+// Implementer 实现了接口的类型
+type Implementer struct {
+	Type string
+	Pos  token.Position
+	// Value T 本身实现了接口，这时 *T 也实现了
+	Value bool
+	// As 泛型接口实例化后的接口，例如 Appender[int]
+	As string
+}
+
+// NearMiss 只差一处就能实现接口的类型
+type NearMiss struct {
+	Type    string
+	Pos     token.Position
+	Problem string
+	As      string
+}
+
+// Report 一个接口的实现情况
+type Report struct {
+	Interface    string
+	Pos          token.Position
+	Implementers []Implementer
+	NearMisses   []NearMiss
+}
+
+type namedType struct {
+	obj   *types.TypeName
+	named *types.Named
+}
+
+// Analyze 找出 pkgs 中每个接口的实现者和 near miss，match 为 nil 时报告所有接口
+func Analyze(fset *token.FileSet, pkgs []*typeload.Package, match func(iface *types.TypeName) bool) []Report {
+	var ifaces, candidates []namedType
+	ctxt := types.NewContext()
+	for _, p := range pkgs {
+		if p.Types == nil {
+			continue
+		}
+		scope := p.Types.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			if it, ok := named.Underlying().(*types.Interface); ok {
+				if it.NumMethods() > 0 && it.IsMethodSet() && (match == nil || match(obj)) {
+					ifaces = append(ifaces, namedType{obj, named})
+				}
+				continue
+			}
+			if tparams := named.TypeParams(); tparams.Len() > 0 {
+				args := make([]types.Type, tparams.Len())
+				for i := range args {
+					args[i] = tparams.At(i)
+				}
+				inst, err := types.Instantiate(ctxt, named, args, false)
+				if err != nil {
+					continue
+				}
+				named = inst.(*types.Named)
+			}
+			candidates = append(candidates, namedType{obj, named})
+		}
+	}
+
+	var reports []Report
+	for _, iface := range ifaces {
+		generic := iface.named.Underlying().(*types.Interface)
+		r := Report{Interface: qualifiedName(iface.obj), Pos: fset.Position(iface.obj.Pos())}
+		for _, c := range candidates {
+			if c.obj.Pkg() != iface.obj.Pkg() && hasUnexported(generic) {
+				continue
+			}
+			it, as := generic, ""
+			if iface.named.TypeParams().Len() > 0 {
+				inst, ok := instantiateFor(ctxt, iface.named, c.named)
+				if !ok {
+					continue
+				}
+				it = inst.Underlying().(*types.Interface)
+				as = types.TypeString(inst, types.RelativeTo(iface.obj.Pkg()))
+			}
+			switch {
+			case types.Implements(c.named, it):
+				r.Implementers = append(r.Implementers, Implementer{Type: qualifiedName(c.obj), Pos: fset.Position(c.obj.Pos()), Value: true, As: as})
+			case types.Implements(types.NewPointer(c.named), it):
+				r.Implementers = append(r.Implementers, Implementer{Type: qualifiedName(c.obj), Pos: fset.Position(c.obj.Pos()), As: as})
+			default:
+				if problem, ok := nearMiss(c.named, it); ok {
+					r.NearMisses = append(r.NearMisses, NearMiss{Type: qualifiedName(c.obj), Pos: fset.Position(c.obj.Pos()), Problem: problem, As: as})
+				}
+			}
+		}
+		reports = append(reports, r)
+	}
+	slices.SortFunc(reports, func(a, b Report) int { return strings.Compare(a.Interface, b.Interface) })
+	return reports
+}
+
+// nearMiss 比较 *T 的方法集和接口，只差一处时返回这一处的说明
+func nearMiss(t types.Type, it *types.Interface) (string, bool) {
+	ptr := types.NewPointer(t)
+	var problems []string
+	named := 0
+	for i := 0; i < it.NumMethods(); i++ {
+		m := it.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(ptr, false, m.Pkg(), m.Name())
+		switch obj := obj.(type) {
+		case nil:
+			problems = append(problems, fmt.Sprintf("missing method %s%s", m.Name(), signature(m)))
+		case *types.Func:
+			named++
+			if !types.Identical(obj.Type(), m.Type()) {
+				problems = append(problems, fmt.Sprintf("wrong signature for %s: have %s%s, want %s%s",
+					m.Name(), m.Name(), signature(obj), m.Name(), signature(m)))
+			}
+		default:
+			named++
+			problems = append(problems, fmt.Sprintf("%s is a field, not a method", m.Name()))
+		}
+	}
+	if len(problems) != 1 || named == 0 {
+		return "", false
+	}
+	return problems[0], true
+}
+
+// instantiateFor 从 t 的同名方法推断泛型接口的类型实参并实例化，
+// 推断不出所有类型实参或者实参不满足约束时返回 false
+func instantiateFor(ctxt *types.Context, generic *types.Named, t types.Type) (*types.Named, bool) {
+	tparams := generic.TypeParams()
+	u := unifier{index: make(map[*types.TypeParam]int), args: make([]types.Type, tparams.Len())}
+	for i := 0; i < tparams.Len(); i++ {
+		u.index[tparams.At(i)] = i
+	}
+	it := generic.Underlying().(*types.Interface)
+	ptr := types.NewPointer(t)
+	for i := 0; i < it.NumMethods(); i++ {
+		m := it.Method(i)
+		if obj, _, _ := types.LookupFieldOrMethod(ptr, false, m.Pkg(), m.Name()); obj != nil {
+			if f, ok := obj.(*types.Func); ok {
+				u.unify(m.Type(), f.Type())
+			}
+		}
+	}
+	if slices.Contains(u.args, nil) {
+		return nil, false
+	}
+	inst, err := types.Instantiate(ctxt, generic, u.args, true)
+	if err != nil {
+		return nil, false
+	}
+	return inst.(*types.Named), true
+}
+
+// unifier 按结构比较接口方法和候选方法的签名，把接口的类型参数绑定到候选方法中对应位置的类型
+type unifier struct {
+	index map[*types.TypeParam]int
+	args  []types.Type
+}
+
+// unify 结构不一致时返回 false，已经绑定的参数不会被覆盖；是否真的实现由 types.Implements 判断
+func (u *unifier) unify(x, y types.Type) bool {
+	if tp, ok := x.(*types.TypeParam); ok {
+		if i, ok := u.index[tp]; ok {
+			if u.args[i] == nil {
+				u.args[i] = y
+				return true
+			}
+			return types.Identical(u.args[i], y)
+		}
+	}
+	switch x := x.(type) {
+	case *types.Pointer:
+		y, ok := y.(*types.Pointer)
+		return ok && u.unify(x.Elem(), y.Elem())
+	case *types.Slice:
+		y, ok := y.(*types.Slice)
+		return ok && u.unify(x.Elem(), y.Elem())
+	case *types.Array:
+		y, ok := y.(*types.Array)
+		return ok && x.Len() == y.Len() && u.unify(x.Elem(), y.Elem())
+	case *types.Map:
+		y, ok := y.(*types.Map)
+		return ok && u.unify(x.Key(), y.Key()) && u.unify(x.Elem(), y.Elem())
+	case *types.Chan:
+		y, ok := y.(*types.Chan)
+		return ok && x.Dir() == y.Dir() && u.unify(x.Elem(), y.Elem())
+	case *types.Signature:
+		y, ok := y.(*types.Signature)
+		return ok && x.Variadic() == y.Variadic() && u.unify(x.Params(), y.Params()) && u.unify(x.Results(), y.Results())
+	case *types.Tuple:
+		y, ok := y.(*types.Tuple)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !u.unify(x.At(i).Type(), y.At(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Named:
+		y, ok := y.(*types.Named)
+		if !ok || x.Origin() != y.Origin() || x.TypeArgs().Len() != y.TypeArgs().Len() {
+			return false
+		}
+		for i := 0; i < x.TypeArgs().Len(); i++ {
+			if !u.unify(x.TypeArgs().At(i), y.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func hasUnexported(it *types.Interface) bool {
+	for i := 0; i < it.NumMethods(); i++ {
+		m := it.Method(i)
+		if !m.Exported() {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedName 包名.类型名
+func qualifiedName(obj *types.TypeName) string {
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// signature 去掉 func 关键字的签名，类型用包名限定
+func signature(f *types.Func) string {
+	s := types.TypeString(f.Type(), (*types.Package).Name)
+	return strings.TrimPrefix(s, "func")
+}
+
+// WriteText 按接口分组输出，路径相对于 base
+func WriteText(w io.Writer, reports []Report, base string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s (%s)\n", r.Interface, relPos(r.Pos, base))
+		if len(r.Implementers) == 0 {
+			fmt.Fprintln(tw, "  no implementations")
+		}
+		for _, impl := range r.Implementers {
+			recv := "*T only"
+			if impl.Value {
+				recv = "T, *T"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", withAs(impl.Type, impl.As), recv, relPos(impl.Pos, base))
+		}
+		if len(r.NearMisses) > 0 {
+			fmt.Fprintln(tw, "  near misses:")
+		}
+		for _, nm := range r.NearMisses {
+			fmt.Fprintf(tw, "    %s\t%s\t%s\n", withAs(nm.Type, nm.As), nm.Problem, relPos(nm.Pos, base))
+		}
+	}
+	return tw.Flush()
+}
+
+// withAs 泛型接口的实现者后面加上实例化后的接口
+func withAs(typ, as string) string {
+	if as == "" {
+		return typ
+	}
+	return typ + " as " + as
+}
+
+func relPos(pos token.Position, base string) string {
+	if rel, err := filepath.Rel(base, pos.Filename); err == nil {
+		pos.Filename = filepath.ToSlash(rel)
+	}
+	return pos.String()
+}
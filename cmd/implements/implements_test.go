@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cbcstars/go/internal/typeload"
+)
+
+var updateGolden = flag.Bool("update", false, "重新生成 testdata 中的 golden 文件")
+
+func TestImplementsGolden(t *testing.T) {
+	dir := filepath.Join("testdata", "ducks")
+	fset, pkgs, err := typeload.Load(typeload.Config{Dir: dir}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	base, _ := filepath.Abs(dir)
+	if err := WriteText(&buf, Analyze(fset, pkgs, nil), base); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", "ducks.golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output differs from %s:\n%s", path, buf.Bytes())
+	}
+}
+
+// 在本仓库的 demo 包上运行，包括只在测试文件里声明的 IDuck
+func TestImplementsRepo(t *testing.T) {
+	fset, pkgs, err := typeload.Load(typeload.Config{Dir: "../..", Tests: true}, "./demo10_method", "./demo11_interface")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pkgs {
+		for _, err := range p.Errors {
+			t.Errorf("%s: %v", p.ImportPath, err)
+		}
+	}
+	reports := Analyze(fset, pkgs, matchNames("IDuck,demo10_method.Engine,File,Appender"))
+	want := map[string]map[string]bool{
+		"demo11_interface.IDuck": {"demo11_interface.Bird": true},
+		"demo10_method.Engine":   {"demo10_method.Car": true},
+		"demo11_interface.File":  {"demo11_interface.Word": false, "demo11_interface.memFile": false},
+		// 泛型接口按每个类型的方法推断类型实参
+		"demo11_interface.Appender": {"demo11_interface.IntList": false, "demo11_interface.List": false,
+			"demo11_interface.LockedList": false, "demo11_interface.SingleProducerList": false},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("got %d reports, want %d", len(reports), len(want))
+	}
+	for _, r := range reports {
+		impls := want[r.Interface]
+		if len(r.Implementers) != len(impls) {
+			t.Errorf("%s: implementers %+v", r.Interface, r.Implementers)
+			continue
+		}
+		for _, impl := range r.Implementers {
+			if value, ok := impls[impl.Type]; !ok || value != impl.Value {
+				t.Errorf("%s: unexpected %+v", r.Interface, impl)
+			}
+		}
+	}
+}
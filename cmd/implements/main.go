@@ -0,0 +1,64 @@
+// implements 列出模块中每个接口的实现类型
+/*
+用法：
+
+	go run ./cmd/implements [-C dir] [-iface IDuck,Shaper] [-tests=false] [packages]
+
+1:packages 是 go list 的包模式，默认是 ./...；-C 指定运行 go list 的目录。
+2:-iface 只报告这些接口，可以写 Name 或者 pkg.Name，用逗号分隔。
+3:默认包含测试文件里声明的接口和类型（例如 interface_test.go 里的 IDuck），-tests=false 只看正式代码。
+4:完全离线运行，不依赖 golang.org/x/tools，加载方式见 internal/typeload。
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cbcstars/go/internal/typeload"
+)
+
+func main() {
+	dir := flag.String("C", ".", "运行 go list 的目录")
+	ifaces := flag.String("iface", "", "只报告这些接口，逗号分隔")
+	tests := flag.Bool("tests", true, "包含测试文件")
+	flag.Parse()
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	fset, pkgs, err := typeload.Load(typeload.Config{Dir: *dir, Tests: *tests}, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "implements:", err)
+		os.Exit(1)
+	}
+	for _, p := range pkgs {
+		for _, err := range p.Errors {
+			fmt.Fprintf(os.Stderr, "implements: warning: %v\n", err)
+		}
+	}
+
+	base, _ := filepath.Abs(*dir)
+	reports := Analyze(fset, pkgs, matchNames(*ifaces))
+	if err := WriteText(os.Stdout, reports, base); err != nil {
+		fmt.Fprintln(os.Stderr, "implements:", err)
+		os.Exit(1)
+	}
+}
+
+// matchNames 按名字或者 包名.名字 过滤接口，list 为空时不过滤
+func matchNames(list string) func(*types.TypeName) bool {
+	if list == "" {
+		return nil
+	}
+	names := strings.Split(list, ",")
+	return func(obj *types.TypeName) bool {
+		return slices.Contains(names, obj.Name()) || slices.Contains(names, obj.Pkg().Name()+"."+obj.Name())
+	}
+}
@@ -0,0 +1,53 @@
+package ducks
+
+import "io"
+
+type IDuck interface {
+	Quack()
+	Walk()
+}
+
+// Bird 值接收者，T 和 *T 都实现 IDuck
+type Bird struct{}
+
+func (Bird) Quack() {}
+func (Bird) Walk()  {}
+
+// Duckling 嵌入 Bird，方法被提升
+type Duckling struct {
+	Bird
+}
+
+// Dog 缺少 Walk
+type Dog struct{}
+
+func (Dog) Quack() {}
+
+// Toy Quack 的签名不对
+type Toy struct{}
+
+func (Toy) Quack(times int) {}
+func (Toy) Walk()           {}
+
+// Statue 同名的字段不是方法
+type Statue struct {
+	Walk func()
+}
+
+func (Statue) Quack() {}
+
+// Stone 没有任何相关的方法，不报告
+type Stone struct{}
+
+type Closer interface {
+	io.Closer
+}
+
+type file struct{}
+
+func (*file) Close() error { return nil }
+
+// secret 有未导出的方法，其他包的类型不参与比较
+type secret interface {
+	hidden()
+}
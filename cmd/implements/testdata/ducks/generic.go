@@ -0,0 +1,28 @@
+package ducks
+
+// Pusher 泛型接口，按候选类型的方法推断类型实参
+type Pusher[T any] interface {
+	Push(T)
+}
+
+// Numbered 类型参数有约束，推断出的实参不满足约束的类型不参与
+type Numbered[T int | float64] interface {
+	Push(T)
+}
+
+// Stack 泛型类型，*Stack[T] 实现 Pusher[T]
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+
+// Names 实现 Pusher[string]，string 不满足 Numbered 的约束
+type Names []string
+
+func (Names) Push(string) {}
+
+// Ints 实现 Pusher[int] 和 Numbered[int]
+type Ints []int
+
+func (*Ints) Push(int) {}
@@ -0,0 +1,3 @@
+module example.com/ducks
+
+go 1.24
@@ -0,0 +1,19 @@
+package robot
+
+// Robot 指针接收者，只有 *Robot 实现 ducks.IDuck
+type Robot struct{}
+
+func (*Robot) Quack() {}
+func (*Robot) Walk()  {}
+
+func (*Robot) hidden() {}
+
+// Stopper 另一个包里的接口
+type Stopper interface {
+	Stop() error
+}
+
+// Engine Stop 没有返回值
+type Engine struct{}
+
+func (Engine) Stop() {}
@@ -0,0 +1,80 @@
+// methodset 解释类型 T 和 *T 能否赋值给某个接口
+/*
+用法：
+
+	go run ./cmd/methodset [-C dir] [-json] [-tests=false] package Type Interface
+
+1:package 是 go list 能识别的一个包，例如 ./demo11_interface；Type 和 Interface 是在这个包里求值的类型表达式，
+  可以是 List、*List、Stack[int]、io.Writer（包里有文件导入了 io）或者加载的其他包中的 pkg.Name。
+2:输出 T 和 *T 的方法集、挡住赋值的方法和修改建议；-json 输出同样的内容给编辑器使用。
+3:T 和 *T 都不能赋值给接口时退出码是 1，参数或者加载出错时是 2。
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cbcstars/go/internal/typeload"
+)
+
+func main() {
+	dir := flag.String("C", ".", "运行 go list 的目录")
+	asJSON := flag.Bool("json", false, "输出 JSON")
+	tests := flag.Bool("tests", true, "包含测试文件")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: methodset [-C dir] [-json] [-tests=false] package Type Interface")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	diag, err := run(*dir, *tests, flag.Arg(0), flag.Arg(1), flag.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "methodset:", err)
+		os.Exit(2)
+	}
+	write := WriteText
+	if *asJSON {
+		write = WriteJSON
+	}
+	if err := write(os.Stdout, diag); err != nil {
+		fmt.Fprintln(os.Stderr, "methodset:", err)
+		os.Exit(2)
+	}
+	for _, r := range diag.Results {
+		if r.Assignable {
+			return
+		}
+	}
+	os.Exit(1)
+}
+
+func run(dir string, tests bool, pattern, typeExpr, ifaceExpr string) (*Diagnosis, error) {
+	fset, pkgs, err := typeload.Load(typeload.Config{Dir: dir, Tests: tests}, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 || pkgs[0].Types == nil {
+		return nil, fmt.Errorf("no package matches %s", pattern)
+	}
+	pkg := pkgs[0]
+	for _, err := range pkg.Errors {
+		fmt.Fprintf(os.Stderr, "methodset: warning: %v\n", err)
+	}
+	typ, err := Resolve(fset, pkg, pkgs, typeExpr)
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %w", typeExpr, err)
+	}
+	iface, err := Resolve(fset, pkg, pkgs, ifaceExpr)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", ifaceExpr, err)
+	}
+	base, _ := filepath.Abs(dir)
+	return Diagnose(fset, pkg.Types, base, typ, iface)
+}
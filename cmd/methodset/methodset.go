@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/cbcstars/go/internal/typeload"
+)
+
+// 方法集诊断
+/*
+1:T 的方法集只包含值接收者的方法（以及通过嵌入提升的值方法），*T 的方法集包含全部方法。
+  v.Append(1) 能编译是因为 v 可寻址，编译器自动取了 &v；赋值给接口时没有这一步，只看方法集。
+2:对 T 和 *T 分别检查接口的每个方法，给出挡住赋值的原因：
+  pointer-receiver（只在 *T 的方法集中）、missing（没有这个方法）、wrong-signature（签名不同）、
+  field（同名的是字段）、ambiguous（同一深度嵌入了两个同名方法）。
+3:每个问题附带修改建议和源码位置，JSON 输出给编辑器使用。
+*/
+
+// 问题的种类
+const (
+	ProblemPointerReceiver = "pointer-receiver"
+	ProblemMissing         = "missing"
+	ProblemWrongSignature  = "wrong-signature"
+	ProblemField           = "field"
+	ProblemAmbiguous       = "ambiguous"
+)
+
+// Method 方法集中的一个方法
+type Method struct {
+	Name            string `json:"name"`
+	Signature       string `json:"signature"`
+	PointerReceiver bool   `json:"pointerReceiver"`
+	// EmbeddedVia 通过嵌入字段提升时的字段路径，例如 Mutex
+	EmbeddedVia string `json:"embeddedVia,omitempty"`
+	Pos         string `json:"pos,omitempty"`
+}
+
+// MethodSet 一个类型的方法集
+type MethodSet struct {
+	Type    string   `json:"type"`
+	Methods []Method `json:"methods"`
+}
+
+// Problem 挡住赋值的一个方法
+type Problem struct {
+	Method string `json:"method"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix"`
+	Pos    string `json:"pos,omitempty"`
+}
+
+// Assignability T 或者 *T 能否赋值给接口
+type Assignability struct {
+	Type       string    `json:"type"`
+	Assignable bool      `json:"assignable"`
+	Problems   []Problem `json:"problems,omitempty"`
+}
+
+// Diagnosis 类型和接口的完整诊断
+type Diagnosis struct {
+	Type       string          `json:"type"`
+	Interface  string          `json:"interface"`
+	MethodSets []MethodSet     `json:"methodSets"`
+	Results    []Assignability `json:"results"`
+	// Explanation 和 Suggestion 是给人看的总结
+	Explanation string `json:"explanation"`
+	Suggestion  string `json:"suggestion,omitempty"`
+}
+
+type diagnoser struct {
+	fset *token.FileSet
+	pkg  *types.Package
+	base string
+}
+
+func (d *diagnoser) qualifier(p *types.Package) string {
+	if p == d.pkg {
+		return ""
+	}
+	return p.Name()
+}
+
+func (d *diagnoser) typeString(t types.Type) string {
+	return types.TypeString(t, d.qualifier)
+}
+
+func (d *diagnoser) pos(p token.Pos) string {
+	if !p.IsValid() {
+		return ""
+	}
+	pos := d.fset.Position(p)
+	if rel, err := filepath.Rel(d.base, pos.Filename); err == nil {
+		pos.Filename = filepath.ToSlash(rel)
+	}
+	return pos.String()
+}
+
+// signature 去掉 func 关键字的签名
+func (d *diagnoser) signature(t types.Type) string {
+	return strings.TrimPrefix(d.typeString(t), "func")
+}
+
+// Diagnose 检查 typ 和 *typ（typ 本身是指针时只检查它）能否赋值给 iface
+func Diagnose(fset *token.FileSet, pkg *types.Package, base string, typ, iface types.Type) (*Diagnosis, error) {
+	it, ok := iface.Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", types.TypeString(iface, types.RelativeTo(pkg)))
+	}
+	d := &diagnoser{fset: fset, pkg: pkg, base: base}
+	diag := &Diagnosis{Type: d.typeString(typ), Interface: d.typeString(iface)}
+
+	recvs := []types.Type{typ}
+	if _, isPtr := typ.(*types.Pointer); !isPtr && !types.IsInterface(typ) {
+		recvs = append(recvs, types.NewPointer(typ))
+	}
+	for _, recv := range recvs {
+		diag.MethodSets = append(diag.MethodSets, d.methodSet(recv))
+		diag.Results = append(diag.Results, d.check(recv, it))
+	}
+	d.summarize(diag)
+	return diag, nil
+}
+
+func (d *diagnoser) methodSet(t types.Type) MethodSet {
+	ms := MethodSet{Type: d.typeString(t), Methods: []Method{}}
+	mset := types.NewMethodSet(t)
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		fn := sel.Obj().(*types.Func)
+		sig := fn.Type().(*types.Signature)
+		m := Method{Name: fn.Name(), Signature: d.signature(sel.Type()), Pos: d.pos(fn.Pos())}
+		if sig.Recv() != nil {
+			_, m.PointerReceiver = sig.Recv().Type().(*types.Pointer)
+		}
+		m.EmbeddedVia = embeddedPath(t, sel.Index())
+		ms.Methods = append(ms.Methods, m)
+	}
+	return ms
+}
+
+// embeddedPath 沿着选择器下标找到提升方法经过的嵌入字段
+func embeddedPath(t types.Type, index []int) string {
+	var names []string
+	for _, i := range index[:len(index)-1] {
+		if p, ok := t.Underlying().(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		s, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		f := s.Field(i)
+		names = append(names, f.Name())
+		t = f.Type()
+	}
+	return strings.Join(names, ".")
+}
+
+// check 按接口的方法逐个检查 recv 的方法集
+func (d *diagnoser) check(recv types.Type, it *types.Interface) Assignability {
+	a := Assignability{Type: d.typeString(recv)}
+	mset := types.NewMethodSet(recv)
+	base := recv
+	if p, ok := recv.(*types.Pointer); ok {
+		base = p.Elem()
+	}
+	for i := 0; i < it.NumMethods(); i++ {
+		m := it.Method(i)
+		want := d.signature(m.Type())
+		if sel := mset.Lookup(m.Pkg(), m.Name()); sel != nil {
+			if !types.Identical(sel.Type(), m.Type()) {
+				a.Problems = append(a.Problems, Problem{
+					Method: m.Name(),
+					Kind:   ProblemWrongSignature,
+					Detail: fmt.Sprintf("%s has signature %s%s, interface wants %s%s", m.Name(), m.Name(), d.signature(sel.Type()), m.Name(), want),
+					Fix:    fmt.Sprintf("change the signature to %s%s", m.Name(), want),
+					Pos:    d.pos(sel.Obj().Pos()),
+				})
+			}
+			continue
+		}
+
+		obj, index, _ := types.LookupFieldOrMethod(types.NewPointer(base), false, m.Pkg(), m.Name())
+		switch obj := obj.(type) {
+		case *types.Func:
+			p := Problem{Method: m.Name(), Pos: d.pos(obj.Pos())}
+			if !types.Identical(obj.Type(), m.Type()) {
+				p.Kind = ProblemWrongSignature
+				p.Detail = fmt.Sprintf("%s has signature %s%s, interface wants %s%s", m.Name(), m.Name(), d.signature(obj.Type()), m.Name(), want)
+				p.Fix = fmt.Sprintf("change the signature to %s%s", m.Name(), want)
+			} else {
+				p.Kind = ProblemPointerReceiver
+				p.Detail = fmt.Sprintf("%s has a pointer receiver, so it is in the method set of %s but not of %s",
+					m.Name(), d.typeString(types.NewPointer(base)), d.typeString(base))
+				p.Fix = fmt.Sprintf("use a %s (take the address: &v), or give %s a value receiver if it does not need to modify the receiver",
+					d.typeString(types.NewPointer(base)), m.Name())
+			}
+			a.Problems = append(a.Problems, p)
+		case *types.Var:
+			a.Problems = append(a.Problems, Problem{
+				Method: m.Name(),
+				Kind:   ProblemField,
+				Detail: fmt.Sprintf("%s is a field of %s, not a method", m.Name(), d.typeString(base)),
+				Fix:    fmt.Sprintf("rename the field %s and add the method %s", m.Name(), d.stub(base, m)),
+				Pos:    d.pos(obj.Pos()),
+			})
+		default:
+			p := Problem{Method: m.Name(), Kind: ProblemMissing, Pos: d.pos(typePos(base))}
+			if index != nil {
+				p.Kind = ProblemAmbiguous
+				p.Detail = fmt.Sprintf("%s is promoted from more than one embedded field at the same depth", m.Name())
+				p.Fix = fmt.Sprintf("declare %s on %s to choose one", d.stub(base, m), d.typeString(base))
+			} else {
+				p.Detail = fmt.Sprintf("%s has no method %s", d.typeString(base), m.Name())
+				p.Fix = fmt.Sprintf("add the method %s", d.stub(base, m))
+			}
+			a.Problems = append(a.Problems, p)
+		}
+	}
+	a.Assignable = len(a.Problems) == 0
+	return a
+}
+
+// stub 给 t 加上方法 m 的声明，已有的方法都是指针接收者时也用指针接收者
+func (d *diagnoser) stub(t types.Type, m *types.Func) string {
+	recv := "x " + d.receiverType(t)
+	if pointerReceivers(t) {
+		recv = "x *" + d.receiverType(t)
+	}
+	return fmt.Sprintf("func (%s) %s%s", recv, m.Name(), d.signature(m.Type()))
+}
+
+// receiverType 接收者里写的类型名，泛型类型写类型参数而不是实参
+func (d *diagnoser) receiverType(t types.Type) string {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return d.typeString(t)
+	}
+	origin := named.Origin()
+	name := origin.Obj().Name()
+	if tps := origin.TypeParams(); tps.Len() > 0 {
+		params := make([]string, tps.Len())
+		for i := range params {
+			params[i] = tps.At(i).Obj().Name()
+		}
+		name += "[" + strings.Join(params, ", ") + "]"
+	}
+	return name
+}
+
+// pointerReceivers t 声明的方法是否都是指针接收者（至少有一个）
+func pointerReceivers(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.NumMethods() == 0 {
+		return false
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		if _, ok := named.Method(i).Type().(*types.Signature).Recv().Type().(*types.Pointer); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func typePos(t types.Type) token.Pos {
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Pos()
+	}
+	return token.NoPos
+}
+
+// summarize 用一两句话说明结论
+func (d *diagnoser) summarize(diag *Diagnosis) {
+	byValue := diag.Results[0]
+	switch {
+	case byValue.Assignable && len(diag.Results) == 2:
+		diag.Explanation = fmt.Sprintf("both %s and %s implement %s.", diag.Results[0].Type, diag.Results[1].Type, diag.Interface)
+	case byValue.Assignable:
+		diag.Explanation = fmt.Sprintf("%s implements %s.", byValue.Type, diag.Interface)
+	case len(diag.Results) == 2 && diag.Results[1].Assignable:
+		var methods []string
+		for _, p := range byValue.Problems {
+			methods = append(methods, p.Method)
+		}
+		diag.Explanation = fmt.Sprintf("%s implements %s but %s does not: %s %s a pointer receiver. "+
+			"Calling v.%s() on an addressable variable works because Go takes &v automatically, "+
+			"but converting a %s value to an interface does not.",
+			diag.Results[1].Type, diag.Interface, byValue.Type, strings.Join(methods, ", "), hasHave(len(methods)), methods[0], byValue.Type)
+		receivers := "a value receiver if it does"
+		if len(methods) > 1 {
+			receivers = "value receivers if they do"
+		}
+		diag.Suggestion = fmt.Sprintf("pass &v (a %s) where %s is expected, or give %s %s not modify the receiver.",
+			diag.Results[1].Type, diag.Interface, strings.Join(methods, ", "), receivers)
+	default:
+		last := diag.Results[len(diag.Results)-1]
+		diag.Explanation = fmt.Sprintf("neither %s implements %s; %d method(s) block assignment even through a pointer.",
+			joinTypes(diag.Results), diag.Interface, len(last.Problems))
+		var fixes []string
+		for _, p := range last.Problems {
+			fixes = append(fixes, p.Fix)
+		}
+		diag.Suggestion = strings.Join(fixes, "; ") + "."
+	}
+}
+
+func hasHave(n int) string {
+	if n == 1 {
+		return "has"
+	}
+	return "have"
+}
+
+func joinTypes(results []Assignability) string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Type
+	}
+	return strings.Join(names, " nor ")
+}
+
+// WriteText 给人看的输出
+func WriteText(w io.Writer, diag *Diagnosis) error {
+	fmt.Fprintf(w, "type:      %s\ninterface: %s\n", diag.Type, diag.Interface)
+	for _, ms := range diag.MethodSets {
+		fmt.Fprintf(w, "\nmethod set of %s:\n", ms.Type)
+		if len(ms.Methods) == 0 {
+			fmt.Fprintln(w, "  (empty)")
+		}
+		for _, m := range ms.Methods {
+			recv := "value receiver"
+			if m.PointerReceiver {
+				recv = "pointer receiver"
+			}
+			if m.EmbeddedVia != "" {
+				recv += ", promoted from " + m.EmbeddedVia
+			}
+			fmt.Fprintf(w, "  %s%s  (%s)\n", m.Name, m.Signature, recv)
+		}
+	}
+	fmt.Fprintln(w)
+	for _, r := range diag.Results {
+		if r.Assignable {
+			fmt.Fprintf(w, "ok   %s implements %s\n", r.Type, diag.Interface)
+			continue
+		}
+		fmt.Fprintf(w, "FAIL %s does not implement %s\n", r.Type, diag.Interface)
+		for _, p := range r.Problems {
+			fmt.Fprintf(w, "     %s [%s] %s\n", p.Pos, p.Kind, p.Detail)
+			fmt.Fprintf(w, "     fix: %s\n", p.Fix)
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n", diag.Explanation)
+	if diag.Suggestion != "" {
+		fmt.Fprintf(w, "suggestion: %s\n", diag.Suggestion)
+	}
+	return nil
+}
+
+// WriteJSON 给编辑器使用的输出
+func WriteJSON(w io.Writer, diag *Diagnosis) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(diag)
+}
+
+// Resolve 在 pkg 中求值类型表达式，例如 List、*List、Stack[int]、io.Writer（pkg 的某个文件导入了 io）
+// 或者 otherpkg.Name（otherpkg 是加载的另一个包）
+func Resolve(fset *token.FileSet, pkg *typeload.Package, all []*typeload.Package, expr string) (types.Type, error) {
+	tv, err := types.Eval(fset, pkg.Types, token.NoPos, expr)
+	if err == nil && tv.IsType() {
+		return tv.Type, nil
+	}
+	// 在每个文件的作用域里再试一次，可以使用文件导入的包
+	for _, f := range pkg.Files {
+		if tv, ferr := types.Eval(fset, pkg.Types, fileScopePos(f), expr); ferr == nil && tv.IsType() {
+			return tv.Type, nil
+		}
+	}
+	stripped := strings.TrimPrefix(expr, "*")
+	if name, rest, ok := strings.Cut(stripped, "."); ok {
+		for _, other := range all {
+			if other.Name == name && other.Types != nil {
+				if tv, oerr := types.Eval(fset, other.Types, token.NoPos, rest); oerr == nil && tv.IsType() {
+					if stripped != expr {
+						return types.NewPointer(tv.Type), nil
+					}
+					return tv.Type, nil
+				}
+			}
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%s is not a type", expr)
+	}
+	return nil, err
+}
+
+// fileScopePos 文件作用域内、不在任何声明里的位置
+func fileScopePos(f *ast.File) token.Pos {
+	return f.Name.End()
+}
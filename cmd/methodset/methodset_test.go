@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "重新生成 testdata 中的 golden 文件")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s", path, got)
+	}
+}
+
+func TestMethodSetText(t *testing.T) {
+	dir := filepath.Join("testdata", "demo6")
+	pairs := [][2]string{
+		{"List", "Appender"},
+		{"List", "Lener"},
+		{"*List", "Appender"},
+		{"Counter", "Resetter"},
+		{"Locked", "sync.Locker"},
+		{"Stack[int]", "Pusher[int]"},
+		{"Shadow", "Lener"},
+	}
+	var buf bytes.Buffer
+	for _, pair := range pairs {
+		diag, err := run(dir, true, ".", pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("%v: %v", pair, err)
+		}
+		buf.WriteString("=== " + pair[0] + " " + pair[1] + "\n")
+		WriteText(&buf, diag)
+	}
+	checkGolden(t, "demo6.golden", buf.Bytes())
+}
+
+func TestMethodSetJSON(t *testing.T) {
+	diag, err := run(filepath.Join("testdata", "demo6"), true, ".", "List", "Appender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, diag); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "list_appender.json", buf.Bytes())
+
+	var decoded Diagnosis
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	r := decoded.Results
+	if len(r) != 2 || r[0].Assignable || !r[1].Assignable || r[0].Problems[0].Kind != ProblemPointerReceiver {
+		t.Errorf("results = %+v", r)
+	}
+}
+
+func TestMethodSetErrors(t *testing.T) {
+	dir := filepath.Join("testdata", "demo6")
+	for _, pair := range [][2]string{
+		{"Nope", "Lener"},
+		{"List", "Counter"},
+		{"List", "io.Writer"},
+	} {
+		if _, err := run(dir, true, ".", pair[0], pair[1]); err == nil {
+			t.Errorf("%v: want error", pair)
+		}
+	}
+}
+
+// 本仓库的 Demo6：IntList 的值只实现 Lener，List[int] 的方法都是指针接收者
+func TestMethodSetRepo(t *testing.T) {
+	diag, err := run("../..", true, "./demo11_interface", "List[int]", "Appender[int]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diag.Results[0].Assignable || !diag.Results[1].Assignable {
+		t.Errorf("results = %+v", diag.Results)
+	}
+	for _, tt := range []struct {
+		iface string
+		value bool
+	}{{"Appender[int]", false}, {"Lener", true}} {
+		diag, err = run("../..", true, "./demo11_interface", "IntList", tt.iface)
+		if err != nil || diag.Results[0].Assignable != tt.value || !diag.Results[1].Assignable {
+			t.Errorf("IntList %s: %+v, %v", tt.iface, diag, err)
+		}
+	}
+	diag, err = run("../..", true, "./demo11_interface", "Bird", "IDuck")
+	if err != nil || !diag.Results[0].Assignable {
+		t.Errorf("Bird: %+v, %v", diag, err)
+	}
+}
@@ -0,0 +1,3 @@
+module example.com/demo6
+
+go 1.24
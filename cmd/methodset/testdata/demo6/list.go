@@ -0,0 +1,65 @@
+package demo6
+
+import "sync"
+
+type Appender interface {
+	Append(int)
+}
+
+type Lener interface {
+	Len() int
+}
+
+// List 和 Demo6 一样：Append 是指针接收者，Len 是值接收者
+type List []int
+
+func (l *List) Append(i int) {
+	*l = append(*l, i)
+}
+
+func (l List) Len() int {
+	return len(l)
+}
+
+// Counter 少一个方法，Reset 的签名不对
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Reset(to int) {
+	c.n = to
+}
+
+type Resetter interface {
+	Lener
+	Reset()
+}
+
+// Locked 通过嵌入 *sync.Mutex 得到 Lock/Unlock，通过嵌入 List 得到 Len 和 Append
+type Locked struct {
+	*sync.Mutex
+	List
+}
+
+// Stack 泛型
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+func (s Stack[T]) Len() int {
+	return len(s.items)
+}
+
+type Pusher[T any] interface {
+	Push(T)
+	Len() int
+}
+
+// Shadow 字段和方法同名
+type Shadow struct {
+	Len int
+}
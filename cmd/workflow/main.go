@@ -0,0 +1,74 @@
+// workflow 运行工作流文件中的任务
+/*
+用法：
+
+	go run ./cmd/workflow [-j n] [-continue] [-state file] [-resume] workflow.yaml
+
+1:工作流文件的格式见 demo11_interface/workflow_file.go，JSON 或者 YAML 子集。
+2:-j 和 -continue 覆盖文件中的 concurrency 和 continueOnFailure。
+3:状态默认保存在工作流文件旁边的 <文件名>.state.json，-state 指定别的路径；-resume 跳过上次已经成功的任务。
+4:任务输出和进度写到 stderr，结束后在 stdout 输出汇总；有任务失败时退出码是 1，参数或者文件出错时是 2。
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/cbcstars/go/demo11_interface"
+)
+
+func main() {
+	jobs := flag.Int("j", 0, "同时运行的任务数，0 表示使用文件中的设置")
+	cont := flag.Bool("continue", false, "任务失败后继续运行不依赖它的任务")
+	stateFile := flag.String("state", "", "状态文件，默认是 <工作流文件>.state.json")
+	resume := flag.Bool("resume", false, "从上次失败的任务继续")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: workflow [-j n] [-continue] [-state file] [-resume] workflow.yaml")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	w, err := demo11_interface.LoadWorkflow(path, log.New(os.Stderr, "", log.Ltime))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "workflow:", err)
+		os.Exit(2)
+	}
+	if *jobs > 0 {
+		w.Concurrency = *jobs
+	}
+	if *cont {
+		w.ContinueOnFailure = true
+	}
+	w.StateFile = *stateFile
+	if w.StateFile == "" {
+		w.StateFile = path + ".state.json"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	run := w.Run
+	if *resume {
+		run = w.Resume
+	}
+	res, err := run(ctx)
+	if res != nil {
+		res.WriteSummary(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "workflow:", err)
+		if res == nil {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
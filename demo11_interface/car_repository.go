@@ -0,0 +1,477 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Car 仓库
+/*
+1:CarRepository 在内存中保存所有车辆，并在 Manufacturer 和 BuildYear 上维护二级索引。
+2:每次修改成功后都会把全部数据写入临时文件，再用 rename 原子地替换目标文件，
+  进程在任何时刻崩溃，文件里要么是修改前的数据，要么是修改后的数据。
+3:支持 JSON Lines 和 CSV 两种文件格式，创建仓库时如果文件已经存在会自动加载。
+4:FindAll 接受谓词表达式（见 predicate.go），表达式顶层 && 中对索引字段的 == 或 in 比较会先通过索引缩小候选集合。
+5:仓库返回的 *Car 都是副本，修改它们不会影响仓库中的数据，需要通过 Update 写回。
+*/
+
+// StorageFormat 仓库文件格式
+type StorageFormat int
+
+const (
+	// JSONLines 每行一个 JSON 对象
+	JSONLines StorageFormat = iota
+	// CSV 第一行是表头
+	CSV
+)
+
+// ErrCarNotFound 指定 ID 的车辆不存在
+var ErrCarNotFound = errors.New("car not found")
+
+// carRecord 持久化时的一条记录
+type carRecord struct {
+	ID int64 `json:"id"`
+	Car
+}
+
+var carCSVHeader = []string{"id", "Module", "Manufacturer", "BuildYear"}
+
+// CarRepository 持久化的 Car 仓库，可以被多个 goroutine 同时使用
+type CarRepository struct {
+	mu     sync.RWMutex
+	path   string
+	format StorageFormat
+	nextID int64
+	cars   map[int64]Car
+
+	byManufacturer map[string]map[int64]struct{}
+	byBuildYear    map[int64]map[int64]struct{}
+}
+
+// NewCarRepository 创建仓库，path 已经存在时从中加载数据
+func NewCarRepository(path string, format StorageFormat) (*CarRepository, error) {
+	if format != JSONLines && format != CSV {
+		return nil, fmt.Errorf("car repository: unknown storage format %d", format)
+	}
+	r := &CarRepository{
+		path:           path,
+		format:         format,
+		nextID:         1,
+		cars:           make(map[int64]Car),
+		byManufacturer: make(map[string]map[int64]struct{}),
+		byBuildYear:    make(map[int64]map[int64]struct{}),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Insert 保存一辆新车，返回分配的 ID
+func (r *CarRepository) Insert(c *Car) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.nextID
+	r.put(id, *c)
+	r.nextID++
+	if err := r.persist(); err != nil {
+		r.remove(id)
+		r.nextID--
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update 用 c 替换 ID 为 id 的车辆
+func (r *CarRepository) Update(id int64, c *Car) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.cars[id]
+	if !ok {
+		return fmt.Errorf("update %d: %w", id, ErrCarNotFound)
+	}
+	r.remove(id)
+	r.put(id, *c)
+	if err := r.persist(); err != nil {
+		r.remove(id)
+		r.put(id, old)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除 ID 为 id 的车辆
+func (r *CarRepository) Delete(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.cars[id]
+	if !ok {
+		return fmt.Errorf("delete %d: %w", id, ErrCarNotFound)
+	}
+	r.remove(id)
+	if err := r.persist(); err != nil {
+		r.put(id, old)
+		return err
+	}
+	return nil
+}
+
+// Get 返回 ID 为 id 的车辆的副本
+func (r *CarRepository) Get(id int64) (*Car, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.cars[id]
+	if !ok {
+		return nil, fmt.Errorf("get %d: %w", id, ErrCarNotFound)
+	}
+	return &c, nil
+}
+
+// Len 车辆数量
+func (r *CarRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.cars)
+}
+
+// All 按 ID 顺序返回所有车辆
+func (r *CarRepository) All() Cars {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.sortedIDs(), nil)
+}
+
+// FindAll 按谓词表达式查找，结果按 ID 排序
+func (r *CarRepository) FindAll(expr string) (Cars, error) {
+	root, err := parsePredicate(expr, reflect.TypeOf(Car{}))
+	if err != nil {
+		return nil, err
+	}
+	pred := bindPredicate[Car](root)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids, ok := r.indexCandidates(root)
+	if !ok {
+		ids = r.sortedIDs()
+	}
+	return r.collect(ids, pred), nil
+}
+
+// FindAllFunc 用任意函数过滤，只能全表扫描
+func (r *CarRepository) FindAllFunc(f func(c *Car) bool) Cars {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.collect(r.sortedIDs(), f)
+}
+
+func (r *CarRepository) collect(ids []int64, pred func(c *Car) bool) Cars {
+	cars := make(Cars, 0, len(ids))
+	for _, id := range ids {
+		c := r.cars[id]
+		if pred == nil || pred(&c) {
+			cars = append(cars, &c)
+		}
+	}
+	return cars
+}
+
+func (r *CarRepository) sortedIDs() []int64 {
+	ids := make([]int64, 0, len(r.cars))
+	for id := range r.cars {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// indexCandidates 用索引求出候选 ID，表达式中没有可用的索引条件时 ok 为 false
+func (r *CarRepository) indexCandidates(root predNode) (ids []int64, ok bool) {
+	var result map[int64]struct{}
+	for _, cond := range indexConditions(root) {
+		set := make(map[int64]struct{})
+		for _, lit := range cond.values {
+			var bucket map[int64]struct{}
+			switch cond.field {
+			case "Manufacturer":
+				bucket = r.byManufacturer[lit.value.(string)]
+			case "BuildYear":
+				bucket = r.byBuildYear[lit.value.(int64)]
+			}
+			for id := range bucket {
+				set[id] = struct{}{}
+			}
+		}
+		if result == nil {
+			result = set
+			continue
+		}
+		for id := range result {
+			if _, ok := set[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	if result == nil {
+		return nil, false
+	}
+	ids = make([]int64, 0, len(result))
+	for id := range result {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, true
+}
+
+// indexCondition 索引字段等于若干个值之一
+type indexCondition struct {
+	field  string
+	values []*predLiteral
+}
+
+// indexConditions 收集顶层 && 中形如 索引字段 == 字面量 或 索引字段 in (...) 的条件
+func indexConditions(n predNode) []indexCondition {
+	switch n := n.(type) {
+	case *predBinary:
+		switch n.op {
+		case tokAnd:
+			return append(indexConditions(n.x), indexConditions(n.y)...)
+		case tokEq:
+			field, lit := n.x, n.y
+			if _, isLit := field.(*predLiteral); isLit {
+				field, lit = lit, field
+			}
+			f, ok1 := field.(*predField)
+			l, ok2 := lit.(*predLiteral)
+			if ok1 && ok2 && indexable(f, l) {
+				return []indexCondition{{field: f.name, values: []*predLiteral{l}}}
+			}
+		}
+	case *predIn:
+		f, ok := n.x.(*predField)
+		if !ok || n.negate {
+			return nil
+		}
+		for _, l := range n.list {
+			if !indexable(f, l) {
+				return nil
+			}
+		}
+		return []indexCondition{{field: f.name, values: n.list}}
+	}
+	return nil
+}
+
+func indexable(f *predField, l *predLiteral) bool {
+	switch f.name {
+	case "Manufacturer":
+		return l.typ == predString
+	case "BuildYear":
+		return l.typ == predInt
+	}
+	return false
+}
+
+// put 写入数据并更新索引，调用方持有写锁
+func (r *CarRepository) put(id int64, c Car) {
+	r.cars[id] = c
+	addToIndex(r.byManufacturer, c.Manufacturer, id)
+	addToIndex(r.byBuildYear, int64(c.BuildYear), id)
+}
+
+// remove 删除数据并更新索引，调用方持有写锁
+func (r *CarRepository) remove(id int64) {
+	c, ok := r.cars[id]
+	if !ok {
+		return
+	}
+	delete(r.cars, id)
+	removeFromIndex(r.byManufacturer, c.Manufacturer, id)
+	removeFromIndex(r.byBuildYear, int64(c.BuildYear), id)
+}
+
+func addToIndex[K comparable](index map[K]map[int64]struct{}, key K, id int64) {
+	bucket, ok := index[key]
+	if !ok {
+		bucket = make(map[int64]struct{})
+		index[key] = bucket
+	}
+	bucket[id] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[int64]struct{}, key K, id int64) {
+	bucket := index[key]
+	delete(bucket, id)
+	if len(bucket) == 0 {
+		delete(index, key)
+	}
+}
+
+// persist 先写临时文件，再 rename 替换目标文件
+func (r *CarRepository) persist() error {
+	dir := filepath.Dir(r.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if r.format == CSV {
+		err = r.writeCSV(w)
+	} else {
+		err = r.writeJSONLines(w)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("car repository: write %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("car repository: %w", err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir 让 rename 本身也落盘，不支持的平台上忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func (r *CarRepository) writeJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, id := range r.sortedIDs() {
+		if err := enc.Encode(carRecord{ID: id, Car: r.cars[id]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *CarRepository) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(carCSVHeader); err != nil {
+		return err
+	}
+	for _, id := range r.sortedIDs() {
+		c := r.cars[id]
+		if err := cw.Write([]string{strconv.FormatInt(id, 10), c.Module, c.Manufacturer, strconv.Itoa(c.BuildYear)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *CarRepository) load() error {
+	f, err := os.Open(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if r.format == CSV {
+		err = r.readCSV(f)
+	} else {
+		err = r.readJSONLines(f)
+	}
+	if err != nil {
+		return fmt.Errorf("car repository: load %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func (r *CarRepository) add(rec carRecord) error {
+	if rec.ID <= 0 {
+		return fmt.Errorf("invalid id %d", rec.ID)
+	}
+	if _, dup := r.cars[rec.ID]; dup {
+		return fmt.Errorf("duplicate id %d", rec.ID)
+	}
+	r.put(rec.ID, rec.Car)
+	r.nextID = max(r.nextID, rec.ID+1)
+	return nil
+}
+
+func (r *CarRepository) readJSONLines(f io.Reader) error {
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var rec carRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := r.add(rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return s.Err()
+}
+
+func (r *CarRepository) readCSV(f io.Reader) error {
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = len(carCSVHeader)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(header, carCSVHeader) {
+		return fmt.Errorf("unexpected header %v, want %v", header, carCSVHeader)
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		id, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid id %q", line, row[0])
+		}
+		year, err := strconv.Atoi(row[3])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid BuildYear %q", line, row[3])
+		}
+		rec := carRecord{ID: id, Car: Car{Module: row[1], Manufacturer: row[2], BuildYear: year}}
+		if err := r.add(rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package demo11_interface
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestRepository(t *testing.T, format StorageFormat) (*CarRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cars.db")
+	r, err := NewCarRepository(path, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range testCars() {
+		if c == nil {
+			continue
+		}
+		if _, err := r.Insert(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r, path
+}
+
+func TestCarRepositoryReload(t *testing.T) {
+	for _, format := range []StorageFormat{JSONLines, CSV} {
+		r, path := newTestRepository(t, format)
+		if err := r.Delete(2); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Update(3, &Car{Module: "2", Manufacturer: "BYD, Inc.", BuildYear: 2024}); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := NewCarRepository(path, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded.All(), r.All()) {
+			t.Errorf("format %d: reloaded %s, want %s", format, modules(loaded.All()), modules(r.All()))
+		}
+		id, err := loaded.Insert(&Car{Module: "new"})
+		if err != nil || id != 7 {
+			t.Errorf("format %d: Insert after reload = %d, %v, want id 7", format, id, err)
+		}
+
+		// 没有残留的临时文件
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("format %d: %d files in directory, want 1", format, len(entries))
+		}
+	}
+}
+
+func TestCarRepositoryIndex(t *testing.T) {
+	r, _ := newTestRepository(t, JSONLines)
+	tests := []struct {
+		expr    string
+		indexed []int64
+		want    string
+	}{
+		{`Manufacturer == "BYD"`, []int64{1, 3, 6}, "BYD/3 BYD/2 BYD/1"},
+		{`"BYD" == Manufacturer && BuildYear == 2024`, []int64{3, 6}, "BYD/2 BYD/1"},
+		{`BuildYear in (2021, 2019) && Module != "2"`, []int64{1, 5}, "BYD/3 /1"},
+		{`Manufacturer == "Tesla"`, []int64{}, ""},
+		{`Manufacturer == "BYD" || BuildYear == 2021`, nil, "BYD/3 BYD/2 BYD/1"},
+		{`BuildYear == 2024.0`, nil, "BMW/1 BYD/2 BMW/ BYD/1"},
+	}
+	for _, tt := range tests {
+		root, err := parsePredicate(tt.expr, reflect.TypeOf(Car{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, ok := r.indexCandidates(root)
+		if ok != (tt.indexed != nil) || !slices.Equal(ids, tt.indexed) {
+			t.Errorf("%s: candidates %v %v, want %v", tt.expr, ids, ok, tt.indexed)
+		}
+		got, err := r.FindAll(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modules(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.expr, modules(got), tt.want)
+		}
+	}
+
+	if _, err := r.FindAll(`Colour == "red"`); err == nil {
+		t.Error("want error for unknown field")
+	}
+}
+
+func TestCarRepositoryMutations(t *testing.T) {
+	r, _ := newTestRepository(t, CSV)
+	c, _ := r.Get(1)
+	c.Manufacturer = "changed"
+	if got, _ := r.Get(1); got.Manufacturer != "BYD" {
+		t.Error("Get returned a car shared with the repository")
+	}
+
+	if err := r.Update(1, &Car{Module: "3", Manufacturer: "BMW", BuildYear: 2030}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.FindAll(`Manufacturer == "BYD"`); modules(got) != "BYD/2 BYD/1" {
+		t.Errorf("after update: %s", modules(got))
+	}
+	if got, _ := r.FindAll(`BuildYear == 2030`); modules(got) != "BMW/3" {
+		t.Errorf("after update: %s", modules(got))
+	}
+	if err := r.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.byBuildYear[2030]; ok {
+		t.Error("empty index bucket not removed")
+	}
+	if got := r.FindAllFunc(func(c *Car) bool { return c.Manufacturer == "BMW" }); modules(got) != "BMW/1 BMW/" {
+		t.Errorf("FindAllFunc = %s", modules(got))
+	}
+
+	for _, err := range []error{r.Delete(1), r.Update(1, &Car{})} {
+		if !errors.Is(err, ErrCarNotFound) {
+			t.Errorf("got %v, want ErrCarNotFound", err)
+		}
+	}
+	if _, err := r.Get(100); !errors.Is(err, ErrCarNotFound) {
+		t.Errorf("got %v, want ErrCarNotFound", err)
+	}
+}
+
+func TestCarRepositoryLoadErrors(t *testing.T) {
+	tests := []struct {
+		format  StorageFormat
+		content string
+		msg     string
+	}{
+		{JSONLines, "{\"id\":1}\n{\"id\":1}\n", "line 2: duplicate id 1"},
+		{JSONLines, "{\"id\":1}\n\nnot json\n", "line 3:"},
+		{CSV, "id,Module\n", "wrong number of fields"},
+		{CSV, "id,Module,Manufacturer,BuildYear\n1,a,b,c\n", `line 2: invalid BuildYear "c"`},
+		{CSV, "Id,Module,Manufacturer,BuildYear\n", "unexpected header"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "cars.db")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewCarRepository(path, tt.format)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%q: got %v, want %q", tt.content, err, tt.msg)
+		}
+	}
+}
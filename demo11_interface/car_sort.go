@@ -0,0 +1,243 @@
+package demo11_interface
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// 多字段排序
+/*
+1:CarOrder 由若干 CarSortKey 组成，先按第一个字段比较，相等时再比较下一个字段。
+2:排序字段可以用 CarKey 从字段访问函数构造，也可以用 ParseCarOrder 从字符串解析，例如：
+	"Manufacturer asc, BuildYear desc, Module asc nulls last"
+3:nil 的 *Car 和字段的零值被视为空值，默认排在最前面，NullsLast 把它们放到最后，与升序降序无关。
+4:SortBy 使用稳定排序并返回新的 Cars，不修改原来的集合，所以可以和 FindAll、Process 链式调用。
+*/
+
+// CarSortKey 一个排序字段
+type CarSortKey struct {
+	name      string
+	compare   func(a, b *Car) int
+	isNull    func(c *Car) bool
+	desc      bool
+	nullsLast bool
+}
+
+// CarKey 通过字段访问函数构造升序的排序字段，get 的返回值为零值时视为空值
+func CarKey[T cmp.Ordered](name string, get func(c *Car) T) CarSortKey {
+	var zero T
+	return CarSortKey{
+		name:    name,
+		compare: func(a, b *Car) int { return cmp.Compare(get(a), get(b)) },
+		isNull:  func(c *Car) bool { return get(c) == zero },
+	}
+}
+
+// Reverse 反转排序方向
+func (k CarSortKey) Reverse() CarSortKey {
+	k.desc = !k.desc
+	return k
+}
+
+// NullsLast 空值排在最后
+func (k CarSortKey) NullsLast() CarSortKey {
+	k.nullsLast = true
+	return k
+}
+
+func (k CarSortKey) String() string {
+	s := k.name + " asc"
+	if k.desc {
+		s = k.name + " desc"
+	}
+	if k.nullsLast {
+		s += " nulls last"
+	}
+	return s
+}
+
+// Compare 比较 a 和 b，a 排在前面时返回负数
+func (k CarSortKey) Compare(a, b *Car) int {
+	aNull := a == nil || k.isNull != nil && k.isNull(a)
+	bNull := b == nil || k.isNull != nil && k.isNull(b)
+	switch {
+	case aNull && bNull:
+		return 0
+	case aNull != bNull:
+		if aNull == k.nullsLast {
+			return 1
+		}
+		return -1
+	}
+	c := k.compare(a, b)
+	if k.desc {
+		return -c
+	}
+	return c
+}
+
+// CarOrder 多字段排序规则
+type CarOrder []CarSortKey
+
+// Compare 依次比较每个字段，直到分出先后
+func (o CarOrder) Compare(a, b *Car) int {
+	for _, k := range o {
+		if c := k.Compare(a, b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Reverse 反转每个字段的排序方向
+func (o CarOrder) Reverse() CarOrder {
+	r := make(CarOrder, len(o))
+	for i, k := range o {
+		r[i] = k.Reverse()
+	}
+	return r
+}
+
+func (o CarOrder) String() string {
+	keys := make([]string, len(o))
+	for i, k := range o {
+		keys[i] = k.String()
+	}
+	return strings.Join(keys, ", ")
+}
+
+// ParseCarOrder 解析 "字段 [asc|desc] [nulls first|nulls last], ..." 形式的排序规则，
+// 字段名对应 Car 的导出字段，不区分大小写
+func ParseCarOrder(spec string) (CarOrder, error) {
+	var order CarOrder
+	offset := 0
+	for _, item := range strings.Split(spec, ",") {
+		words, positions := splitWords(item, offset)
+		offset += len(item) + 1
+		if len(words) == 0 {
+			return nil, fmt.Errorf("car order: position %d: missing field name", positions[0])
+		}
+
+		key, err := carFieldKey(words[0])
+		if err != nil {
+			return nil, fmt.Errorf("car order: position %d: %w", positions[0], err)
+		}
+		rest, pos := words[1:], positions[1:]
+		if len(rest) > 0 {
+			switch strings.ToLower(rest[0]) {
+			case "asc":
+				rest, pos = rest[1:], pos[1:]
+			case "desc":
+				key = key.Reverse()
+				rest, pos = rest[1:], pos[1:]
+			}
+		}
+		if len(rest) > 0 {
+			if len(rest) != 2 || !strings.EqualFold(rest[0], "nulls") {
+				return nil, fmt.Errorf("car order: position %d: unexpected %q", pos[0], rest[0])
+			}
+			switch strings.ToLower(rest[1]) {
+			case "first":
+			case "last":
+				key = key.NullsLast()
+			default:
+				return nil, fmt.Errorf("car order: position %d: expected first or last after nulls, got %q", pos[1], rest[1])
+			}
+		}
+		order = append(order, key)
+	}
+	return order, nil
+}
+
+// splitWords 按空白切分，同时返回每个单词在整个字符串中的位置；没有单词时返回这一段的起始位置
+func splitWords(s string, offset int) ([]string, []int) {
+	var words []string
+	var positions []int
+	start := -1
+	for i, r := range s + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, s[start:i])
+				positions = append(positions, offset+start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if len(words) == 0 {
+		positions = []int{offset}
+	}
+	return words, positions
+}
+
+// carFieldKey 通过反射为 Car 的字段构造排序字段
+func carFieldKey(name string) (CarSortKey, error) {
+	t := reflect.TypeOf(Car{})
+	f, ok := t.FieldByNameFunc(func(s string) bool { return strings.EqualFold(s, name) })
+	if !ok || !f.IsExported() {
+		return CarSortKey{}, fmt.Errorf("unknown field %q", name)
+	}
+	var compare func(a, b reflect.Value) int
+	switch f.Type.Kind() {
+	case reflect.String:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.String(), b.String()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Int(), b.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Uint(), b.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Float(), b.Float()) }
+	case reflect.Bool:
+		compare = func(a, b reflect.Value) int {
+			return cmp.Compare(boolRank(a.Bool()), boolRank(b.Bool()))
+		}
+	default:
+		return CarSortKey{}, fmt.Errorf("field %s of type %s is not orderable", f.Name, f.Type)
+	}
+	index := f.Index
+	return CarSortKey{
+		name: f.Name,
+		compare: func(a, b *Car) int {
+			return compare(reflect.ValueOf(a).Elem().FieldByIndex(index), reflect.ValueOf(b).Elem().FieldByIndex(index))
+		},
+		isNull: func(c *Car) bool { return reflect.ValueOf(c).Elem().FieldByIndex(index).IsZero() },
+	}, nil
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// carsSorter 让 Cars 按 CarOrder 通过 Sorter 接口排序
+type carsSorter struct {
+	cars  Cars
+	order CarOrder
+}
+
+func (s carsSorter) Len() int           { return len(s.cars) }
+func (s carsSorter) Less(i, j int) bool { return s.order.Compare(s.cars[i], s.cars[j]) < 0 }
+func (s carsSorter) Swap(i, j int)      { s.cars[i], s.cars[j] = s.cars[j], s.cars[i] }
+
+// SortBy 按给定的字段稳定排序，返回新的 Cars
+func (cs Cars) SortBy(keys ...CarSortKey) Cars {
+	sorted := make(Cars, len(cs))
+	copy(sorted, cs)
+	Stable(carsSorter{cars: sorted, order: keys})
+	return sorted
+}
+
+// SortBySpec 按 ParseCarOrder 格式的排序规则排序，返回新的 Cars
+func (cs Cars) SortBySpec(spec string) (Cars, error) {
+	order, err := ParseCarOrder(spec)
+	if err != nil {
+		return nil, err
+	}
+	return cs.SortBy(order...), nil
+}
@@ -0,0 +1,95 @@
+package demo11_interface
+
+import (
+	"strings"
+	"testing"
+)
+
+func testCars() Cars {
+	return Cars{
+		&Car{Module: "3", Manufacturer: "BYD", BuildYear: 2021},
+		&Car{Module: "1", Manufacturer: "BMW", BuildYear: 2024},
+		&Car{Module: "2", Manufacturer: "BYD", BuildYear: 2024},
+		&Car{Module: "", Manufacturer: "BMW", BuildYear: 2024},
+		nil,
+		&Car{Module: "1", Manufacturer: "", BuildYear: 2019},
+		&Car{Module: "1", Manufacturer: "BYD", BuildYear: 2024},
+	}
+}
+
+func modules(cs Cars) string {
+	var parts []string
+	for _, c := range cs {
+		if c == nil {
+			parts = append(parts, "nil")
+			continue
+		}
+		parts = append(parts, c.Manufacturer+"/"+c.Module)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestCarsSortBy(t *testing.T) {
+	cars := testCars()
+	byMaker := CarKey("Manufacturer", func(c *Car) string { return c.Manufacturer })
+	byYear := CarKey("BuildYear", func(c *Car) int { return c.BuildYear })
+	byModule := CarKey("Module", func(c *Car) string { return c.Module })
+
+	got := cars.SortBy(byMaker.NullsLast(), byYear.Reverse(), byModule.NullsLast())
+	want := "BMW/1 BMW/ BYD/1 BYD/2 BYD/3 nil /1"
+	if modules(got) != want {
+		t.Errorf("got %s, want %s", modules(got), want)
+	}
+	if modules(cars) != modules(testCars()) {
+		t.Error("SortBy modified the original Cars")
+	}
+
+	// 稳定排序：只按厂商排序时，同一厂商保持原来的先后
+	got = cars.SortBy(byMaker)
+	want = "nil /1 BMW/1 BMW/ BYD/3 BYD/2 BYD/1"
+	if modules(got) != want {
+		t.Errorf("got %s, want %s", modules(got), want)
+	}
+}
+
+func TestCarsSortBySpec(t *testing.T) {
+	cars := testCars()
+	got, err := cars.FindAll(func(c *Car) bool { return c != nil && c.BuildYear > 2020 }).
+		SortBySpec("manufacturer DESC, BuildYear desc, Module asc nulls last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BYD/1 BYD/2 BYD/3 BMW/1 BMW/"
+	if modules(got) != want {
+		t.Errorf("got %s, want %s", modules(got), want)
+	}
+
+	order, err := ParseCarOrder(" Module , BuildYear desc nulls first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := order.String(); s != "Module asc, BuildYear desc" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := order.Reverse().String(); s != "Module desc, BuildYear asc" {
+		t.Errorf("Reverse().String() = %q", s)
+	}
+}
+
+func TestParseCarOrderErrors(t *testing.T) {
+	tests := []struct {
+		spec, err string
+	}{
+		{"Color asc", "position 0: unknown field"},
+		{"Module, ", "position 7: missing field name"},
+		{"Module up", "position 7: unexpected"},
+		{"Module asc nulls middle", "position 17: expected first or last"},
+		{"Module asc nulls", "position 11: unexpected"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCarOrder(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseCarOrder(%q) error = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package demo11_interface
+
+// Demo12:结构体、集合和高阶函数
+// Cars 的 Process、FindAll 基于通用的 Query 实现，更复杂的查询直接使用 Query()
+type Car struct {
+	Module       string
+	Manufacturer string
+	BuildYear    int
+}
+
+type Cars []*Car
+
+// Query 返回以 cs 为数据源的惰性查询
+func (cs Cars) Query() Query[*Car] {
+	return From(cs)
+}
+
+func (cs Cars) Process(f func(c *Car)) {
+	cs.Query().ForEach(f)
+}
+
+func (cs Cars) FindAll(f func(c *Car) bool) Cars {
+	return cs.Query().Where(f).ToSlice()
+}
@@ -0,0 +1,227 @@
+package demo11_interface
+
+import "math"
+
+// 精确的相交检测和距离
+/*
+1:ShapesIntersect 判断两个图形是否有公共点（包括只接触边界），ShapeDistance 计算点到图形的距离，点在图形内时为 0。
+2:按图形的几何表示分为四类处理：
+	a:多边形类：Square、Rectangle、Triangle、Polygon 以及其他实现 Polygonal 的类型
+	b:Circle
+	c:Ellipse：通过仿射变换把椭圆变成单位圆，多边形经过仿射变换仍然是多边形，所以椭圆与多边形的检测是精确的
+	d:其他 Shaper：只能用包围盒近似
+3:椭圆与圆的检测用点到椭圆的距离；椭圆与椭圆先把 a 变换成单位圆，b 在同一个仿射变换下仍然是椭圆，
+  两者相交当且仅当原点在 b 内或者原点到 b 的距离不超过 1。点到椭圆的距离用二分法求解，误差不超过 geomEpsilon 量级。
+4:判断之前先用 normalizeShape 去掉 *Circle、*Ellipse 这样的指针和 Styled 这样的包装，
+  所以 &Circle{...} 和 Styled{Shaper: Ellipse{...}} 与对应的值一样使用精确检测。
+*/
+
+// shapeWrapper 包装了另一个图形的类型，例如 Styled；相交和距离按被包装的图形计算
+type shapeWrapper interface {
+	Unwrap() Shaper
+}
+
+// normalizeShape 去掉包装，把曲线图形的指针换成值
+func normalizeShape(s Shaper) Shaper {
+	for {
+		switch v := s.(type) {
+		case shapeWrapper:
+			s = v.Unwrap()
+		case *Circle:
+			if v == nil {
+				return s
+			}
+			s = *v
+		case *Ellipse:
+			if v == nil {
+				return s
+			}
+			s = *v
+		default:
+			return s
+		}
+	}
+}
+
+// ShapesIntersect a 和 b 是否相交
+func ShapesIntersect(a, b Shaper) bool {
+	if !a.Bounds().Intersects(b.Bounds()) {
+		return false
+	}
+	a, b = normalizeShape(a), normalizeShape(b)
+	switch a := a.(type) {
+	case Circle:
+		switch b := b.(type) {
+		case Circle:
+			return a.Center.Dist(b.Center) <= a.Radius+b.Radius+geomEpsilon
+		case Ellipse:
+			return b.Contains(a.Center) || ellipseDistance(b, a.Center) <= a.Radius+geomEpsilon
+		case Polygonal:
+			return circlePolygonIntersect(a.Center, a.Radius, b.Vertices())
+		}
+	case Ellipse:
+		switch b := b.(type) {
+		case Circle:
+			return ShapesIntersect(b, a)
+		case Ellipse:
+			return ellipsesIntersect(a, b)
+		case Polygonal:
+			return ellipsePolygonIntersect(a, b.Vertices())
+		}
+	case Polygonal:
+		switch b := b.(type) {
+		case Circle, Ellipse:
+			return ShapesIntersect(b, a)
+		case Polygonal:
+			return polygonsIntersect(a.Vertices(), b.Vertices())
+		}
+	}
+	// 未知的图形只能依靠包围盒
+	return true
+}
+
+// ShapeDistance 点 p 到图形 s 的距离
+func ShapeDistance(s Shaper, p Point) float64 {
+	if s.Contains(p) {
+		return 0
+	}
+	switch s := normalizeShape(s).(type) {
+	case Circle:
+		return math.Max(0, p.Dist(s.Center)-s.Radius)
+	case Ellipse:
+		return ellipseDistance(s, p)
+	case Polygonal:
+		return polygonDistance(s.Vertices(), p)
+	}
+	return boxDistance(s.Bounds(), p)
+}
+
+// boxDistance 点到包围盒的距离，不小于点到盒内任意图形的距离
+func boxDistance(b Box, p Point) float64 {
+	dx := math.Max(0, math.Max(b.Min.X-p.X, p.X-b.Max.X))
+	dy := math.Max(0, math.Max(b.Min.Y-p.Y, p.Y-b.Max.Y))
+	return math.Hypot(dx, dy)
+}
+
+// segmentDistance 点 p 到线段 ab 的距离
+func segmentDistance(a, b, p Point) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return p.Dist(a)
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return p.Dist(a.Add(ab.Mul(t)))
+}
+
+// polygonDistance 点到多边形边界的距离
+func polygonDistance(points []Point, p Point) float64 {
+	d := math.Inf(1)
+	for i, a := range points {
+		d = math.Min(d, segmentDistance(a, points[(i+1)%len(points)], p))
+	}
+	return d
+}
+
+// polygonsIntersect 边相交，或者一个多边形包含另一个多边形的顶点
+func polygonsIntersect(a, b []Point) bool {
+	for i, p := range a {
+		q := a[(i+1)%len(a)]
+		for j, r := range b {
+			if segmentsIntersect(p, q, r, b[(j+1)%len(b)]) {
+				return true
+			}
+		}
+	}
+	return polygonContains(a, b[0]) || polygonContains(b, a[0])
+}
+
+// circlePolygonIntersect 圆心在多边形内，或者圆心到某条边的距离不超过半径
+func circlePolygonIntersect(center Point, r float64, points []Point) bool {
+	if polygonContains(points, center) {
+		return true
+	}
+	return polygonDistance(points, center) <= r+geomEpsilon
+}
+
+// toEllipseFrame 把 p 变换到 e 变成单位圆的坐标系
+func toEllipseFrame(e Ellipse, p Point) Point {
+	l := p.Rotate(-e.Angle, e.Center).Sub(e.Center)
+	return Point{l.X / e.RX, l.Y / e.RY}
+}
+
+func ellipsePolygonIntersect(e Ellipse, points []Point) bool {
+	if e.RX == 0 || e.RY == 0 {
+		return false
+	}
+	local := transformPoints(points, func(p Point) Point { return toEllipseFrame(e, p) })
+	return circlePolygonIntersect(Point{}, 1, local)
+}
+
+// ellipsesIntersect 在 a 变成单位圆的坐标系中，b 的像是中心为 c、由 N 把单位圆映射出的椭圆，
+// 它的半轴长和方向是 N·Nᵀ 的特征值的平方根和特征向量
+func ellipsesIntersect(a, b Ellipse) bool {
+	if a.RX == 0 || a.RY == 0 || b.RX == 0 || b.RY == 0 {
+		return false
+	}
+	c := toEllipseFrame(a, b.Center)
+	// N 的两列是 b 的两个半轴向量在 a 的坐标系中的像
+	sinB, cosB := math.Sincos(b.Angle - a.Angle)
+	n00, n10 := b.RX*cosB/a.RX, b.RX*sinB/a.RY
+	n01, n11 := -b.RY*sinB/a.RX, b.RY*cosB/a.RY
+	s00 := n00*n00 + n01*n01
+	s11 := n10*n10 + n11*n11
+	s01 := n00*n10 + n01*n11
+	mean, diff := (s00+s11)/2, math.Hypot((s00-s11)/2, s01)
+	local := Ellipse{
+		Center: c,
+		RX:     math.Sqrt(mean + diff),
+		RY:     math.Sqrt(math.Max(0, mean-diff)),
+		Angle:  math.Atan2(2*s01, s00-s11) / 2,
+	}
+	if local.RY == 0 {
+		// b 的像数值上退化成线段时给一个极小的半轴，避免 ellipseDistance 除以 0
+		local.RY = geomEpsilon
+	}
+	return local.Contains(Point{}) || ellipseDistance(local, Point{}) <= 1+geomEpsilon
+}
+
+// ellipsePolygon 用 n 边形近似椭圆，顶点在椭圆上
+func ellipsePolygon(e Ellipse, n int) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		points[i] = e.Center.Add(Pt(e.RX*cos, e.RY*sin)).Rotate(e.Angle, e.Center)
+	}
+	return points
+}
+
+// ellipseDistance 椭圆外的点到椭圆的距离
+/*
+在椭圆的局部坐标系中把点翻转到第一象限，最近点为 (a²x/(t+a²), b²y/(t+b²))，
+其中 t 是 F(t) = (ax/(t+a²))² + (by/(t+b²))² - 1 的正根，F 在 t >= 0 上单调递减，用二分法求解。
+*/
+func ellipseDistance(e Ellipse, p Point) float64 {
+	l := p.Rotate(-e.Angle, e.Center).Sub(e.Center)
+	x, y := math.Abs(l.X), math.Abs(l.Y)
+	a, b := e.RX, e.RY
+	f := func(t float64) float64 {
+		u, v := a*x/(t+a*a), b*y/(t+b*b)
+		return u*u + v*v - 1
+	}
+	lo, hi := 0.0, math.Max(a, b)*math.Hypot(x, y)
+	if f(lo) <= 0 {
+		return 0
+	}
+	for i := 0; i < 200 && hi-lo > geomEpsilon*math.Max(1, hi); i++ {
+		mid := (lo + hi) / 2
+		if f(mid) > 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	t := (lo + hi) / 2
+	q := Point{a * a * x / (t + a*a), b * b * y / (t + b*b)}
+	return q.Dist(Point{x, y})
+}
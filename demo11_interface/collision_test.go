@@ -0,0 +1,112 @@
+package demo11_interface
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestShapesIntersect(t *testing.T) {
+	tri := Triangle{Pt(0, 0), Pt(4, 0), Pt(0, 4)}
+	tests := []struct {
+		a, b Shaper
+		want bool
+	}{
+		{&Square{Side: 2}, Rectangle{Length: 2, Width: 2, Center: Pt(2, 0)}, true},
+		{&Square{Side: 2}, Rectangle{Length: 2, Width: 2, Center: Pt(2.1, 0)}, false},
+		// 旋转 45 度后包围盒相交，但实际不相交
+		{&Square{Side: 2, Angle: math.Pi / 4}, &Square{Side: 1, Center: Pt(1.3, 1.3)}, false},
+		{&Square{Side: 2, Angle: math.Pi / 4}, &Square{Side: 1, Center: Pt(1.2, 1.2)}, true},
+		// 一个包含另一个
+		{&Square{Side: 10}, Triangle{Pt(0, 0), Pt(1, 0), Pt(0, 1)}, true},
+		{tri, Circle{Center: Pt(3, 3), Radius: 1.4}, false},
+		{tri, Circle{Center: Pt(3, 3), Radius: 1.5}, true},
+		{Circle{Radius: 1}, Circle{Center: Pt(2, 0), Radius: 1}, true},
+		{Circle{Radius: 1}, Circle{Center: Pt(1.5, 1.5), Radius: 1}, false},
+		{Ellipse{RX: 3, RY: 1}, Circle{Center: Pt(0, 2.5), Radius: 1.45}, false},
+		{Ellipse{RX: 3, RY: 1}, Circle{Center: Pt(0, 2.5), Radius: 1.55}, true},
+		{Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2}, Rectangle{Length: 1, Width: 1, Center: Pt(1.6, 0)}, false},
+		{Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2}, Rectangle{Length: 1, Width: 1, Center: Pt(1.4, 0)}, true},
+		{Ellipse{RX: 3, RY: 1}, Ellipse{RX: 3, RY: 1, Center: Pt(0, 2.1)}, false},
+		{Ellipse{RX: 3, RY: 1}, Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2, Center: Pt(0, 3.9)}, true},
+		{Polygon{[]Point{Pt(0, 0), Pt(4, 0), Pt(4, 1), Pt(1, 1), Pt(1, 4), Pt(0, 4)}}, Circle{Center: Pt(2.5, 2.5), Radius: 1}, false},
+		// 切点 (3, 0)，两个椭圆的轴不平行
+		{Ellipse{RX: 3, RY: 1}, Ellipse{RX: 2, RY: 0.5, Angle: math.Pi / 2, Center: Pt(3.501, 0)}, false},
+		{Ellipse{RX: 3, RY: 1}, Ellipse{RX: 2, RY: 0.5, Angle: math.Pi / 2, Center: Pt(3.499, 0)}, true},
+		// 大椭圆的切点不在 256 边形的顶点上，多边形近似在这里会差 0.05 左右
+		{Ellipse{RX: 1000, RY: 1000}, Ellipse{RX: 1, RY: 1, Center: polar(1000.99, math.Pi/180)}, true},
+		{Ellipse{RX: 1000, RY: 1000}, Ellipse{RX: 1, RY: 1, Center: polar(1001.01, math.Pi/180)}, false},
+		{Ellipse{RX: 1000, RY: 10, Angle: 0.3}, Ellipse{RX: 5, RY: 2, Angle: 1.1, Center: Pt(0, 1000)}, false},
+		// 指针和 Styled 包装同样使用精确检测，而不是只看包围盒
+		{&Circle{Radius: 1}, &Circle{Center: Pt(1.5, 1.5), Radius: 1}, false},
+		{&Circle{Radius: 1}, &Circle{Center: Pt(1.4, 1.4), Radius: 1}, true},
+		{&Ellipse{RX: 3, RY: 1}, Circle{Center: Pt(0, 2.5), Radius: 1.45}, false},
+		{Styled{Shaper: Ellipse{RX: 3, RY: 1}}, &Circle{Center: Pt(0, 2.5), Radius: 1.45}, false},
+		{Styled{Shaper: &Square{Side: 2, Angle: math.Pi / 4}}, Styled{Shaper: &Square{Side: 1, Center: Pt(1.3, 1.3)}}, false},
+		{Styled{Shaper: &Ellipse{RX: 3, RY: 1}}, &Rectangle{Length: 1, Width: 1, Center: Pt(0, 1.4)}, true},
+	}
+	for _, tt := range tests {
+		if got := ShapesIntersect(tt.a, tt.b); got != tt.want {
+			t.Errorf("ShapesIntersect(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := ShapesIntersect(tt.b, tt.a); got != tt.want {
+			t.Errorf("ShapesIntersect(%v, %v) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestShapeDistance(t *testing.T) {
+	tests := []struct {
+		s    Shaper
+		p    Point
+		want float64
+	}{
+		{&Square{Side: 2}, Pt(0.5, 0.5), 0},
+		{&Square{Side: 2}, Pt(4, 5), 5},
+		{Rectangle{Length: 4, Width: 2}, Pt(0, -3), 2},
+		{Circle{Center: Pt(1, 1), Radius: 1}, Pt(4, 5), 4},
+		{Ellipse{RX: 3, RY: 1}, Pt(5, 0), 2},
+		{Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2}, Pt(0, -5), 2},
+		{Ellipse{RX: 2, RY: 2, Center: Pt(1, 1)}, Pt(4, 5), 3},
+		{Triangle{Pt(0, 0), Pt(2, 0), Pt(0, 2)}, Pt(2, 2), math.Sqrt2},
+		{&Circle{Center: Pt(1, 1), Radius: 1}, Pt(4, 5), 4},
+		{Styled{Shaper: &Ellipse{RX: 3, RY: 1}}, Pt(5, 0), 2},
+	}
+	for _, tt := range tests {
+		if got := ShapeDistance(tt.s, tt.p); !near(got, tt.want) {
+			t.Errorf("ShapeDistance(%v, %v) = %v, want %v", tt.s, tt.p, got, tt.want)
+		}
+	}
+}
+
+func polar(r, angle float64) Point {
+	sin, cos := math.Sincos(angle)
+	return Pt(r*cos, r*sin)
+}
+
+// TestEllipsesIntersectSampled 和在 b 的边界上密集采样的结果比较，跳过接近相切的情况
+func TestEllipsesIntersectSampled(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for i := 0; i < 300; i++ {
+		a := Ellipse{RX: 0.2 + 3*r.Float64(), RY: 0.2 + 3*r.Float64(), Angle: 2 * math.Pi * r.Float64()}
+		b := Ellipse{RX: 0.2 + 3*r.Float64(), RY: 0.2 + 3*r.Float64(), Angle: 2 * math.Pi * r.Float64(),
+			Center: Pt(8*r.Float64()-4, 8*r.Float64()-4)}
+		gap := math.Inf(1)
+		if a.Contains(b.Center) || b.Contains(a.Center) {
+			gap = -1
+		}
+		for _, p := range ellipsePolygon(b, 4096) {
+			if a.Contains(p) {
+				gap = -1
+				break
+			}
+			gap = math.Min(gap, ellipseDistance(a, p))
+		}
+		if math.Abs(gap) < 1e-2 {
+			continue
+		}
+		if got, want := ShapesIntersect(a, b), gap < 0; got != want {
+			t.Errorf("ShapesIntersect(%v, %v) = %v, sampled gap %v", a, b, got, gap)
+		}
+	}
+}
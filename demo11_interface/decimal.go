@@ -0,0 +1,403 @@
+package demo11_interface
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// 定点小数
+/*
+1:Decimal 的值是 unscaled × 10^-scale，unscaled 是 int64，scale 在 [0, MaxDecimalScale] 之间，
+  例如 459800.00 是 (45980000, 2)。零值是 0。
+2:Add、Sub 的结果是精确的，scale 取两者中较大的一个。Mul 的 scale 也取两者中较大的一个，按调用方给的舍入方式舍入，
+  例如 88.99 × 0.3333333333333333 得到 16 位小数，而不是 18 位以后再溢出。
+  Div 和 Round 需要指定结果的 scale 和舍入方式。DecimalContext 把 scale 和舍入方式固定下来，每次运算后都舍入。
+3:运算的中间结果用 math/big 计算，结果超出 int64 范围时返回 ErrDecimalOverflow，不会 panic。
+4:scale 不同的相等的值（1.5 和 1.50）用 == 比较不相等，比较大小应该使用 Cmp。
+5:文本格式是普通的十进制写法，总是输出 scale 位小数。JSON 编码为数字字面量，解码同时接受数字和字符串；
+  实现了 database/sql 的 driver.Valuer 和 sql.Scanner，数据库中以字符串保存。
+*/
+
+// MaxDecimalScale 最多的小数位数
+const MaxDecimalScale = 18
+
+// RoundingMode 舍入方式
+type RoundingMode int
+
+const (
+	// RoundHalfEven 四舍六入五成双（银行家舍入）
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp 四舍五入，5 向远离零的方向进位
+	RoundHalfUp
+	// RoundDown 向零截断
+	RoundDown
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundHalfEven:
+		return "half-even"
+	case RoundHalfUp:
+		return "half-up"
+	case RoundDown:
+		return "down"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+var (
+	// ErrDecimalOverflow 结果超出 Decimal 的表示范围
+	ErrDecimalOverflow = errors.New("decimal overflow")
+	// ErrDivisionByZero 除数为零
+	ErrDivisionByZero = errors.New("decimal division by zero")
+)
+
+// Decimal 定点小数
+type Decimal struct {
+	unscaled int64
+	scale    int8
+}
+
+// NewDecimal 返回 unscaled × 10^-scale
+func NewDecimal(unscaled int64, scale int) Decimal {
+	checkScale(scale)
+	return Decimal{unscaled: unscaled, scale: int8(scale)}
+}
+
+// ParseDecimal 解析 [+-]digits[.digits] 形式的小数，scale 等于小数点后的位数
+func ParseDecimal(s string) (Decimal, error) {
+	text := s
+	neg := false
+	if text != "" && (text[0] == '+' || text[0] == '-') {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	intPart, fracPart, hasPoint := strings.Cut(text, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) || hasPoint && fracPart == "" {
+		return Decimal{}, fmt.Errorf("decimal: invalid syntax %q", s)
+	}
+	if len(fracPart) > MaxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal: %q has more than %d decimal places", s, MaxDecimalScale)
+	}
+	b, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if neg {
+		b.Neg(b)
+	}
+	if !b.IsInt64() {
+		return Decimal{}, fmt.Errorf("decimal: %q: %w", s, ErrDecimalOverflow)
+	}
+	return Decimal{unscaled: b.Int64(), scale: int8(len(fracPart))}, nil
+}
+
+// MustParseDecimal 和 ParseDecimal 一样，出错时 panic，用于常量
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromFloat 把浮点数转换为能精确还原它的最短小数，小数位数超过 MaxDecimalScale 时按 RoundHalfEven 舍入，
+// 舍入后去掉末尾的零
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("decimal: cannot convert %v", f)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if _, frac, _ := strings.Cut(s, "."); len(frac) > MaxDecimalScale {
+		s = strconv.FormatFloat(f, 'f', MaxDecimalScale, 64)
+	}
+	d, err := ParseDecimal(s)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return d.Reduce(), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func checkScale(scale int) {
+	if scale < 0 || scale > MaxDecimalScale {
+		panic(fmt.Sprintf("decimal: scale %d out of range [0, %d]", scale, MaxDecimalScale))
+	}
+}
+
+// Scale 小数位数
+func (d Decimal) Scale() int {
+	return int(d.scale)
+}
+
+// Unscaled 去掉小数点后的整数
+func (d Decimal) Unscaled() int64 {
+	return d.unscaled
+}
+
+// Sign 返回 -1、0 或 1
+func (d Decimal) Sign() int {
+	switch {
+	case d.unscaled < 0:
+		return -1
+	case d.unscaled > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero 值是否为 0
+func (d Decimal) IsZero() bool {
+	return d.unscaled == 0
+}
+
+// Cmp 比较大小，不考虑 scale
+func (d Decimal) Cmp(e Decimal) int {
+	a, b := align(d, e)
+	return a.Cmp(b)
+}
+
+// Neg 相反数
+func (d Decimal) Neg() (Decimal, error) {
+	return fromBig(new(big.Int).Neg(d.big()), int(d.scale))
+}
+
+// Abs 绝对值
+func (d Decimal) Abs() (Decimal, error) {
+	if d.unscaled < 0 {
+		return d.Neg()
+	}
+	return d, nil
+}
+
+// Add 精确的加法
+func (d Decimal) Add(e Decimal) (Decimal, error) {
+	a, b := align(d, e)
+	return fromBig(a.Add(a, b), max(int(d.scale), int(e.scale)))
+}
+
+// Sub 精确的减法
+func (d Decimal) Sub(e Decimal) (Decimal, error) {
+	a, b := align(d, e)
+	return fromBig(a.Sub(a, b), max(int(d.scale), int(e.scale)))
+}
+
+// Mul 乘法，结果的 scale 是两者中较大的一个，多出的小数位按 mode 舍入。需要其他 scale 时使用 DecimalContext.Mul
+func (d Decimal) Mul(e Decimal, mode RoundingMode) (Decimal, error) {
+	return DecimalContext{Scale: max(int(d.scale), int(e.scale)), Mode: mode}.Mul(d, e)
+}
+
+// Div 除法，结果舍入到 scale 位小数
+func (d Decimal) Div(e Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	checkScale(scale)
+	return quo(d.big(), int(d.scale), e, scale, mode)
+}
+
+// quo 计算 (num × 10^-numScale) / e，舍入到 scale 位小数，num 的 scale 可以超过 MaxDecimalScale
+func quo(num *big.Int, numScale int, e Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	if e.IsZero() {
+		return Decimal{}, ErrDivisionByZero
+	}
+	// num/e = (num × 10^(scale + e.scale - numScale)) / e.unscaled × 10^-scale
+	den := e.big()
+	if shift := scale + int(e.scale) - numScale; shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	if den.Sign() < 0 {
+		num.Neg(num)
+		den.Neg(den)
+	}
+	return fromBig(roundQuo(num, den, mode), scale)
+}
+
+// Round 舍入到 scale 位小数，scale 大于当前位数时补零
+func (d Decimal) Round(scale int, mode RoundingMode) (Decimal, error) {
+	checkScale(scale)
+	if scale >= int(d.scale) {
+		b := d.big()
+		return fromBig(b.Mul(b, pow10(scale-int(d.scale))), scale)
+	}
+	return fromBig(roundQuo(d.big(), pow10(int(d.scale)-scale), mode), scale)
+}
+
+// Reduce 去掉小数末尾的零，例如 1.500 变成 1.5，值不变
+func (d Decimal) Reduce() Decimal {
+	for d.scale > 0 && d.unscaled%10 == 0 {
+		d.unscaled /= 10
+		d.scale--
+	}
+	return d
+}
+
+// Float64 最接近的浮点数
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String 十进制表示，总是有 scale 位小数
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.unscaled, 10)
+	if d.scale == 0 {
+		return s
+	}
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	if n := int(d.scale) + 1 - len(s); n > 0 {
+		s = strings.Repeat("0", n) + s
+	}
+	point := len(s) - int(d.scale)
+	return sign + s[:point] + "." + s[point:]
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON 编码为 JSON 数字，保留所有小数位
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON 接受 JSON 数字和字符串，不接受指数形式
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if uq, err := strconv.Unquote(s); err == nil && len(s) > 0 && s[0] == '"' {
+		s = uq
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Value 实现 driver.Valuer，以字符串写入数据库
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan 实现 sql.Scanner，接受字符串、[]byte、整数和浮点数
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	case int64:
+		*d = NewDecimal(v, 0)
+		return nil
+	case float64:
+		f, err := DecimalFromFloat(v)
+		if err != nil {
+			return err
+		}
+		*d = f
+		return nil
+	}
+	return fmt.Errorf("decimal: cannot scan %T", src)
+}
+
+// DecimalContext 固定结果的小数位数和舍入方式
+type DecimalContext struct {
+	Scale int
+	Mode  RoundingMode
+}
+
+func (c DecimalContext) Add(a, b Decimal) (Decimal, error) {
+	sum, err := a.Add(b)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return sum.Round(c.Scale, c.Mode)
+}
+
+func (c DecimalContext) Sub(a, b Decimal) (Decimal, error) {
+	diff, err := a.Sub(b)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return diff.Round(c.Scale, c.Mode)
+}
+
+// Mul 先精确相乘再舍入，只舍入一次
+func (c DecimalContext) Mul(a, b Decimal) (Decimal, error) {
+	p := new(big.Int).Mul(a.big(), b.big())
+	scale := int(a.scale) + int(b.scale)
+	checkScale(c.Scale)
+	if scale <= c.Scale {
+		return fromBig(p.Mul(p, pow10(c.Scale-scale)), c.Scale)
+	}
+	return fromBig(roundQuo(p, pow10(scale-c.Scale), c.Mode), c.Scale)
+}
+
+func (c DecimalContext) Div(a, b Decimal) (Decimal, error) {
+	return a.Div(b, c.Scale, c.Mode)
+}
+
+func (d Decimal) big() *big.Int {
+	return big.NewInt(d.unscaled)
+}
+
+func fromBig(b *big.Int, scale int) (Decimal, error) {
+	if !b.IsInt64() {
+		return Decimal{}, fmt.Errorf("decimal: %s × 10^-%d: %w", b, scale, ErrDecimalOverflow)
+	}
+	return Decimal{unscaled: b.Int64(), scale: int8(scale)}, nil
+}
+
+// align 把两个数放大到相同的 scale
+func align(d, e Decimal) (*big.Int, *big.Int) {
+	a, b := d.big(), e.big()
+	switch {
+	case d.scale < e.scale:
+		a.Mul(a, pow10(int(e.scale-d.scale)))
+	case d.scale > e.scale:
+		b.Mul(b, pow10(int(d.scale-e.scale)))
+	}
+	return a, b
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo 计算 num/den 并按 mode 舍入到整数，den 必须为正
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 || mode == RoundDown {
+		return q
+	}
+	// 比较余数的两倍和除数，判断是否过半
+	half := r.Abs(r).Lsh(r, 1).Cmp(den)
+	if half > 0 || half == 0 && (mode == RoundHalfUp || q.Bit(0) == 1) {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
@@ -0,0 +1,211 @@
+package demo11_interface
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecimalParseFormat(t *testing.T) {
+	tests := []struct {
+		in, out string
+		scale   int
+	}{
+		{"0", "0", 0},
+		{"459800.00", "459800.00", 2},
+		{"-0.05", "-0.05", 2},
+		{"+.5", "0.5", 1},
+		{"007.010", "7.010", 3},
+		{"-9223372036854775808", "-9223372036854775808", 0},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if err != nil || d.String() != tt.out || d.Scale() != tt.scale {
+			t.Errorf("ParseDecimal(%q) = %v (scale %d), %v; want %s (scale %d)", tt.in, d, d.Scale(), err, tt.out, tt.scale)
+		}
+	}
+	for _, in := range []string{"", "-", ".", "1.", "1e3", "1,5", "0x10", "9223372036854775808", "0.1234567890123456789"} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q): want error", in)
+		}
+	}
+	x := 0.1
+	floats := []struct {
+		in   float64
+		want string
+	}{
+		{x + 0.2, "0.30000000000000004"},
+		{1.0 / 3, "0.3333333333333333"},
+		{2.5e-17, "0.000000000000000025"},
+		// 舍入到 18 位后末尾的零被去掉
+		{1.5e-18 + 1e-30, "0.000000000000000002"},
+		{4e-19, "0"},
+		{1200, "1200"},
+	}
+	for _, tt := range floats {
+		if d, err := DecimalFromFloat(tt.in); err != nil || d.String() != tt.want {
+			t.Errorf("DecimalFromFloat(%v) = %v, %v; want %s", tt.in, d, err, tt.want)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	d := MustParseDecimal
+	// float32 算不准的例子
+	sp := stockPosition{ticker: "ESOP", sharePrice: d("88.99"), count: d("10")}
+	if got, err := sp.getValue(); err != nil || got.String() != "889.90" {
+		t.Errorf("stockPosition value = %s, %v; want 889.90", got, err)
+	}
+	if got, _ := (car{price: d("16777217.01")}).getValue(); got.String() != "16777217.01" {
+		t.Errorf("car value = %s", got)
+	}
+
+	neg, _ := d("1").Neg()
+	tests := []struct {
+		op   func() (Decimal, error)
+		want string
+	}{
+		{func() (Decimal, error) { return d("0.1").Add(d("0.2")) }, "0.3"},
+		{func() (Decimal, error) { return d("1.5").Sub(d("2.25")) }, "-0.75"},
+		{func() (Decimal, error) { return d("-1.5").Mul(d("1.25"), RoundHalfEven) }, "-1.88"},
+		{func() (Decimal, error) { return d("-1.5").Mul(d("1.25"), RoundDown) }, "-1.87"},
+		{func() (Decimal, error) { return d("88.99").Mul(d("10"), RoundHalfEven) }, "889.90"},
+		{neg.Abs, "1"},
+		{func() (Decimal, error) { return d("12.5").Round(4, RoundDown) }, "12.5000"},
+		{func() (Decimal, error) { return d("12.500").Reduce(), nil }, "12.5"},
+	}
+	for i, tt := range tests {
+		if got, err := tt.op(); err != nil || got.String() != tt.want {
+			t.Errorf("%d: got %s, %v; want %s", i, got, err, tt.want)
+		}
+	}
+	if d("1.50").Cmp(d("1.5")) != 0 || d("-2").Cmp(d("1.99")) != -1 || d("1.50") == d("1.5") {
+		t.Error("Cmp ignores scale, == does not")
+	}
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		in                     string
+		halfEven, halfUp, down string
+	}{
+		{"2.345", "2.34", "2.35", "2.34"},
+		{"2.355", "2.36", "2.36", "2.35"},
+		{"-2.345", "-2.34", "-2.35", "-2.34"},
+		{"2.3451", "2.35", "2.35", "2.34"},
+		{"-2.349", "-2.35", "-2.35", "-2.34"},
+		{"2.3", "2.30", "2.30", "2.30"},
+	}
+	for _, tt := range tests {
+		d := MustParseDecimal(tt.in)
+		for mode, want := range map[RoundingMode]string{RoundHalfEven: tt.halfEven, RoundHalfUp: tt.halfUp, RoundDown: tt.down} {
+			if got, err := d.Round(2, mode); err != nil || got.String() != want {
+				t.Errorf("%s rounded %v = %s, %v; want %s", tt.in, mode, got, err, want)
+			}
+		}
+	}
+
+	div := []struct {
+		a, b  string
+		scale int
+		mode  RoundingMode
+		want  string
+	}{
+		{"10", "3", 4, RoundHalfEven, "3.3333"},
+		{"2", "3", 2, RoundHalfUp, "0.67"},
+		{"2", "3", 2, RoundDown, "0.66"},
+		{"-1", "8", 2, RoundHalfEven, "-0.12"},
+		{"1", "-0.08", 0, RoundHalfUp, "-13"},
+		{"459800.00", "0.14", 2, RoundHalfEven, "3284285.71"},
+	}
+	for _, tt := range div {
+		got, err := MustParseDecimal(tt.a).Div(MustParseDecimal(tt.b), tt.scale, tt.mode)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s / %s = %v, %v; want %s", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := NewDecimal(1, 0).Div(Decimal{}, 2, RoundHalfEven); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("division by zero: got %v", err)
+	}
+
+	// 先精确相乘再舍入：0.125 × 1 舍入到 2 位是 0.12（五成双），不是先把 0.125 舍入
+	ctx := DecimalContext{Scale: 2, Mode: RoundHalfEven}
+	if got, _ := ctx.Mul(MustParseDecimal("0.125"), NewDecimal(1, 0)); got.String() != "0.12" {
+		t.Errorf("ctx.Mul = %s", got)
+	}
+	if got, _ := ctx.Add(MustParseDecimal("0.005"), MustParseDecimal("0.01")); got.String() != "0.02" {
+		t.Errorf("ctx.Add = %s", got)
+	}
+}
+
+func TestDecimalOverflow(t *testing.T) {
+	large := NewDecimal(1<<62, 0)
+	ops := map[string]func() (Decimal, error){
+		"add":   func() (Decimal, error) { return large.Add(large) },
+		"sub":   func() (Decimal, error) { return NewDecimal(-(1<<62)-1, 0).Sub(large) },
+		"mul":   func() (Decimal, error) { return large.Mul(NewDecimal(2, 0), RoundHalfEven) },
+		"neg":   NewDecimal(math.MinInt64, 0).Neg,
+		"round": func() (Decimal, error) { return large.Round(2, RoundDown) },
+		"div":   func() (Decimal, error) { return large.Div(MustParseDecimal("0.5"), 0, RoundDown) },
+	}
+	for name, op := range ops {
+		if _, err := op(); !errors.Is(err, ErrDecimalOverflow) {
+			t.Errorf("%s: got %v, want ErrDecimalOverflow", name, err)
+		}
+	}
+}
+
+// TestDecimalFractionalShares 小数股数的估值不会因为 scale 相加而溢出
+func TestDecimalFractionalShares(t *testing.T) {
+	third, err := DecimalFromFloat(1.0 / 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MustParseDecimal("88.99").Mul(third, RoundHalfEven)
+	if err != nil || got.String() != "29.6633333333333304" {
+		t.Errorf("88.99 × %s = %v, %v", third, got, err)
+	}
+	sp := stockPosition{ticker: "ESOP", sharePrice: MustParseDecimal("88.99"), count: MustParseDecimal("2.5")}
+	if got, err := sp.getValue(); err != nil || got.String() != "222.48" {
+		t.Errorf("2.5 shares = %v, %v; want 222.48", got, err)
+	}
+}
+
+func TestDecimalMarshal(t *testing.T) {
+	type price struct {
+		Amount Decimal  `json:"amount"`
+		Tax    *Decimal `json:"tax"`
+	}
+	in := price{Amount: MustParseDecimal("459800.10")}
+	data, err := json.Marshal(in)
+	if err != nil || string(data) != `{"amount":459800.10,"tax":null}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	var out price
+	if err := json.Unmarshal([]byte(`{"amount":"459800.10","tax":0.13}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Amount != in.Amount || out.Tax == nil || out.Tax.String() != "0.13" {
+		t.Errorf("Unmarshal = %+v", out)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":1e3}`), &out); err == nil || !strings.Contains(err.Error(), "invalid syntax") {
+		t.Errorf("exponent: got %v", err)
+	}
+
+	// database/sql 风格
+	v, _ := in.Amount.Value()
+	var scanned Decimal
+	for _, src := range []any{v, []byte("459800.10"), int64(7), 0.25} {
+		if err := scanned.Scan(src); err != nil {
+			t.Errorf("Scan(%#v): %v", src, err)
+		}
+	}
+	if scanned.String() != "0.25" {
+		t.Errorf("scanned %v", scanned)
+	}
+	if err := scanned.Scan(true); err == nil {
+		t.Error("Scan(bool): want error")
+	}
+}
@@ -0,0 +1,533 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 外部排序
+/*
+1:数据量超过内存时，先按内存预算把输入切成若干块，每块用 Stable 在内存中排好序后写到临时文件（称为一个有序段 run），
+  再用堆对所有有序段做 k 路归并，输出到 out。
+2:有序段太多时分多轮归并，每轮最多同时打开 MaxFanIn 个文件。
+3:块内使用 Stable，归并时相等的记录按有序段的先后输出，所以整个外部排序也是稳定的。
+4:记录的读写方式由 RecordCodec 决定，内置按行、长度前缀二进制和 JSON Lines 三种。
+5:比较需要先解析记录时（例如 ByJSONField）使用 RecordKey：每条记录读入内存块或者归并读到它时只提取一次键，
+  排序和归并都比较缓存的键，而不是每次比较都重新解析两条记录。
+*/
+
+// RecordReader 逐条读取记录，读完时返回 io.EOF，返回的切片归调用方所有
+type RecordReader interface {
+	ReadRecord() ([]byte, error)
+}
+
+// RecordWriter 逐条写入记录
+type RecordWriter interface {
+	WriteRecord(rec []byte) error
+	Flush() error
+}
+
+// RecordCodec 记录格式
+type RecordCodec interface {
+	NewReader(r io.Reader) RecordReader
+	NewWriter(w io.Writer) RecordWriter
+}
+
+// LineCodec 每行一条记录，记录中不包含换行符；读取时 \r\n 按 \n 处理
+type LineCodec struct{}
+
+func (LineCodec) NewReader(r io.Reader) RecordReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+func (LineCodec) NewWriter(w io.Writer) RecordWriter {
+	return &lineWriter{w: bufio.NewWriter(w)}
+}
+
+type lineReader struct {
+	r *bufio.Reader
+}
+
+func (lr *lineReader) ReadRecord() ([]byte, error) {
+	line, err := lr.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		return bytes.TrimSuffix(line, []byte{'\r'}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'}), nil
+}
+
+type lineWriter struct {
+	w *bufio.Writer
+}
+
+func (lw *lineWriter) WriteRecord(rec []byte) error {
+	if bytes.IndexByte(rec, '\n') >= 0 {
+		return errors.New("extsort: line record contains a newline")
+	}
+	lw.w.Write(rec)
+	return lw.w.WriteByte('\n')
+}
+
+func (lw *lineWriter) Flush() error {
+	return lw.w.Flush()
+}
+
+// LengthPrefixedCodec 每条记录前是 4 字节大端序的长度
+type LengthPrefixedCodec struct {
+	// MaxSize 单条记录的字节数上限，为 0 时是 DefaultMaxRecordSize；
+	// 读到更大的长度时报错，损坏的文件不会导致申请巨大的内存
+	MaxSize int
+}
+
+// DefaultMaxRecordSize LengthPrefixedCodec 默认的单条记录上限
+const DefaultMaxRecordSize = 64 << 20
+
+func (c LengthPrefixedCodec) maxSize() uint64 {
+	if c.MaxSize > 0 {
+		return uint64(c.MaxSize)
+	}
+	return DefaultMaxRecordSize
+}
+
+func (c LengthPrefixedCodec) NewReader(r io.Reader) RecordReader {
+	return &lengthPrefixedReader{r: bufio.NewReader(r), max: c.maxSize()}
+}
+
+func (c LengthPrefixedCodec) NewWriter(w io.Writer) RecordWriter {
+	return &lengthPrefixedWriter{w: bufio.NewWriter(w), max: c.maxSize()}
+}
+
+type lengthPrefixedReader struct {
+	r   *bufio.Reader
+	max uint64
+}
+
+func (lr *lengthPrefixedReader) ReadRecord() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(lr.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if uint64(n) > lr.max {
+		return nil, fmt.Errorf("extsort: record length %d exceeds limit %d", n, lr.max)
+	}
+	rec := make([]byte, n)
+	if _, err := io.ReadFull(lr.r, rec); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+type lengthPrefixedWriter struct {
+	w   *bufio.Writer
+	max uint64
+}
+
+func (lw *lengthPrefixedWriter) WriteRecord(rec []byte) error {
+	if uint64(len(rec)) > min(lw.max, 1<<32-1) {
+		return fmt.Errorf("extsort: record length %d exceeds limit %d", len(rec), min(lw.max, 1<<32-1))
+	}
+	lw.w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(rec))))
+	_, err := lw.w.Write(rec)
+	return err
+}
+
+func (lw *lengthPrefixedWriter) Flush() error {
+	return lw.w.Flush()
+}
+
+// JSONLinesCodec 每行一个 JSON 值，读取时跳过空行并校验 JSON 格式
+type JSONLinesCodec struct{}
+
+func (JSONLinesCodec) NewReader(r io.Reader) RecordReader {
+	return &jsonLinesReader{lines: lineReader{r: bufio.NewReader(r)}}
+}
+
+func (JSONLinesCodec) NewWriter(w io.Writer) RecordWriter {
+	return &lineWriter{w: bufio.NewWriter(w)}
+}
+
+type jsonLinesReader struct {
+	lines  lineReader
+	lineNo int
+}
+
+func (jr *jsonLinesReader) ReadRecord() ([]byte, error) {
+	for {
+		line, err := jr.lines.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		jr.lineNo++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("extsort: invalid JSON on line %d", jr.lineNo)
+		}
+		return line, nil
+	}
+}
+
+// RecordKey 先从记录中提取排序键再比较，Extract 对每条记录只调用一次
+type RecordKey struct {
+	Extract func(rec []byte) any
+	Less    func(a, b any) bool
+}
+
+// ByJSONField 按 JSON 对象中某个字段排序：数字按数值，字符串按字典序，缺少该字段的排在最前面
+func ByJSONField(field string) RecordKey {
+	extract := func(rec []byte) any {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(rec, &obj) != nil {
+			return nil
+		}
+		var v any
+		d := json.NewDecoder(bytes.NewReader(obj[field]))
+		d.UseNumber()
+		if d.Decode(&v) != nil {
+			return nil
+		}
+		// 数字转换一次，比较时不再解析
+		if n, ok := v.(json.Number); ok {
+			f, _ := n.Float64()
+			return f
+		}
+		return v
+	}
+	return RecordKey{Extract: extract, Less: jsonValueLess}
+}
+
+// jsonValueLess 不同类型之间按 null < bool < 数字 < 字符串 < 其他 排序
+func jsonValueLess(a, b any) bool {
+	ra, rb := jsonRank(a), jsonRank(b)
+	if ra != rb {
+		return ra < rb
+	}
+	switch x := a.(type) {
+	case bool:
+		return !x && b.(bool)
+	case float64:
+		return x < b.(float64)
+	case string:
+		return x < b.(string)
+	}
+	return false
+}
+
+func jsonRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
+
+// ExternalSorter 外部排序器
+type ExternalSorter struct {
+	// Codec 输入输出以及临时文件的记录格式
+	Codec RecordCodec
+	// Less 比较两条记录，设置了 Key 时不使用
+	Less func(a, b []byte) bool
+	// Key 按提取出的键比较，不为 nil 时代替 Less
+	Key *RecordKey
+	// MemoryLimit 内存中缓存记录的字节数上限
+	MemoryLimit int
+	// TempDir 临时文件目录，为空时使用 os.TempDir()
+	TempDir string
+	// MaxFanIn 每轮归并最多同时打开的有序段个数
+	MaxFanIn int
+
+	// runs 最近一次排序生成的有序段个数
+	runs int
+}
+
+const (
+	defaultMaxFanIn = 64
+	// recordOverhead 每条记录除内容外的内存开销估计（切片头和键的接口值）
+	recordOverhead = 40
+)
+
+// NewExternalSorter 创建外部排序器，memoryLimit 是内存预算（字节）
+func NewExternalSorter(codec RecordCodec, less func(a, b []byte) bool, memoryLimit int) *ExternalSorter {
+	return &ExternalSorter{Codec: codec, Less: less, MemoryLimit: memoryLimit, MaxFanIn: defaultMaxFanIn}
+}
+
+// NewExternalSorterByKey 创建按 RecordKey 排序的外部排序器
+func NewExternalSorterByKey(codec RecordCodec, key RecordKey, memoryLimit int) *ExternalSorter {
+	return &ExternalSorter{Codec: codec, Key: &key, MemoryLimit: memoryLimit, MaxFanIn: defaultMaxFanIn}
+}
+
+// keyedRecord 记录和它的排序键，没有设置 Key 时 key 为 nil
+type keyedRecord struct {
+	rec []byte
+	key any
+}
+
+// recordOrder 比较 keyedRecord，按键或者直接按记录
+type recordOrder struct {
+	extract func(rec []byte) any
+	less    func(a, b keyedRecord) bool
+}
+
+func (s *ExternalSorter) order() recordOrder {
+	if s.Key != nil {
+		less := s.Key.Less
+		return recordOrder{
+			extract: s.Key.Extract,
+			less:    func(a, b keyedRecord) bool { return less(a.key, b.key) },
+		}
+	}
+	less := s.Less
+	return recordOrder{less: func(a, b keyedRecord) bool { return less(a.rec, b.rec) }}
+}
+
+func (o recordOrder) record(rec []byte) keyedRecord {
+	if o.extract == nil {
+		return keyedRecord{rec: rec}
+	}
+	return keyedRecord{rec: rec, key: o.extract(rec)}
+}
+
+// recordChunk 内存中的一块记录，通过 Sorter 接口交给 Stable 排序
+type recordChunk struct {
+	recs []keyedRecord
+	less func(a, b keyedRecord) bool
+}
+
+func (c recordChunk) Len() int           { return len(c.recs) }
+func (c recordChunk) Less(i, j int) bool { return c.less(c.recs[i], c.recs[j]) }
+func (c recordChunk) Swap(i, j int)      { c.recs[i], c.recs[j] = c.recs[j], c.recs[i] }
+
+// Sort 读取 in 中的全部记录，排序后写入 out
+func (s *ExternalSorter) Sort(in io.Reader, out io.Writer) error {
+	if s.Codec == nil || s.Key == nil && s.Less == nil {
+		return errors.New("extsort: Codec and Less (or Key) are required")
+	}
+	if s.Key != nil && (s.Key.Extract == nil || s.Key.Less == nil) {
+		return errors.New("extsort: Key needs both Extract and Less")
+	}
+	if s.MemoryLimit <= 0 {
+		return errors.New("extsort: MemoryLimit must be positive")
+	}
+	fanIn := s.MaxFanIn
+	if fanIn < 2 {
+		fanIn = defaultMaxFanIn
+	}
+
+	// temps 记录创建过的全部临时文件，出错时也能清理干净
+	var runs, temps []string
+	defer func() {
+		for _, name := range temps {
+			os.Remove(name)
+		}
+	}()
+
+	order := s.order()
+	r := s.Codec.NewReader(in)
+	chunk := recordChunk{less: order.less}
+	used := 0
+	eof := false
+	for !eof {
+		rec, err := r.ReadRecord()
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return err
+		} else {
+			chunk.recs = append(chunk.recs, order.record(rec))
+			used += len(rec) + recordOverhead
+		}
+		if used < s.MemoryLimit && !eof {
+			continue
+		}
+		if len(chunk.recs) == 0 {
+			break
+		}
+		Stable(chunk)
+		// 只有一块时直接输出，不需要临时文件
+		if eof && len(runs) == 0 {
+			s.runs = 1
+			return writeRecords(s.Codec.NewWriter(out), chunk.recs)
+		}
+		name, err := s.writeRun(chunk.recs)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, name)
+		temps = append(temps, name)
+		clear(chunk.recs)
+		chunk.recs = chunk.recs[:0]
+		used = 0
+	}
+	s.runs = len(runs)
+
+	// 多轮归并，直到剩下的有序段可以一次合并到 out
+	for len(runs) > fanIn {
+		var next []string
+		for len(runs) > 0 {
+			n := min(fanIn, len(runs))
+			name, err := s.mergeToTemp(runs[:n])
+			if err != nil {
+				return err
+			}
+			temps = append(temps, name)
+			for _, old := range runs[:n] {
+				os.Remove(old)
+			}
+			runs = runs[n:]
+			next = append(next, name)
+		}
+		runs = next
+	}
+	return s.merge(runs, s.Codec.NewWriter(out))
+}
+
+func writeRecords(w RecordWriter, recs []keyedRecord) error {
+	for _, rec := range recs {
+		if err := w.WriteRecord(rec.rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (s *ExternalSorter) writeRun(recs []keyedRecord) (string, error) {
+	f, err := os.CreateTemp(s.TempDir, "extsort-run-*")
+	if err != nil {
+		return "", err
+	}
+	err = writeRecords(s.Codec.NewWriter(f), recs)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (s *ExternalSorter) mergeToTemp(runs []string) (string, error) {
+	f, err := os.CreateTemp(s.TempDir, "extsort-merge-*")
+	if err != nil {
+		return "", err
+	}
+	err = s.merge(runs, s.Codec.NewWriter(f))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// merge 用堆对若干有序段做 k 路归并
+func (s *ExternalSorter) merge(runs []string, w RecordWriter) error {
+	order := s.order()
+	h := &mergeHeap{less: order.less}
+	for i, name := range runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src := &mergeSource{r: s.Codec.NewReader(f), order: order, run: i}
+		ok, err := src.next()
+		if err != nil {
+			return fmt.Errorf("extsort: reading run %s: %w", name, err)
+		}
+		if ok {
+			h.sources = append(h.sources, src)
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		src := h.sources[0]
+		if err := w.WriteRecord(src.head.rec); err != nil {
+			return err
+		}
+		ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return w.Flush()
+}
+
+// mergeSource 一个有序段及其当前的第一条记录，键在读入时提取一次
+type mergeSource struct {
+	r     RecordReader
+	order recordOrder
+	head  keyedRecord
+	run   int
+}
+
+func (m *mergeSource) next() (bool, error) {
+	rec, err := m.r.ReadRecord()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	m.head = m.order.record(rec)
+	return true, nil
+}
+
+// mergeHeap 按当前记录排序的小顶堆，记录相等时先输出靠前的有序段以保持稳定
+type mergeHeap struct {
+	sources []*mergeSource
+	less    func(a, b keyedRecord) bool
+}
+
+func (h *mergeHeap) Len() int { return len(h.sources) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	if h.less(a.head, b.head) {
+		return true
+	}
+	if h.less(b.head, a.head) {
+		return false
+	}
+	return a.run < b.run
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+
+func (h *mergeHeap) Push(x any) { h.sources = append(h.sources, x.(*mergeSource)) }
+
+func (h *mergeHeap) Pop() any {
+	last := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return last
+}
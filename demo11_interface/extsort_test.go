@@ -0,0 +1,236 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func bytesLess(a, b []byte) bool {
+	return bytes.Compare(a, b) < 0
+}
+
+func TestExternalSortLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%08d", r.Intn(1e6))
+	}
+	in := strings.Join(lines, "\n")
+
+	for _, fanIn := range []int{2, 3, 64} {
+		dir := t.TempDir()
+		s := NewExternalSorter(LineCodec{}, bytesLess, 4096)
+		s.TempDir = dir
+		s.MaxFanIn = fanIn
+
+		var out bytes.Buffer
+		if err := s.Sort(strings.NewReader(in), &out); err != nil {
+			t.Fatal(err)
+		}
+		if s.runs < 10 {
+			t.Errorf("want many runs with a small memory limit, got %d", s.runs)
+		}
+
+		want := slices.Clone(lines)
+		slices.Sort(want)
+		if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); !slices.Equal(got, want) {
+			t.Errorf("fanIn=%d: output not sorted", fanIn)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("fanIn=%d: %d temp files left behind", fanIn, len(entries))
+		}
+	}
+}
+
+func TestExternalSortInMemory(t *testing.T) {
+	s := NewExternalSorter(LineCodec{}, bytesLess, 1<<20)
+	var out bytes.Buffer
+	if err := s.Sort(strings.NewReader("c\na\nb"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a\nb\nc\n" || s.runs != 1 {
+		t.Errorf("got %q with %d runs", out.String(), s.runs)
+	}
+
+	out.Reset()
+	if err := s.Sort(strings.NewReader(""), &out); err != nil || out.Len() != 0 {
+		t.Errorf("empty input: got %q, %v", out.String(), err)
+	}
+}
+
+func TestExternalSortLengthPrefixed(t *testing.T) {
+	var in bytes.Buffer
+	w := LengthPrefixedCodec{}.NewWriter(&in)
+	var want [][]byte
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 2000; i++ {
+		rec := make([]byte, r.Intn(40))
+		r.Read(rec)
+		want = append(want, rec)
+		if err := w.WriteRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	slices.SortStableFunc(want, bytes.Compare)
+
+	s := NewExternalSorter(LengthPrefixedCodec{}, bytesLess, 2048)
+	s.TempDir = t.TempDir()
+	var out bytes.Buffer
+	if err := s.Sort(&in, &out); err != nil {
+		t.Fatal(err)
+	}
+	rr := LengthPrefixedCodec{}.NewReader(&out)
+	for i, w := range want {
+		got, err := rr.ReadRecord()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !bytes.Equal(got, w) {
+			t.Fatalf("record %d: got %x, want %x", i, got, w)
+		}
+	}
+
+	truncated := []byte{0, 0, 0, 9, 'a'}
+	if err := s.Sort(bytes.NewReader(truncated), &out); err == nil {
+		t.Error("want error for truncated record")
+	}
+}
+
+func TestExternalSortJSONLinesStable(t *testing.T) {
+	var in strings.Builder
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&in, `{"year":%d,"seq":%d}`+"\n", 2000+r.Intn(5), i)
+		if i%100 == 0 {
+			in.WriteString("\n")
+		}
+	}
+
+	s := NewExternalSorterByKey(JSONLinesCodec{}, ByJSONField("year"), 8192)
+	s.TempDir = t.TempDir()
+	s.MaxFanIn = 4
+	var out bytes.Buffer
+	if err := s.Sort(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type rec struct{ Year, Seq int }
+	var prev rec
+	n := 0
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var cur rec
+		if err := json.Unmarshal([]byte(line), &cur); err != nil {
+			t.Fatal(err)
+		}
+		if n > 0 && (cur.Year < prev.Year || cur.Year == prev.Year && cur.Seq < prev.Seq) {
+			t.Fatalf("line %d: %+v after %+v", n, cur, prev)
+		}
+		prev = cur
+		n++
+	}
+	if n != 3000 {
+		t.Errorf("got %d records, want 3000", n)
+	}
+
+	if err := s.Sort(strings.NewReader("{\"a\":1}\n{oops\n"), &out); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("want invalid JSON error on line 2, got %v", err)
+	}
+}
+
+func TestByJSONField(t *testing.T) {
+	key := ByJSONField("v")
+	less := func(a, b []byte) bool { return key.Less(key.Extract(a), key.Extract(b)) }
+	ordered := []string{`{}`, `{"v":false}`, `{"v":true}`, `{"v":-1.5}`, `{"v":10}`, `{"v":"A"}`, `{"v":"b"}`}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := []byte(ordered[i]), []byte(ordered[i+1])
+		if !less(a, b) || less(b, a) {
+			t.Errorf("want %s < %s", a, b)
+		}
+	}
+}
+
+func TestExternalSortExtractsKeysOnce(t *testing.T) {
+	const n = 2000
+	var in strings.Builder
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&in, `{"k":%d}`+"\n", r.Intn(1000))
+	}
+	key := ByJSONField("k")
+	calls := 0
+	extract := key.Extract
+	key.Extract = func(rec []byte) any {
+		calls++
+		return extract(rec)
+	}
+
+	// 一块放得下：每条记录只提取一次
+	s := NewExternalSorterByKey(JSONLinesCodec{}, key, 1<<20)
+	var out bytes.Buffer
+	if err := s.Sort(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if calls != n {
+		t.Errorf("single chunk: %d extractions, want %d", calls, n)
+	}
+
+	// 多个有序段、多轮归并：读入时一次，每轮归并各一次
+	calls = 0
+	s = NewExternalSorterByKey(JSONLinesCodec{}, key, 4096)
+	s.TempDir = t.TempDir()
+	s.MaxFanIn = 4
+	out.Reset()
+	if err := s.Sort(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if s.runs <= 4 {
+		t.Fatalf("runs = %d, want more than MaxFanIn", s.runs)
+	}
+	passes := 1
+	for runs := s.runs; runs > s.MaxFanIn; runs = (runs + s.MaxFanIn - 1) / s.MaxFanIn {
+		passes++
+	}
+	if want := n * (1 + passes); calls != want {
+		t.Errorf("multi-pass: %d extractions, want %d (%d runs)", calls, want, s.runs)
+	}
+}
+
+func TestLineCodecCRLF(t *testing.T) {
+	s := NewExternalSorter(LineCodec{}, bytesLess, 1<<20)
+	var out bytes.Buffer
+	if err := s.Sort(strings.NewReader("b\r\nc\r\na\r"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "a\nb\nc\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLengthPrefixedLimit(t *testing.T) {
+	s := NewExternalSorter(LengthPrefixedCodec{MaxSize: 8}, bytesLess, 1<<20)
+	var out bytes.Buffer
+	// 损坏的长度前缀（约 4GB）不会触发对应大小的内存分配
+	corrupt := []byte{0xff, 0xff, 0xff, 0xf0, 'a'}
+	if err := s.Sort(bytes.NewReader(corrupt), &out); err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("corrupt prefix: %v", err)
+	}
+	if err := s.Sort(bytes.NewReader([]byte{0, 0, 0, 1, 'x'}), &out); err != nil {
+		t.Errorf("small record: %v", err)
+	}
+	w := LengthPrefixedCodec{MaxSize: 8}.NewWriter(&out)
+	if err := w.WriteRecord(make([]byte, 9)); err == nil {
+		t.Error("writing oversized record: want error")
+	}
+	if _, err := (LengthPrefixedCodec{}).NewReader(bytes.NewReader(corrupt)).ReadRecord(); err == nil {
+		t.Error("default limit: want error")
+	}
+}
@@ -0,0 +1,101 @@
+package demo11_interface
+
+import (
+	"errors"
+	"io"
+)
+
+// Demo3:接口嵌套接口
+/*
+1:File 由标准库的小接口组合而成：io.Reader、io.Writer、io.Seeker、io.ReaderAt 和 Lock，
+  任何接受这些小接口的函数（io.Copy、io.ReadAll、io.NewSectionReader 等）都可以直接使用 File。
+2:Read/Write 移动文件偏移量，ReadAt 不使用也不改变偏移量，可以和其他操作并发调用。
+3:Lock/Unlock 是建议性的锁，只约束同样调用 Lock 的使用者，不影响读写本身。
+  需要共享模式、升级降级、超时、租期和死锁检测时，把 File 断言为可选接口 FileLocker，使用 FileLock 返回的 RWLock（见 lock.go）。
+  Lock 本身保持只有 Lock/Unlock，sync.Mutex 这样的普通锁也满足它。
+4:Word（见 word.go）是一个只在内存中的可编辑文档，零值可以直接使用；MemFS（见 memfs.go）打开的文件也实现了 File。
+*/
+
+// ReadWrite 可读可写
+type ReadWrite interface {
+	io.Reader
+	io.Writer
+}
+
+// Lock 建议性锁，Lock/Unlock 是不区分持有者的排他锁
+type Lock interface {
+	Lock()
+	Unlock()
+}
+
+// FileLocker 可选接口，提供功能完整的读写锁，Lock/Unlock 与 RWLock 的排他锁互斥。
+// Word 和 MemFS 打开的文件都实现了它，使用者通过类型断言获取：
+//
+//	if fl, ok := f.(FileLocker); ok {
+//		err := fl.FileLock().TryLock(ctx, owner, Shared)
+//	}
+type FileLocker interface {
+	FileLock() *RWLock
+}
+
+// File 可读写、可定位、可加锁的文件
+type File interface {
+	ReadWrite
+	io.Seeker
+	io.ReaderAt
+	Lock
+	io.Closer
+}
+
+// errNegativeOffset Seek 或 ReadAt 的偏移量为负数
+var errNegativeOffset = errors.New("negative offset")
+
+// readAt 从 data 的 off 处读取，读不满 p 时返回 io.EOF
+func readAt(data, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= int64(len(data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// writeAt 把 p 写到 data 的 off 处，off 超过末尾时中间补零
+func writeAt(data, p []byte, off int64) []byte {
+	if end := off + int64(len(p)); end > int64(len(data)) {
+		if end > int64(cap(data)) {
+			grown := make([]byte, len(data), max(end, 2*int64(cap(data))))
+			copy(grown, data)
+			data = grown
+		}
+		clear(data[len(data):end])
+		data = data[:end]
+	}
+	copy(data[off:], p)
+	return data
+}
+
+// seek 计算新的偏移量，可以超过末尾，不能为负数
+func seek(cur, size, offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += cur
+	case io.SeekEnd:
+		offset += size
+	default:
+		return 0, errors.New("seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	return offset, nil
+}
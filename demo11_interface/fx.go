@@ -0,0 +1,92 @@
+package demo11_interface
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// 汇率
+/*
+1:FXRates 是汇率来源的接口，Portfolio 只通过它换算货币，可以换成任何实现（固定汇率表、行情服务等）。
+2:FXFunc 把普通函数适配为 FXRates，类似 http.HandlerFunc。
+3:RateTable 以一种货币为中心保存汇率，其他货币之间通过中心货币交叉换算。
+4:汇率是 Decimal，交叉汇率保留 CrossRateScale 位小数（RoundHalfEven），并去掉末尾的零。
+*/
+
+// ErrNoRate 没有两种货币之间的汇率
+var ErrNoRate = errors.New("fx rate not available")
+
+// CrossRateScale RateTable 交叉换算出的汇率的小数位数
+const CrossRateScale = 10
+
+// FXRates 汇率来源，Rate 返回 1 单位 from 货币可以兑换多少 to 货币
+type FXRates interface {
+	Rate(from, to string) (Decimal, error)
+}
+
+// FXFunc 函数形式的 FXRates
+type FXFunc func(from, to string) (Decimal, error)
+
+func (f FXFunc) Rate(from, to string) (Decimal, error) {
+	return f(from, to)
+}
+
+// RateTable 以 base 货币为中心的汇率表，可以被多个 goroutine 同时使用
+type RateTable struct {
+	mu    sync.RWMutex
+	base  string
+	rates map[string]Decimal
+}
+
+// NewRateTable 创建汇率表，rates 是 1 单位各货币值多少 base 货币
+func NewRateTable(base string, rates map[string]Decimal) (*RateTable, error) {
+	t := &RateTable{base: base, rates: map[string]Decimal{base: NewDecimal(1, 0)}}
+	for currency, rate := range rates {
+		if err := t.Set(currency, rate); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Base 中心货币
+func (t *RateTable) Base() string {
+	return t.base
+}
+
+// Set 设置 1 单位 currency 值多少 base 货币
+func (t *RateTable) Set(currency string, rate Decimal) error {
+	if rate.Sign() <= 0 {
+		return fmt.Errorf("fx: invalid rate %v for %s", rate, currency)
+	}
+	if currency == t.base && rate.Cmp(NewDecimal(1, 0)) != 0 {
+		return fmt.Errorf("fx: rate of base currency %s must be 1", currency)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rates[currency] = rate
+	return nil
+}
+
+// Rate 实现 FXRates
+func (t *RateTable) Rate(from, to string) (Decimal, error) {
+	if from == to {
+		return NewDecimal(1, 0), nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	f, ok1 := t.rates[from]
+	g, ok2 := t.rates[to]
+	if !ok1 || !ok2 {
+		return Decimal{}, fmt.Errorf("fx: %s/%s: %w", from, to, ErrNoRate)
+	}
+	if to == t.base {
+		return f, nil
+	}
+	r, err := f.Div(g, CrossRateScale, RoundHalfEven)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("fx: %s/%s: %w", from, to, err)
+	}
+	return r.Reduce(), nil
+}
@@ -0,0 +1,219 @@
+package demo11_interface
+
+import (
+	"fmt"
+	"math"
+)
+
+// 平面几何基础
+/*
+1:坐标系是数学坐标系，x 轴向右，y 轴向上，角度用弧度表示，逆时针为正。
+2:Point 既表示点也表示向量，Box 是坐标轴对齐的包围盒。
+3:浮点比较统一使用 geomEpsilon 作为容差，边界上的点视为在图形内部。
+*/
+
+// geomEpsilon 浮点比较的容差
+const geomEpsilon = 1e-9
+
+// Point 平面上的点
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Pt 是 Point{x, y} 的简写
+func Pt(x, y float64) Point {
+	return Point{x, y}
+}
+
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+func (p Point) Sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+func (p Point) Mul(k float64) Point {
+	return Point{p.X * k, p.Y * k}
+}
+
+// Dot 点积
+func (p Point) Dot(q Point) float64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+// Cross 叉积的 z 分量，q 在 p 的逆时针方向时为正
+func (p Point) Cross(q Point) float64 {
+	return p.X*q.Y - p.Y*q.X
+}
+
+// Len 向量长度
+func (p Point) Len() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// Dist 两点之间的距离
+func (p Point) Dist(q Point) float64 {
+	return p.Sub(q).Len()
+}
+
+// Rotate 绕 pivot 逆时针旋转 angle 弧度
+func (p Point) Rotate(angle float64, pivot Point) Point {
+	sin, cos := math.Sincos(angle)
+	d := p.Sub(pivot)
+	return Point{pivot.X + d.X*cos - d.Y*sin, pivot.Y + d.X*sin + d.Y*cos}
+}
+
+// Scale 以 pivot 为中心缩放 k 倍
+func (p Point) Scale(k float64, pivot Point) Point {
+	return pivot.Add(p.Sub(pivot).Mul(k))
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("(%g,%g)", p.X, p.Y)
+}
+
+// Box 坐标轴对齐的包围盒，Min 是左下角，Max 是右上角
+type Box struct {
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+// BoxOf 返回包含所有点的最小包围盒
+func BoxOf(points ...Point) Box {
+	if len(points) == 0 {
+		return Box{}
+	}
+	b := Box{points[0], points[0]}
+	for _, p := range points[1:] {
+		b.Min.X = math.Min(b.Min.X, p.X)
+		b.Min.Y = math.Min(b.Min.Y, p.Y)
+		b.Max.X = math.Max(b.Max.X, p.X)
+		b.Max.Y = math.Max(b.Max.Y, p.Y)
+	}
+	return b
+}
+
+func (b Box) Width() float64 {
+	return b.Max.X - b.Min.X
+}
+
+func (b Box) Height() float64 {
+	return b.Max.Y - b.Min.Y
+}
+
+func (b Box) Center() Point {
+	return Point{(b.Min.X + b.Max.X) / 2, (b.Min.Y + b.Max.Y) / 2}
+}
+
+// Contains p 是否在包围盒内，包括边界
+func (b Box) Contains(p Point) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y
+}
+
+// Intersects 两个包围盒是否相交，只接触边界也算相交
+func (b Box) Intersects(o Box) bool {
+	return b.Min.X <= o.Max.X && o.Min.X <= b.Max.X && b.Min.Y <= o.Max.Y && o.Min.Y <= b.Max.Y
+}
+
+// Union 同时包含 b 和 o 的最小包围盒
+func (b Box) Union(o Box) Box {
+	return Box{
+		Min: Point{math.Min(b.Min.X, o.Min.X), math.Min(b.Min.Y, o.Min.Y)},
+		Max: Point{math.Max(b.Max.X, o.Max.X), math.Max(b.Max.Y, o.Max.Y)},
+	}
+}
+
+func (b Box) String() string {
+	return fmt.Sprintf("[%v-%v]", b.Min, b.Max)
+}
+
+// orientation c 在有向线段 ab 的左侧返回 1，右侧返回 -1，共线返回 0
+func orientation(a, b, c Point) int {
+	cross := b.Sub(a).Cross(c.Sub(a))
+	// 容差按线段长度缩放，避免大坐标下误判
+	eps := geomEpsilon * math.Max(1, b.Sub(a).Len()*c.Sub(a).Len())
+	switch {
+	case cross > eps:
+		return 1
+	case cross < -eps:
+		return -1
+	}
+	return 0
+}
+
+// onSegment 已知 p 与 ab 共线时，判断 p 是否落在线段 ab 上
+func onSegment(a, b, p Point) bool {
+	return p.X >= math.Min(a.X, b.X)-geomEpsilon && p.X <= math.Max(a.X, b.X)+geomEpsilon &&
+		p.Y >= math.Min(a.Y, b.Y)-geomEpsilon && p.Y <= math.Max(a.Y, b.Y)+geomEpsilon
+}
+
+// pointOnSegment p 是否在线段 ab 上
+func pointOnSegment(a, b, p Point) bool {
+	return orientation(a, b, p) == 0 && onSegment(a, b, p)
+}
+
+// segmentsIntersect 线段 ab 与 cd 是否有公共点
+func segmentsIntersect(a, b, c, d Point) bool {
+	o1, o2 := orientation(a, b, c), orientation(a, b, d)
+	o3, o4 := orientation(c, d, a), orientation(c, d, b)
+	switch {
+	case o1*o2 < 0 && o3*o4 < 0:
+		return true
+	case o1 == 0 && onSegment(a, b, c):
+		return true
+	case o2 == 0 && onSegment(a, b, d):
+		return true
+	case o3 == 0 && onSegment(c, d, a):
+		return true
+	case o4 == 0 && onSegment(c, d, b):
+		return true
+	}
+	return false
+}
+
+// polygonArea 鞋带公式计算有向面积，逆时针为正
+func polygonArea(points []Point) float64 {
+	var sum float64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		sum += p.Cross(q)
+	}
+	return sum / 2
+}
+
+// polygonPerimeter 闭合折线的长度
+func polygonPerimeter(points []Point) float64 {
+	var sum float64
+	for i, p := range points {
+		sum += p.Dist(points[(i+1)%len(points)])
+	}
+	return sum
+}
+
+// polygonContains 边界上的点返回 true，其余用射线法判断
+func polygonContains(points []Point, p Point) bool {
+	inside := false
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		if pointOnSegment(a, b, p) {
+			return true
+		}
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			x := a.X + (p.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+			if p.X < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func transformPoints(points []Point, f func(Point) Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[i] = f(p)
+	}
+	return out
+}
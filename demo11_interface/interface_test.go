@@ -1,11 +1,12 @@
 package demo11_interface
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"slices"
 	"testing"
 )
 
@@ -125,46 +126,7 @@ OO 语言最重要的三个方面分别是：封装、继承和多态，在 Go 
 */
 
 // Demo1
-// Shaper 图形
-type Shaper interface {
-	// Area 面积
-	Area() float32
-}
-
-// TopologicalGenus 拓扑级
-type TopologicalGenus interface {
-	// Rank 等级
-	Rank() int
-}
-
-// Square 正方形
-type Square struct {
-	// Side 边
-	Side float32
-}
-
-func (s *Square) Area() float32 {
-	return s.Side * s.Side
-}
-
-func (s *Square) Rank() int {
-	return 1
-}
-
-// Rectangle 长方形
-type Rectangle struct {
-	// 长，宽
-	Length, Width float32
-}
-
-func (r Rectangle) Area() float32 {
-	return r.Length * r.Width
-}
-
-func (r Rectangle) Rank() int {
-	return 2
-}
-
+// Shaper、TopologicalGenus、Square、Rectangle 以及其他图形见 shape.go
 func TestShaper(t *testing.T) {
 	squarePointer := &Square{Side: 4.5}
 	rectangle := Rectangle{Length: 4.5, Width: 5.5}
@@ -182,88 +144,32 @@ func TestShaper(t *testing.T) {
 }
 
 // Demo2: 所有实现了 valuable 接口的类型都可以用这个函数
-type stockPosition struct {
-	ticker     string
-	sharePrice float32
-	count      float32
-}
-
-func (sp stockPosition) getValue() float32 {
-	return sp.sharePrice * sp.count
-}
-
-type car struct {
-	make  string
-	model string
-	price float32
-}
-
-func (c car) getValue() float32 {
-	return c.price
-}
-
-type valuable interface {
-	getValue() float32
-}
-
-func showValue(asset valuable) {
-	fmt.Println(asset.getValue())
-}
-
+// valuable、stockPosition、car、showValue 见 valuable.go
 func TestValuable(t *testing.T) {
-	var asset valuable = stockPosition{ticker: "ESOP", sharePrice: 88.99, count: 10}
+	var asset valuable = stockPosition{ticker: "ESOP", sharePrice: MustParseDecimal("88.99"), count: NewDecimal(10, 0)}
 	showValue(asset)
-	asset = car{make: "Ben", model: "BMW", price: 459800}
+	asset = car{make: "Ben", model: "BMW", price: NewDecimal(459800, 0)}
 	showValue(asset)
 }
 
 // Demo3:接口嵌套接口
-type ReadWrite interface {
-	Read(b bytes.Buffer) bool
-	Write(b bytes.Buffer) bool
-}
-
-type Lock interface {
-	Lock()
-	Unlock()
-}
-
-type File interface {
-	ReadWrite
-	Lock
-	Close()
-}
-
-type Word struct {
-}
-
-func (w *Word) Read(b bytes.Buffer) bool {
-	return true
-}
-
-func (w *Word) Write(b bytes.Buffer) bool {
-	return true
-}
-
-func (w *Word) Lock() {
-
-}
-
-func (w *Word) Unlock() {
-
-}
-
-func (w *Word) Close() {
-
-}
-
+// ReadWrite、Lock、File 见 file.go，实现了 File 的 Word 见 word.go
 func TestWord(t *testing.T) {
-	w := new(Word)
-	w.Read(bytes.Buffer{})
-	w.Write(bytes.Buffer{})
-	w.Lock()
-	w.Unlock()
-	w.Close()
+	var f File = new(Word)
+	f.Write([]byte("hello, world"))
+	f.Seek(7, io.SeekStart)
+	f.Lock()
+	rest, _ := io.ReadAll(f)
+	f.Unlock()
+	head := make([]byte, 5)
+	f.ReadAt(head, 0)
+	fmt.Println(string(head), string(rest))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(head); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("read after close: %v", err)
+	}
 }
 
 // Demo4:类型断言
@@ -307,48 +213,31 @@ func TestTypeSwitch(t *testing.T) {
 }
 
 // Demo6:接口方法集的调用规则
-type Appender interface {
-	Append(int)
-}
-
-func CountInto(a Appender, start, end int) {
-	for i := start; i < end; i++ {
-		a.Append(i)
-	}
-}
-
-type Lener interface {
-	Len() int
-}
-
-func LongEnough(l Lener) bool {
-	return l.Len()*10 > 42
-}
-
-type List []int
-
-func (listPtr *List) Append(i int) {
-	*listPtr = append(*listPtr, i)
-}
-
-func (listVal List) Len() int {
-	return len(listVal)
-}
-
+// Appender、Lener、CountInto、LongEnough 和 List 见 list.go
 func TestList(t *testing.T) {
-	listVal := List{1, 2}
+	listVal := IntList{1, 2}
 	listVal.Append(3)
 	fmt.Println(listVal.Len())
+	// Append 是指针方法，IntList 的值不是 Appender[int]，要传 &listVal；Len 是值方法，可以直接传值
 	CountInto(&listVal, 5, 10)
 	LongEnough(listVal)
 	fmt.Println(listVal)
 
-	listPtr := &List{1, 2}
+	listPtr := &IntList{1, 2}
 	listPtr.Append(3)
 	fmt.Println(listPtr.Len())
 	CountInto(listPtr, 5, 10)
 	LongEnough(listPtr)
 	fmt.Println(listPtr)
+
+	// List[int] 的方法都是指针接收者，传给 Appender[int] 和 Lener 时都要取地址
+	var generic List[int]
+	CountInto(&generic, 5, 10)
+	if !LongEnough(&generic) || generic.Len() != listVal.Len()-3 {
+		t.Errorf("List[int] len %d", generic.Len())
+	}
+	var _ Lener = listVal
+	var _ Appender[int] = &listVal
 }
 
 // Demo7:空接口
@@ -375,23 +264,7 @@ func TestAny2(t *testing.T) {
 }
 
 // Demo8:使用Sorter接口排序
-type Sorter interface {
-	Len() int
-	Less(i, j int) bool
-	Swap(i, j int)
-}
-
-// 冒泡排序
-func Sort(data Sorter) {
-	for pass := 1; pass < data.Len(); pass++ {
-		for i := 0; i < data.Len()-pass; i++ {
-			if data.Less(i, i+1) {
-				data.Swap(i, i+1)
-			}
-		}
-	}
-}
-
+// Sorter、Sort、Stable 与 IsSorted 见 sort.go
 type IntArray []int
 
 func (a IntArray) Len() int {
@@ -399,7 +272,7 @@ func (a IntArray) Len() int {
 }
 
 func (a IntArray) Less(i, j int) bool {
-	return a[i] > a[j]
+	return a[i] < a[j]
 }
 
 func (a IntArray) Swap(i, j int) {
@@ -410,6 +283,9 @@ func TestIntArray(t *testing.T) {
 	intSlice := IntArray{3, 5, 6, 2, 1}
 	Sort(intSlice)
 	fmt.Println(intSlice)
+	if !slices.Equal(intSlice, IntArray{1, 2, 3, 5, 6}) {
+		t.Errorf("got %v, want ascending order", intSlice)
+	}
 }
 
 // Demo9:Go的动态类型
@@ -442,30 +318,10 @@ func TestDuck(t *testing.T) {
 }
 
 // Demo10:动态方法调用
-type xmlWriter interface {
-	WriteXML(w io.Writer) error
-}
-
-func StreamXml(v any, w io.Writer) error {
-	if xw, ok := v.(xmlWriter); ok {
-		return xw.WriteXML(w)
-	}
-	return EncodeToXML(v, w)
-}
-
-func EncodeToXML(v any, w io.Writer) error {
-	return nil
-}
+// xmlWriter、StreamXml 与 EncodeToXML 见 xml.go
 
 // Demo11:接口的继承
-type Task struct {
-	Command string
-	*log.Logger
-}
-
-func NewTask(command string, logger *log.Logger) *Task {
-	return &Task{command, logger}
-}
+// Task、NewTask 与 Task.Run 见 task.go
 
 // 当 log.Logger 实现了 Log() 方法后，Task 的实例 task 就可以调用该方法：
 //task.Log()
@@ -476,32 +332,7 @@ func NewTask(command string, logger *log.Logger) *Task {
 //}
 
 // Demo12:结构体、集合和高阶函数
-type Car struct {
-	Module       string
-	Manufacturer string
-	BuildYear    int
-}
-
-type Cars []*Car
-
-func (cs Cars) Process(f func(c *Car)) {
-	for _, c := range cs {
-		f(c)
-	}
-}
-
-func (cs Cars) FindAll(f func(c *Car) bool) Cars {
-	cars := make([]*Car, 0)
-
-	cs.Process(func(c *Car) {
-		if f(c) {
-			cars = append(cars, c)
-		}
-	})
-
-	return cars
-}
-
+// Car、Cars 见 cars.go
 func TestCar(t *testing.T) {
 	cars := Cars{
 		&Car{Module: "1", Manufacturer: "BMW", BuildYear: 2024},
@@ -0,0 +1,386 @@
+package demo11_interface
+
+import (
+	"iter"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Demo6:接口方法集的调用规则
+/*
+1:IntList 的 Append 是指针接收者，Len 是值接收者：IntList 的值实现 Lener，但是只有 *IntList 实现 Appender[int]，
+  因为存储在接口中的值没有地址，不能调用指针方法。CountInto(&listVal, ...) 必须取地址，LongEnough(listVal) 可以直接传值。
+2:List[T] 的方法都是指针接收者，只有 *List[T] 实现 Appender[T] 和 Lener。List[T] 不加锁，
+  需要被多个 goroutine 同时使用时用 LockedList[T]，它在 List[T] 外面加一把读写锁，零值可以直接使用。
+3:容量不够时按 GrowthPolicy 扩容：DoublingGrowth 翻倍，ChunkGrowth 每次增加固定的块，CappedGrowth 翻倍但是每次最多增加 maxStep。
+4:SingleProducerList[T] 只允许一个 goroutine 追加，读取不加锁：元素分块存放，已经写入的元素不会被搬动，
+  追加完成后才用原子操作发布新的长度，读者只读发布过的部分。适合追加频繁的热路径。
+*/
+
+// Appender 可以追加元素
+type Appender[T any] interface {
+	Append(T)
+}
+
+// CountInto 把 [start, end) 的整数依次追加到 a
+func CountInto[T Integer](a Appender[T], start, end T) {
+	for i := start; i < end; i++ {
+		a.Append(i)
+	}
+}
+
+// Lener 有长度
+type Lener interface {
+	Len() int
+}
+
+func LongEnough(l Lener) bool {
+	return l.Len()*10 > 42
+}
+
+// Integer 整数类型
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// GrowthPolicy 根据当前容量和需要的长度计算新的容量，结果小于 need 时按 need 处理
+type GrowthPolicy func(capacity, need int) int
+
+// minGrowth 从空列表开始扩容时的最小容量
+const minGrowth = 8
+
+// DoublingGrowth 容量翻倍
+func DoublingGrowth(capacity, need int) int {
+	return max(need, 2*capacity, minGrowth)
+}
+
+// ChunkGrowth 每次增加 n 的整数倍
+func ChunkGrowth(n int) GrowthPolicy {
+	if n <= 0 {
+		panic("ChunkGrowth: chunk size must be positive")
+	}
+	return func(capacity, need int) int {
+		return capacity + (need-capacity+n-1)/n*n
+	}
+}
+
+// CappedGrowth 翻倍，但是每次最多增加 maxStep，避免大列表一次多分配一倍的内存
+func CappedGrowth(maxStep int) GrowthPolicy {
+	if maxStep <= 0 {
+		panic("CappedGrowth: max step must be positive")
+	}
+	return func(capacity, need int) int {
+		return max(need, capacity+min(max(capacity, minGrowth), maxStep))
+	}
+}
+
+// ListOption List 和 SingleProducerList 的选项
+type ListOption func(*listConfig)
+
+type listConfig struct {
+	growth   GrowthPolicy
+	capacity int
+}
+
+// WithGrowth 使用 g 扩容，默认是 DoublingGrowth
+func WithGrowth(g GrowthPolicy) ListOption {
+	return func(c *listConfig) { c.growth = g }
+}
+
+// WithCapacity 预先分配 n 个元素的容量
+func WithCapacity(n int) ListOption {
+	return func(c *listConfig) { c.capacity = n }
+}
+
+func newListConfig(opts []ListOption) listConfig {
+	c := listConfig{growth: DoublingGrowth}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// IntList 原来的 Demo6 例子：Append 需要修改切片头，所以是指针接收者；Len 只读，是值接收者
+type IntList []int
+
+func (listPtr *IntList) Append(i int) {
+	*listPtr = append(*listPtr, i)
+}
+
+func (listVal IntList) Len() int {
+	return len(listVal)
+}
+
+// List 列表，零值是翻倍扩容的空列表，不能被多个 goroutine 同时修改
+type List[T any] struct {
+	growth GrowthPolicy
+	items  []T
+}
+
+// NewList 创建列表
+func NewList[T any](opts ...ListOption) *List[T] {
+	c := newListConfig(opts)
+	l := &List[T]{growth: c.growth}
+	if c.capacity > 0 {
+		l.items = make([]T, 0, c.capacity)
+	}
+	return l
+}
+
+// Append 追加一个元素
+func (l *List[T]) Append(v T) {
+	l.grow(len(l.items) + 1)
+	l.items = append(l.items, v)
+}
+
+// AppendAll 一次追加多个元素，最多扩容一次
+func (l *List[T]) AppendAll(vs ...T) {
+	l.grow(len(l.items) + len(vs))
+	l.items = append(l.items, vs...)
+}
+
+// AppendSeq 追加 seq 产生的所有元素，返回追加的个数。先收集再一次性追加，seq 里可以读这个列表
+func (l *List[T]) AppendSeq(seq iter.Seq[T]) int {
+	vs := slices.Collect(seq)
+	l.AppendAll(vs...)
+	return len(vs)
+}
+
+// Len 元素个数
+func (l *List[T]) Len() int {
+	return len(l.items)
+}
+
+// Cap 当前容量
+func (l *List[T]) Cap() int {
+	return cap(l.items)
+}
+
+// At 第 i 个元素，越界时 panic
+func (l *List[T]) At(i int) T {
+	return l.items[i]
+}
+
+// Set 替换第 i 个元素，越界时 panic
+func (l *List[T]) Set(i int, v T) {
+	l.items[i] = v
+}
+
+// Slice 所有元素的副本
+func (l *List[T]) Slice() []T {
+	return slices.Clone(l.items)
+}
+
+// All 遍历调用时的快照，遍历过程中可以修改列表
+func (l *List[T]) All() iter.Seq2[int, T] {
+	return slices.All(l.Slice())
+}
+
+// grow 保证容量至少是 need，调用方持有锁
+func (l *List[T]) grow(need int) {
+	if need <= cap(l.items) {
+		return
+	}
+	growth := l.growth
+	if growth == nil {
+		growth = DoublingGrowth
+	}
+	items := make([]T, len(l.items), max(growth(cap(l.items), need), need))
+	copy(items, l.items)
+	l.items = items
+}
+
+// LockedList 加了读写锁的 List，可以被多个 goroutine 同时使用，零值可以直接使用
+type LockedList[T any] struct {
+	mu   sync.RWMutex
+	list List[T]
+}
+
+// NewLockedList 创建加锁的列表
+func NewLockedList[T any](opts ...ListOption) *LockedList[T] {
+	return &LockedList[T]{list: *NewList[T](opts...)}
+}
+
+// Append 追加一个元素
+func (l *LockedList[T]) Append(v T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.list.Append(v)
+}
+
+// AppendAll 一次追加多个元素，其他 goroutine 不会看到只追加了一部分的结果
+func (l *LockedList[T]) AppendAll(vs ...T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.list.AppendAll(vs...)
+}
+
+// AppendSeq 先在锁外收集 seq 产生的元素，再一次性追加，seq 里可以读这个列表，返回追加的个数
+func (l *LockedList[T]) AppendSeq(seq iter.Seq[T]) int {
+	vs := slices.Collect(seq)
+	l.AppendAll(vs...)
+	return len(vs)
+}
+
+// Len 元素个数
+func (l *LockedList[T]) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.list.Len()
+}
+
+// Cap 当前容量
+func (l *LockedList[T]) Cap() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.list.Cap()
+}
+
+// At 第 i 个元素，越界时 panic
+func (l *LockedList[T]) At(i int) T {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.list.At(i)
+}
+
+// Set 替换第 i 个元素，越界时 panic
+func (l *LockedList[T]) Set(i int, v T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.list.Set(i, v)
+}
+
+// Slice 所有元素的副本
+func (l *LockedList[T]) Slice() []T {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.list.Slice()
+}
+
+// All 遍历调用时的快照，遍历过程中可以修改列表
+func (l *LockedList[T]) All() iter.Seq2[int, T] {
+	return slices.All(l.Slice())
+}
+
+// listChunk SingleProducerList 的一块，覆盖 [start, start+len(data))
+type listChunk[T any] struct {
+	start int
+	data  []T
+}
+
+// SingleProducerList 单个生产者、多个读者的无锁列表，零值可以直接使用
+type SingleProducerList[T any] struct {
+	growth GrowthPolicy
+	// chunks 只由生产者替换，替换时复制目录，已有的块不变
+	chunks atomic.Pointer[[]*listChunk[T]]
+	// n 已经发布的长度，读者只访问 n 之前的元素
+	n atomic.Int64
+	// appending 检测多个 goroutine 同时追加
+	appending atomic.Bool
+}
+
+// NewSingleProducerList 创建列表，只使用 WithGrowth 和 WithCapacity
+func NewSingleProducerList[T any](opts ...ListOption) *SingleProducerList[T] {
+	c := newListConfig(opts)
+	l := &SingleProducerList[T]{growth: c.growth}
+	if c.capacity > 0 {
+		l.addChunk(0, c.capacity)
+	}
+	return l
+}
+
+// Append 追加一个元素，同时只能有一个 goroutine 调用 Append 或 AppendSeq，否则 panic
+func (l *SingleProducerList[T]) Append(v T) {
+	l.begin()
+	defer l.appending.Store(false)
+	n := int(l.n.Load())
+	l.slot(n)[0] = v
+	l.n.Store(int64(n + 1))
+}
+
+// AppendSeq 追加 seq 产生的所有元素，每个元素写入后立即对读者可见，返回追加的个数
+func (l *SingleProducerList[T]) AppendSeq(seq iter.Seq[T]) int {
+	l.begin()
+	defer l.appending.Store(false)
+	count := 0
+	for v := range seq {
+		n := int(l.n.Load())
+		l.slot(n)[0] = v
+		l.n.Store(int64(n + 1))
+		count++
+	}
+	return count
+}
+
+// Len 已经发布的元素个数
+func (l *SingleProducerList[T]) Len() int {
+	return int(l.n.Load())
+}
+
+// At 第 i 个元素，越界时 panic
+func (l *SingleProducerList[T]) At(i int) T {
+	if i < 0 || i >= l.Len() {
+		panic("SingleProducerList: index out of range")
+	}
+	return l.slot(i)[0]
+}
+
+// All 遍历调用时已经发布的元素
+func (l *SingleProducerList[T]) All() iter.Seq2[int, T] {
+	n := l.Len()
+	var chunks []*listChunk[T]
+	if p := l.chunks.Load(); p != nil {
+		chunks = *p
+	}
+	return func(yield func(int, T) bool) {
+		for _, c := range chunks {
+			// 先检查下标，没有发布的元素可能正在被写入
+			for j := range c.data {
+				if c.start+j >= n || !yield(c.start+j, c.data[j]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *SingleProducerList[T]) begin() {
+	if !l.appending.CompareAndSwap(false, true) {
+		panic("SingleProducerList: concurrent Append")
+	}
+}
+
+// slot 第 i 个元素所在的切片，生产者调用时如果 i 超出容量就增加一块
+func (l *SingleProducerList[T]) slot(i int) []T {
+	var chunks []*listChunk[T]
+	if p := l.chunks.Load(); p != nil {
+		chunks = *p
+	}
+	if len(chunks) > 0 {
+		last := chunks[len(chunks)-1]
+		if i < last.start+len(last.data) {
+			k := sort.Search(len(chunks), func(k int) bool { return chunks[k].start > i }) - 1
+			return chunks[k].data[i-chunks[k].start:]
+		}
+		return l.addChunk(last.start+len(last.data), i+1)[i-(last.start+len(last.data)):]
+	}
+	return l.addChunk(0, i+1)[i:]
+}
+
+// addChunk 容量从 capacity 扩到至少 need，新块接在后面，返回新块的数据
+func (l *SingleProducerList[T]) addChunk(capacity, need int) []T {
+	growth := l.growth
+	if growth == nil {
+		growth = DoublingGrowth
+	}
+	c := &listChunk[T]{start: capacity, data: make([]T, max(growth(capacity, need), need)-capacity)}
+	var chunks []*listChunk[T]
+	if p := l.chunks.Load(); p != nil {
+		chunks = *p
+	}
+	chunks = append(slices.Clip(chunks), c)
+	l.chunks.Store(&chunks)
+	return c.data
+}
@@ -0,0 +1,147 @@
+package demo11_interface
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestGrowthPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		growth GrowthPolicy
+		caps   []int
+	}{
+		{"doubling", DoublingGrowth, []int{8, 16, 32, 64}},
+		{"chunk", ChunkGrowth(10), []int{10, 20, 30, 40, 50}},
+		{"capped", CappedGrowth(16), []int{8, 16, 32, 48, 64}},
+	}
+	for _, tt := range tests {
+		l := NewList[int](WithGrowth(tt.growth))
+		var caps []int
+		for i := 0; i < 50; i++ {
+			l.Append(i)
+			if c := l.Cap(); len(caps) == 0 || caps[len(caps)-1] != c {
+				caps = append(caps, c)
+			}
+		}
+		if !slices.Equal(caps, tt.caps) {
+			t.Errorf("%s: capacities %v, want %v", tt.name, caps, tt.caps)
+		}
+	}
+
+	// 一次追加很多时直接扩到需要的大小
+	l := NewList[int](WithGrowth(ChunkGrowth(10)), WithCapacity(4))
+	l.AppendAll(make([]int, 25)...)
+	if l.Cap() != 34 || l.Len() != 25 {
+		t.Errorf("chunk bulk append: len %d cap %d", l.Len(), l.Cap())
+	}
+}
+
+func TestListAppendSeq(t *testing.T) {
+	var l List[string]
+	n := l.AppendSeq(slices.Values([]string{"a", "b"}))
+	n += l.AppendSeq(maps.Keys(map[string]int{"c": 1}))
+	if n != 3 || !slices.Equal(l.Slice(), []string{"a", "b", "c"}) {
+		t.Errorf("AppendSeq = %d, %v", n, l.Slice())
+	}
+	// seq 里读列表不会死锁
+	locked := NewLockedList[int]()
+	locked.AppendAll(1, 2)
+	locked.AppendSeq(func(yield func(int) bool) {
+		for _, v := range locked.All() {
+			if !yield(v * 10) {
+				return
+			}
+		}
+	})
+	if got := locked.Slice(); !slices.Equal(got, []int{1, 2, 10, 20}) {
+		t.Errorf("self append = %v", got)
+	}
+	locked.Set(0, 7)
+	if locked.At(0) != 7 {
+		t.Errorf("At(0) = %d", locked.At(0))
+	}
+}
+
+func TestLockedList(t *testing.T) {
+	l := NewLockedList[int](WithGrowth(CappedGrowth(64)))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CountInto(l, g*1000, g*1000+500)
+			l.Len()
+		}()
+	}
+	wg.Wait()
+	got := l.Slice()
+	slices.Sort(got)
+	if len(got) != 4000 || got[0] != 0 || got[3999] != 7499 {
+		t.Errorf("len %d, first %d, last %d", len(got), got[0], got[len(got)-1])
+	}
+}
+
+func TestSingleProducerList(t *testing.T) {
+	var _ Appender[int] = (*SingleProducerList[int])(nil)
+	var _ Lener = (*SingleProducerList[int])(nil)
+
+	l := NewSingleProducerList[int](WithGrowth(ChunkGrowth(100)))
+	const total = 10000
+	var wg sync.WaitGroup
+	// 读者和生产者同时运行，读到的前缀必须完整
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l.Len() < total {
+				n := l.Len()
+				if n > 0 && l.At(n-1) != n-1 {
+					t.Errorf("At(%d) = %d", n-1, l.At(n-1))
+					return
+				}
+				i := 0
+				for j, v := range l.All() {
+					if j != i || v != i {
+						t.Errorf("All yielded %d at %d, want %d", v, j, i)
+						return
+					}
+					i++
+				}
+				if i < n {
+					t.Errorf("All stopped at %d, Len was %d", i, n)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < total/2; i++ {
+		l.Append(i)
+	}
+	l.AppendSeq(func(yield func(int) bool) {
+		for i := total / 2; i < total; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	wg.Wait()
+	if chunks := len(*l.chunks.Load()); chunks != total/100 {
+		t.Errorf("%d chunks", chunks)
+	}
+
+	// 零值可以直接使用，越界访问 panic
+	var zero SingleProducerList[string]
+	for range zero.All() {
+		t.Error("empty list yielded")
+	}
+	zero.Append("x")
+	defer func() {
+		if recover() == nil {
+			t.Error("At past end: want panic")
+		}
+	}()
+	zero.At(1)
+}
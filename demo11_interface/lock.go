@@ -0,0 +1,451 @@
+package demo11_interface
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 建议性读写锁
+/*
+1:RWLock 区分持有者（LockOwner），同一个持有者可以从共享模式升级为排他模式，也可以从排他模式降级为共享模式。
+  重复获取已经持有的模式不计数，只会续租。
+2:等待者按先来先到排队，避免排他请求饿死；升级请求排在队首。
+3:TryLock 一直等到获得锁、ctx 结束或者检测到死锁，ctx 已经结束时只尝试一次。
+4:设置了租期的锁，持有者必须在到期前 Renew 或者 Release，否则到期后这个锁上的任何操作（包括等待者在到期时醒来）
+  都会把它视为放弃而收回，之后它的 Release/Renew 返回 ErrLeaseExpired。
+5:同一个 LockManager 中的所有锁共享一张等待图：持有者 A 等待 B 持有（或者排在 A 前面）的锁时有一条 A → B 的边。
+  加入等待队列时如果形成环，这次请求返回 *DeadlockError 而不是永远等下去。
+  每个持有者同一时刻只能在一个锁上等待，也就是同一个 LockOwner 不能被多个 goroutine 同时用来加锁。
+6:Lock/Unlock 实现 sync.Locker，是不区分持有者的排他锁，File 的 Lock/Unlock 就是它。
+*/
+
+// LockMode 加锁模式
+type LockMode int
+
+const (
+	// Shared 共享模式，可以有多个持有者
+	Shared LockMode = iota + 1
+	// Exclusive 排他模式
+	Exclusive
+)
+
+func (m LockMode) String() string {
+	switch m {
+	case Shared:
+		return "shared"
+	case Exclusive:
+		return "exclusive"
+	}
+	return fmt.Sprintf("LockMode(%d)", int(m))
+}
+
+var (
+	// ErrDeadlock 等待会形成死锁
+	ErrDeadlock = errors.New("deadlock detected")
+	// ErrLeaseExpired 租期已过，锁已经被收回
+	ErrLeaseExpired = errors.New("lock lease expired")
+	// ErrNotHeld 没有持有锁或者没有持有需要的模式
+	ErrNotHeld = errors.New("lock not held")
+)
+
+// DeadlockError 等待图中的环，从发起请求的持有者开始
+type DeadlockError struct {
+	Cycle []*LockOwner
+}
+
+func (e *DeadlockError) Error() string {
+	names := make([]string, len(e.Cycle))
+	for i, o := range e.Cycle {
+		names[i] = o.String()
+	}
+	return fmt.Sprintf("deadlock detected: %s -> %s", strings.Join(names, " -> "), names[0])
+}
+
+func (e *DeadlockError) Unwrap() error {
+	return ErrDeadlock
+}
+
+var lockOwnerSeq atomic.Uint64
+
+// LockOwner 锁的持有者，用指针区分
+type LockOwner struct {
+	id   uint64
+	name string
+}
+
+// NewLockOwner 创建持有者，name 只用于错误信息
+func NewLockOwner(name string) *LockOwner {
+	return &LockOwner{id: lockOwnerSeq.Add(1), name: name}
+}
+
+func (o *LockOwner) String() string {
+	return fmt.Sprintf("%s#%d", o.name, o.id)
+}
+
+// LockManager 管理一组锁的等待图
+type LockManager struct {
+	mu sync.Mutex
+	// waiting 每个持有者正在等待的锁
+	waiting map[*LockOwner]*RWLock
+}
+
+// NewLockManager 创建 LockManager
+func NewLockManager() *LockManager {
+	return &LockManager{waiting: make(map[*LockOwner]*RWLock)}
+}
+
+var defaultLockManager = NewLockManager()
+
+// RWLockOption RWLock 的选项
+type RWLockOption func(*RWLock)
+
+// WithLease 持有者必须在 d 内续租，d <= 0 表示不限制
+func WithLease(d time.Duration) RWLockOption {
+	return func(l *RWLock) { l.lease = d }
+}
+
+// WithLockManager 使用指定的 LockManager，不指定时所有锁共享一个全局的 LockManager
+func WithLockManager(m *LockManager) RWLockOption {
+	return func(l *RWLock) { l.mgr = m }
+}
+
+// LockHolder 持有者和它持有的模式
+type LockHolder struct {
+	Owner *LockOwner
+	Mode  LockMode
+	// Expires 租期到期的时间，没有租期时是零值
+	Expires time.Time
+}
+
+type lockRequest struct {
+	owner *LockOwner
+	mode  LockMode
+}
+
+// RWLock 建议性读写锁，下面的字段都由 mgr.mu 保护
+type RWLock struct {
+	name    string
+	lease   time.Duration
+	mgr     *LockManager
+	holders map[*LockOwner]*LockHolder
+	queue   []lockRequest
+	// expired 租期到期被收回的持有者
+	expired map[*LockOwner]bool
+	// changed 状态变化时关闭并换成新的，唤醒所有等待者
+	changed chan struct{}
+	// locker Lock 获得锁时使用的匿名持有者
+	locker *LockOwner
+}
+
+// NewRWLock 创建读写锁，name 只用于错误信息
+func NewRWLock(name string, opts ...RWLockOption) *RWLock {
+	l := &RWLock{
+		name:    name,
+		mgr:     defaultLockManager,
+		holders: make(map[*LockOwner]*LockHolder),
+		expired: make(map[*LockOwner]bool),
+		changed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// TryLock 以 mode 获得锁，已经持有共享模式时请求排他模式就是升级
+func (l *RWLock) TryLock(ctx context.Context, owner *LockOwner, mode LockMode) error {
+	if mode != Shared && mode != Exclusive {
+		return fmt.Errorf("lock %s: invalid mode %v", l.name, mode)
+	}
+	m := l.mgr
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := false
+	defer func() {
+		if queued {
+			l.dequeue(owner)
+			delete(m.waiting, owner)
+			l.broadcast()
+		}
+	}()
+	for {
+		now := time.Now()
+		l.expire(now)
+		if h, ok := l.holders[owner]; ok && h.Mode >= mode {
+			l.renew(h, now)
+			return nil
+		}
+		if l.grantable(owner, mode) {
+			if h, ok := l.holders[owner]; ok {
+				h.Mode = mode
+			} else {
+				l.holders[owner] = &LockHolder{Owner: owner, Mode: mode}
+			}
+			l.renew(l.holders[owner], now)
+			delete(l.expired, owner)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("lock %s: %w", l.name, ctx.Err())
+		}
+		if !queued {
+			req := lockRequest{owner, mode}
+			if _, upgrading := l.holders[owner]; upgrading {
+				l.queue = slices.Insert(l.queue, 0, req)
+			} else {
+				l.queue = append(l.queue, req)
+			}
+			m.waiting[owner] = l
+			queued = true
+			if cycle := m.findCycle(owner); cycle != nil {
+				return &DeadlockError{Cycle: cycle}
+			}
+			// 升级请求排到队首之后可能立即满足
+			continue
+		}
+
+		changed := l.changed
+		var expiry <-chan time.Time
+		var timer *time.Timer
+		if next, ok := l.nextExpiry(); ok {
+			timer = time.NewTimer(next.Sub(now))
+			expiry = timer.C
+		}
+		m.mu.Unlock()
+		select {
+		case <-changed:
+		case <-expiry:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		m.mu.Lock()
+	}
+}
+
+// Upgrade 从共享模式升级为排他模式
+func (l *RWLock) Upgrade(ctx context.Context, owner *LockOwner) error {
+	if l.Mode(owner) == 0 {
+		return fmt.Errorf("upgrade %s: %w", l.name, l.notHeld(owner))
+	}
+	return l.TryLock(ctx, owner, Exclusive)
+}
+
+// Downgrade 从排他模式降级为共享模式，不会让其他持有者插进来
+func (l *RWLock) Downgrade(owner *LockOwner) error {
+	l.mgr.mu.Lock()
+	defer l.mgr.mu.Unlock()
+	l.expire(time.Now())
+	h, ok := l.holders[owner]
+	if !ok || h.Mode != Exclusive {
+		return fmt.Errorf("downgrade %s: %w", l.name, l.notHeld(owner))
+	}
+	h.Mode = Shared
+	l.broadcast()
+	return nil
+}
+
+// Release 释放 owner 持有的锁
+func (l *RWLock) Release(owner *LockOwner) error {
+	l.mgr.mu.Lock()
+	defer l.mgr.mu.Unlock()
+	l.expire(time.Now())
+	if _, ok := l.holders[owner]; !ok {
+		return fmt.Errorf("release %s: %w", l.name, l.notHeld(owner))
+	}
+	delete(l.holders, owner)
+	l.broadcast()
+	return nil
+}
+
+// Renew 续租
+func (l *RWLock) Renew(owner *LockOwner) error {
+	l.mgr.mu.Lock()
+	defer l.mgr.mu.Unlock()
+	now := time.Now()
+	l.expire(now)
+	h, ok := l.holders[owner]
+	if !ok {
+		return fmt.Errorf("renew %s: %w", l.name, l.notHeld(owner))
+	}
+	l.renew(h, now)
+	return nil
+}
+
+// Mode owner 持有的模式，没有持有时返回 0
+func (l *RWLock) Mode(owner *LockOwner) LockMode {
+	l.mgr.mu.Lock()
+	defer l.mgr.mu.Unlock()
+	l.expire(time.Now())
+	if h, ok := l.holders[owner]; ok {
+		return h.Mode
+	}
+	return 0
+}
+
+// Holders 当前持有者的快照，按持有者创建的顺序排列
+func (l *RWLock) Holders() []LockHolder {
+	l.mgr.mu.Lock()
+	defer l.mgr.mu.Unlock()
+	l.expire(time.Now())
+	holders := make([]LockHolder, 0, len(l.holders))
+	for _, h := range l.holders {
+		holders = append(holders, *h)
+	}
+	slices.SortFunc(holders, func(a, b LockHolder) int {
+		return cmp.Compare(a.Owner.id, b.Owner.id)
+	})
+	return holders
+}
+
+// Lock 实现 sync.Locker，以匿名持有者获得排他锁
+func (l *RWLock) Lock() {
+	owner := NewLockOwner(l.name + ".Lock")
+	// 匿名持有者不持有其他锁，不会形成死锁
+	if err := l.TryLock(context.Background(), owner, Exclusive); err != nil {
+		panic(err)
+	}
+	l.mgr.mu.Lock()
+	l.locker = owner
+	l.mgr.mu.Unlock()
+}
+
+// Unlock 释放 Lock 获得的锁，没有加锁时 panic
+func (l *RWLock) Unlock() {
+	l.mgr.mu.Lock()
+	owner := l.locker
+	l.locker = nil
+	l.mgr.mu.Unlock()
+	if owner == nil {
+		panic("lock " + l.name + ": unlock of unlocked lock")
+	}
+	if err := l.Release(owner); err != nil && !errors.Is(err, ErrLeaseExpired) {
+		panic(err)
+	}
+}
+
+func (l *RWLock) notHeld(owner *LockOwner) error {
+	if l.expired[owner] {
+		return ErrLeaseExpired
+	}
+	return ErrNotHeld
+}
+
+func (l *RWLock) renew(h *LockHolder, now time.Time) {
+	if l.lease > 0 {
+		h.Expires = now.Add(l.lease)
+	}
+}
+
+// expire 收回租期已到的持有者
+func (l *RWLock) expire(now time.Time) {
+	for owner, h := range l.holders {
+		if !h.Expires.IsZero() && !now.Before(h.Expires) {
+			delete(l.holders, owner)
+			l.expired[owner] = true
+			l.broadcast()
+		}
+	}
+}
+
+func (l *RWLock) nextExpiry() (time.Time, bool) {
+	var next time.Time
+	for _, h := range l.holders {
+		if !h.Expires.IsZero() && (next.IsZero() || h.Expires.Before(next)) {
+			next = h.Expires
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (l *RWLock) broadcast() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *RWLock) dequeue(owner *LockOwner) {
+	l.queue = slices.DeleteFunc(l.queue, func(r lockRequest) bool { return r.owner == owner })
+}
+
+func conflicts(a, b LockMode) bool {
+	return a == Exclusive || b == Exclusive
+}
+
+// grantable owner 现在能否以 mode 获得锁：和其他持有者兼容，并且前面没有排队的请求
+func (l *RWLock) grantable(owner *LockOwner, mode LockMode) bool {
+	for o, h := range l.holders {
+		if o != owner && conflicts(h.Mode, mode) {
+			return false
+		}
+	}
+	for _, r := range l.queue {
+		if r.owner == owner {
+			break
+		}
+		return false
+	}
+	return true
+}
+
+// blockers owner 在 l 上等待的持有者：冲突的持有者和排在它前面的冲突请求
+func (l *RWLock) blockers(owner *LockOwner) []*LockOwner {
+	var mode LockMode
+	for _, r := range l.queue {
+		if r.owner == owner {
+			mode = r.mode
+		}
+	}
+	var bs []*LockOwner
+	for o, h := range l.holders {
+		if o != owner && conflicts(h.Mode, mode) {
+			bs = append(bs, o)
+		}
+	}
+	for _, r := range l.queue {
+		if r.owner == owner {
+			break
+		}
+		if conflicts(r.mode, mode) {
+			bs = append(bs, r.owner)
+		}
+	}
+	return bs
+}
+
+// findCycle 在等待图中从 start 出发深度优先搜索回到 start 的路径
+func (m *LockManager) findCycle(start *LockOwner) []*LockOwner {
+	visited := make(map[*LockOwner]bool)
+	var path []*LockOwner
+	var visit func(o *LockOwner) bool
+	visit = func(o *LockOwner) bool {
+		path = append(path, o)
+		if l, ok := m.waiting[o]; ok {
+			for _, next := range l.blockers(o) {
+				if next == start {
+					return true
+				}
+				if !visited[next] {
+					visited[next] = true
+					if visit(next) {
+						return true
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start) {
+		return path
+	}
+	return nil
+}
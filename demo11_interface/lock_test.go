@@ -0,0 +1,288 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued 等到 l 的等待队列有 n 个请求
+func waitQueued(t *testing.T, l *RWLock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mgr.mu.Lock()
+		queued := len(l.queue)
+		l.mgr.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("lock %s: waiting for %d queued requests", l.name, n)
+}
+
+func TestRWLockModes(t *testing.T) {
+	ctx := context.Background()
+	l := NewRWLock("doc", WithLockManager(NewLockManager()))
+	a, b, c := NewLockOwner("a"), NewLockOwner("b"), NewLockOwner("c")
+
+	if err := l.TryLock(ctx, a, Shared); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.TryLock(ctx, b, Shared); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Holders(); len(got) != 2 || got[0].Owner != a || got[1].Mode != Shared {
+		t.Errorf("Holders = %+v", got)
+	}
+
+	// 有共享持有者时排他请求等到超时，已经结束的 ctx 只尝试一次
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := l.TryLock(short, c, Exclusive); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("exclusive while shared: %v", err)
+	}
+	if err := l.TryLock(short, c, Shared); err != nil {
+		t.Errorf("shared after timed out request: %v", err)
+	}
+	l.Release(c)
+
+	// 排队的排他请求挡住后来的共享请求
+	granted := make(chan LockMode, 2)
+	go func() {
+		if err := l.TryLock(ctx, c, Exclusive); err == nil {
+			granted <- Exclusive
+		}
+	}()
+	waitQueued(t, l, 1)
+	d := NewLockOwner("d")
+	go func() {
+		if err := l.TryLock(ctx, d, Shared); err == nil {
+			granted <- Shared
+		}
+	}()
+	waitQueued(t, l, 2)
+
+	// 升级排在队首，b 释放后 a 先拿到排他锁，再降级放行
+	l.Release(b)
+	if err := l.Upgrade(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if l.Mode(a) != Exclusive {
+		t.Errorf("mode after upgrade = %v", l.Mode(a))
+	}
+	if err := l.Downgrade(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Downgrade(a); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("second downgrade: %v", err)
+	}
+	l.Release(a)
+	if mode := <-granted; mode != Exclusive {
+		t.Errorf("first granted %v, want exclusive", mode)
+	}
+	l.Release(c)
+	if mode := <-granted; mode != Shared {
+		t.Errorf("then granted %v, want shared", mode)
+	}
+	if err := l.Release(a); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("release twice: %v", err)
+	}
+	if err := l.Upgrade(ctx, b); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("upgrade without holding: %v", err)
+	}
+}
+
+func TestRWLockLease(t *testing.T) {
+	ctx := context.Background()
+	l := NewRWLock("doc", WithLease(30*time.Millisecond), WithLockManager(NewLockManager()))
+	a, b := NewLockOwner("a"), NewLockOwner("b")
+
+	if err := l.TryLock(ctx, a, Exclusive); err != nil {
+		t.Fatal(err)
+	}
+	if h := l.Holders(); len(h) != 1 || h[0].Expires.IsZero() {
+		t.Errorf("Holders = %+v", h)
+	}
+	// 续租之后仍然持有，a 随后放弃，b 在租期到了之后拿到锁
+	time.Sleep(20 * time.Millisecond)
+	if err := l.Renew(a); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	wait, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := l.TryLock(wait, b, Exclusive); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("acquired after %v, before the renewed lease expired", waited)
+	}
+	if err := l.Release(a); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("release after expiry: %v", err)
+	}
+	if err := l.Renew(a); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("renew after expiry: %v", err)
+	}
+	// 重新获得锁之后 a 不再是过期的持有者
+	l.Release(b)
+	if err := l.TryLock(ctx, a, Shared); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(a); err != nil {
+		t.Errorf("release: %v", err)
+	}
+}
+
+func TestRWLockDeadlock(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewLockManager()
+	l1 := NewRWLock("l1", WithLockManager(mgr))
+	l2 := NewRWLock("l2", WithLockManager(mgr))
+	a, b := NewLockOwner("a"), NewLockOwner("b")
+
+	// a 持有 l1 等 l2，b 持有 l2 再等 l1
+	l1.TryLock(ctx, a, Exclusive)
+	l2.TryLock(ctx, b, Exclusive)
+	done := make(chan error)
+	go func() { done <- l2.TryLock(ctx, a, Exclusive) }()
+	waitQueued(t, l2, 1)
+	err := l1.TryLock(ctx, b, Exclusive)
+	var de *DeadlockError
+	if !errors.As(err, &de) || !errors.Is(err, ErrDeadlock) || len(de.Cycle) != 2 || de.Cycle[0] != b || de.Cycle[1] != a {
+		t.Fatalf("got %v", err)
+	}
+	if want := fmt.Sprintf("deadlock detected: %v -> %v -> %v", b, a, b); err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+	// 被拒绝的一方放弃之后另一方继续
+	l2.Release(b)
+	if err := <-done; err != nil {
+		t.Errorf("a: %v", err)
+	}
+
+	// 两个共享持有者同时升级
+	l3 := NewRWLock("l3", WithLockManager(mgr))
+	l3.TryLock(ctx, a, Shared)
+	l3.TryLock(ctx, b, Shared)
+	go func() { done <- l3.Upgrade(ctx, a) }()
+	waitQueued(t, l3, 1)
+	if err := l3.Upgrade(ctx, b); !errors.Is(err, ErrDeadlock) {
+		t.Fatalf("second upgrade: %v", err)
+	}
+	l3.Release(b)
+	if err := <-done; err != nil || l3.Mode(a) != Exclusive {
+		t.Errorf("first upgrade: %v, mode %v", err, l3.Mode(a))
+	}
+
+	// 排在前面的请求也算等待：c 排队等 b 释放 l4，b 等 a 持有的 l1，
+	// a 请求 l4 的共享锁虽然和 b 兼容，但是排在 c 后面
+	l4 := NewRWLock("l4", WithLockManager(mgr))
+	c := NewLockOwner("c")
+	l4.TryLock(ctx, b, Shared)
+	go func() { done <- l4.TryLock(ctx, c, Exclusive) }()
+	waitQueued(t, l4, 1)
+	go func() { done <- l1.TryLock(ctx, b, Shared) }()
+	waitQueued(t, l1, 1)
+	if err := l4.TryLock(ctx, a, Shared); !errors.Is(err, ErrDeadlock) {
+		t.Errorf("queued behind waiter: %v", err)
+	}
+	l1.Release(a)
+	if err := <-done; err != nil {
+		t.Errorf("b: %v", err)
+	}
+	l4.Release(b)
+	if err := <-done; err != nil {
+		t.Errorf("c: %v", err)
+	}
+}
+
+// 很多 goroutine 争用同一个 Word：写者在排他锁下整体改写内容，读者在共享锁下必须看到一致的内容
+func TestWordLockContention(t *testing.T) {
+	var w Word
+	w.Write(bytes.Repeat([]byte{'0'}, 64))
+	l := w.FileLock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	readers := 0
+	maxReaders := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := NewLockOwner(fmt.Sprint("g", i))
+			ctx := context.Background()
+			for j := 0; j < 50; j++ {
+				if err := l.TryLock(ctx, owner, Shared); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				readers++
+				maxReaders = max(maxReaders, readers)
+				mu.Unlock()
+				buf := make([]byte, 64)
+				w.ReadAt(buf, 0)
+				if !bytes.Equal(buf, bytes.Repeat(buf[:1], 64)) {
+					t.Errorf("torn read %q", buf)
+				}
+				mu.Lock()
+				readers--
+				mu.Unlock()
+
+				// 一部分读者升级成写者，可能和其他升级的读者死锁，这时放弃这一轮
+				if j%5 == 0 {
+					err := l.Upgrade(ctx, owner)
+					if err == nil {
+						mu.Lock()
+						if readers != 0 {
+							t.Errorf("%d readers while exclusive", readers)
+						}
+						mu.Unlock()
+						// 逐个字节改写，没有锁的话读者会看到新旧混杂的内容
+						digit := []byte{byte('0' + (i+j)%10)}
+						w.Seek(0, io.SeekStart)
+						for k := 0; k < 64; k++ {
+							w.Write(digit)
+						}
+					} else if !errors.Is(err, ErrDeadlock) {
+						t.Error(err)
+					}
+				}
+				if err := l.Release(owner); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	// sync.Locker 风格的使用者也参与争用
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w.Lock()
+				mu.Lock()
+				if readers != 0 {
+					t.Errorf("%d readers while locked", readers)
+				}
+				mu.Unlock()
+				w.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if h := l.Holders(); len(h) != 0 {
+		t.Errorf("holders left: %+v", h)
+	}
+	if maxReaders < 2 {
+		t.Logf("readers never overlapped (max %d)", maxReaders)
+	}
+}
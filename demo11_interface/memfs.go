@@ -0,0 +1,618 @@
+package demo11_interface
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内存文件系统
+/*
+1:MemFS 把目录树保存在内存中，路径使用 io/fs 的规则：以 / 分隔、不以 / 开头，根目录是 "."。
+2:权限只检查属主位：读文件需要 0400，写文件需要 0200；在目录中查找需要 0100，列出目录需要 0400，
+  创建、删除和重命名目录项需要 0200。错误都是 *fs.PathError，可以用 errors.Is 判断 fs.ErrNotExist、fs.ErrPermission 等。
+3:同一个文件可以同时打开多个句柄，每个句柄有自己的偏移量，写入对所有句柄立即可见；
+  文件被删除后已经打开的句柄仍然可以读写，和 Unix 一样。
+4:写入和目录项的变化会更新 mtime，时间来自 WithClock 指定的时钟，默认是 time.Now。
+5:MemFS 实现了 fs.FS、fs.StatFS、fs.ReadDirFS 和 fs.ReadFileFS，可以传给 fs.WalkDir、fs.Sub、template.ParseFS 等，
+  在测试中代替磁盘。OpenFile 返回的句柄实现了 File。
+*/
+
+var (
+	// ErrIsDir 需要文件的地方给了目录
+	ErrIsDir = errors.New("is a directory")
+	// ErrNotDir 路径中间的某一段不是目录
+	ErrNotDir = errors.New("not a directory")
+	// ErrDirNotEmpty 删除非空目录
+	ErrDirNotEmpty = errors.New("directory not empty")
+)
+
+// MemFSOption MemFS 的选项
+type MemFSOption func(*MemFS)
+
+// WithClock 指定 mtime 使用的时钟
+func WithClock(now func() time.Time) MemFSOption {
+	return func(m *MemFS) { m.now = now }
+}
+
+// MemFS 内存文件系统，可以被多个 goroutine 同时使用
+type MemFS struct {
+	// mu 保护目录树的结构（每个目录的 children）
+	mu   sync.RWMutex
+	root *memNode
+	now  func() time.Time
+}
+
+// memNode 文件或者目录
+type memNode struct {
+	// mu 保护 mode、modTime 和 data
+	mu       sync.RWMutex
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	children map[string]*memNode
+	// flock 句柄的 Lock/Unlock 和 FileLock 使用的锁，同一个文件的所有句柄共享，第一次使用时创建
+	flockOnce sync.Once
+	flock     *RWLock
+}
+
+// NewMemFS 创建只有根目录的文件系统，根目录的权限是 0755
+func NewMemFS(opts ...MemFSOption) *MemFS {
+	m := &MemFS{now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.root = &memNode{mode: fs.ModeDir | 0o755, modTime: m.now(), children: make(map[string]*memNode)}
+	return m
+}
+
+func (n *memNode) isDir() bool {
+	return n.children != nil
+}
+
+// perm 读取权限位，调用方不能持有 n.mu
+func (n *memNode) perm() fs.FileMode {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.mode.Perm()
+}
+
+func (n *memNode) fileLock(name string) *RWLock {
+	n.flockOnce.Do(func() { n.flock = NewRWLock(name) })
+	return n.flock
+}
+
+func (n *memNode) touch(t time.Time) {
+	n.mu.Lock()
+	n.modTime = t
+	n.mu.Unlock()
+}
+
+// lookup 找到 name 对应的节点，调用方持有 m.mu
+func (m *MemFS) lookup(op, name string) (*memNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	n := m.root
+	if name == "." {
+		return n, nil
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if !n.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: ErrNotDir}
+		}
+		if n.perm()&0o100 == 0 {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+		}
+		child, ok := n.children[elem]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// lookupParent 找到 name 所在的目录并检查是否可以修改，返回目录和最后一段名字，调用方持有 m.mu
+func (m *MemFS) lookupParent(op, name string) (*memNode, string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	dir, elem := path.Split(name)
+	parent, err := m.lookup(op, path.Clean(dir))
+	if err != nil {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: errors.Unwrap(err)}
+	}
+	if !parent.isDir() {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: ErrNotDir}
+	}
+	return parent, elem, nil
+}
+
+func (m *MemFS) checkWritableDir(op, name string, dir *memNode) error {
+	if dir.perm()&0o300 != 0o300 {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+// Mkdir 创建目录
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdir(name, perm)
+}
+
+func (m *MemFS) mkdir(name string, perm fs.FileMode) error {
+	parent, elem, err := m.lookupParent("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if _, ok := parent.children[elem]; ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := m.checkWritableDir("mkdir", name, parent); err != nil {
+		return err
+	}
+	now := m.now()
+	parent.children[elem] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: now, children: make(map[string]*memNode)}
+	parent.touch(now)
+	return nil
+}
+
+// MkdirAll 创建目录以及所有不存在的上级目录，目录已经存在时不做任何事
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "." {
+		return nil
+	}
+	elems := strings.Split(name, "/")
+	for i := range elems {
+		dir := strings.Join(elems[:i+1], "/")
+		n, err := m.lookup("mkdir", dir)
+		switch {
+		case err == nil && n.isDir():
+			continue
+		case err == nil:
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: ErrNotDir}
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+		if err := m.mkdir(dir, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenFile 按 os.O_* 标志打开文件，需要创建时使用 perm 作为权限
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, err := m.lookup("open", name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		parent, elem, err := m.lookupParent("open", name)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.checkWritableDir("open", name, parent); err != nil {
+			return nil, err
+		}
+		now := m.now()
+		n = &memNode{mode: perm.Perm(), modTime: now}
+		parent.children[elem] = n
+		parent.touch(now)
+		// 新建的文件不再检查权限，和 open(2) 一样
+		return &memFile{fs: m, node: n, name: name, flag: flag}, nil
+	case err != nil:
+		return nil, err
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case n.isDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDir}
+	}
+
+	need := fs.FileMode(0o400)
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY:
+		need = 0o200
+	case os.O_RDWR:
+		need = 0o600
+	}
+	if n.perm()&need != need {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		n.mu.Lock()
+		n.data = nil
+		n.modTime = m.now()
+		n.mu.Unlock()
+	}
+	return &memFile{fs: m, node: n, name: name, flag: flag}, nil
+}
+
+// Create 创建或者清空文件，以读写方式打开
+func (m *MemFS) Create(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+// Open 实现 fs.FS，以只读方式打开文件或者目录
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	n, err := m.lookup("open", name)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if n.perm()&0o400 == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if n.isDir() {
+		return &memDir{fs: m, node: n, name: name}, nil
+	}
+	return &memFile{fs: m, node: n, name: name, flag: os.O_RDONLY}, nil
+}
+
+// Stat 实现 fs.StatFS
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.stat(path.Base(name)), nil
+}
+
+// ReadDir 实现 fs.ReadDirFS，按名字排序
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+	if n.perm()&0o400 == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	return n.entries(), nil
+}
+
+// ReadFile 实现 fs.ReadFileFS
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	f, err := m.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile 写入整个文件，文件不存在时以 perm 创建
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Remove 删除文件或者空目录
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parent, elem, err := m.lookupParent("remove", name)
+	if err != nil {
+		return err
+	}
+	n, ok := parent.children[elem]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := m.checkWritableDir("remove", name, parent); err != nil {
+		return err
+	}
+	if n.isDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrDirNotEmpty}
+	}
+	delete(parent.children, elem)
+	parent.touch(m.now())
+	return nil
+}
+
+// Rename 移动文件或者目录，newname 是已经存在的文件时被替换，是目录时必须为空
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldParent, oldElem, err := m.lookupParent("rename", oldname)
+	if err != nil {
+		return err
+	}
+	n, ok := oldParent.children[oldElem]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	newParent, newElem, err := m.lookupParent("rename", newname)
+	if err != nil {
+		return err
+	}
+	if n.isDir() && (newname == oldname || strings.HasPrefix(newname, oldname+"/")) {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrInvalid}
+	}
+	for _, dir := range []*memNode{oldParent, newParent} {
+		if err := m.checkWritableDir("rename", newname, dir); err != nil {
+			return err
+		}
+	}
+	if target, ok := newParent.children[newElem]; ok {
+		switch {
+		case target == n:
+			return nil
+		case target.isDir() != n.isDir() && target.isDir():
+			return &fs.PathError{Op: "rename", Path: newname, Err: ErrIsDir}
+		case target.isDir() != n.isDir():
+			return &fs.PathError{Op: "rename", Path: newname, Err: ErrNotDir}
+		case target.isDir() && len(target.children) > 0:
+			return &fs.PathError{Op: "rename", Path: newname, Err: ErrDirNotEmpty}
+		}
+	}
+	delete(oldParent.children, oldElem)
+	newParent.children[newElem] = n
+	now := m.now()
+	oldParent.touch(now)
+	newParent.touch(now)
+	return nil
+}
+
+// Chmod 修改权限位
+func (m *MemFS) Chmod(name string, perm fs.FileMode) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.mode = n.mode.Type() | perm.Perm()
+	n.mu.Unlock()
+	return nil
+}
+
+// Chtimes 修改 mtime
+func (m *MemFS) Chtimes(name string, mtime time.Time) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.touch(mtime)
+	return nil
+}
+
+// stat 节点的快照
+func (n *memNode) stat(name string) fs.FileInfo {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return &memFileInfo{name: name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// entries 目录项的快照，调用方持有 MemFS.mu
+func (n *memNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for name, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.stat(name)))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() any           { return nil }
+
+// memFile 打开的文件句柄，实现 File 和 fs.File
+type memFile struct {
+	fs   *MemFS
+	node *memNode
+	name string
+	flag int
+	// mu 保护 off 和 closed
+	mu     sync.Mutex
+	off    int64
+	closed bool
+}
+
+func (f *memFile) readable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != os.O_WRONLY
+}
+
+func (f *memFile) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *memFile) check(op string, ok bool) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if !ok {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("read", f.readable()); err != nil {
+		return 0, err
+	}
+	f.node.mu.RLock()
+	n, err := readAt(f.node.data, p, f.off)
+	f.node.mu.RUnlock()
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	err := f.check("read", f.readable())
+	f.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	n, err := readAt(f.node.data, p, off)
+	if err != nil && err != io.EOF {
+		err = &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	return n, err
+}
+
+// Write 写到当前偏移量，以 O_APPEND 打开时总是写到文件末尾
+func (f *memFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("write", f.writable()); err != nil {
+		return 0, err
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if f.flag&os.O_APPEND != 0 {
+		f.off = int64(len(f.node.data))
+	}
+	f.node.data = writeAt(f.node.data, p, f.off)
+	f.node.modTime = f.fs.now()
+	f.off += int64(len(p))
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+	f.node.mu.RLock()
+	size := int64(len(f.node.data))
+	f.node.mu.RUnlock()
+	off, err := seek(f.off, size, offset, whence)
+	if err != nil {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: err}
+	}
+	f.off = off
+	return off, nil
+}
+
+// Lock 同一个文件的所有句柄共享一把锁，改名或者删除后仍然是同一把
+func (f *memFile) Lock() {
+	f.FileLock().Lock()
+}
+
+func (f *memFile) Unlock() {
+	f.FileLock().Unlock()
+}
+
+func (f *memFile) FileLock() *RWLock {
+	return f.node.fileLock(f.name)
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.node.stat(path.Base(f.name)), nil
+}
+
+func (f *memFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// memDir 打开的目录，实现 fs.ReadDirFile
+type memDir struct {
+	fs      *MemFS
+	node    *memNode
+	name    string
+	entries []fs.DirEntry
+	read    bool
+	closed  bool
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) {
+	return d.node.stat(path.Base(d.name)), nil
+}
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: ErrIsDir}
+}
+
+// ReadDir 第一次调用时对目录做快照，之后按 fs.ReadDirFile 的约定分批返回
+func (d *memDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: fs.ErrClosed}
+	}
+	if !d.read {
+		d.fs.mu.RLock()
+		d.entries = d.node.entries()
+		d.fs.mu.RUnlock()
+		d.read = true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(d.entries))
+	entries := d.entries[:count:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+func (d *memDir) Close() error {
+	if d.closed {
+		return &fs.PathError{Op: "close", Path: d.name, Err: fs.ErrClosed}
+	}
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,260 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeClock 每次调用前进一秒
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(time.Second)
+	return c.t
+}
+
+func testMemFS(t *testing.T) *MemFS {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemFS(WithClock(clock.now))
+	if err := m.MkdirAll("docs/drafts", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"readme.txt":          "hello",
+		"docs/a.txt":          "alpha",
+		"docs/drafts/b.txt":   "beta",
+		"docs/drafts/empty.x": "",
+	} {
+		if err := m.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestMemFSImplementsFS(t *testing.T) {
+	m := testMemFS(t)
+	if err := fstest.TestFS(m, "readme.txt", "docs/a.txt", "docs/drafts/b.txt", "docs/drafts/empty.x"); err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := fs.Sub(m, "docs")
+	if data, err := fs.ReadFile(sub, "drafts/b.txt"); err != nil || string(data) != "beta" {
+		t.Errorf("fs.Sub: %q, %v", data, err)
+	}
+	matches, _ := fs.Glob(m, "docs/*/*.txt")
+	if len(matches) != 1 || matches[0] != "docs/drafts/b.txt" {
+		t.Errorf("fs.Glob = %v", matches)
+	}
+}
+
+func TestMemFSHandles(t *testing.T) {
+	m := testMemFS(t)
+	w, err := m.OpenFile("docs/a.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.OpenFile("docs/a.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个句柄的偏移量互不影响，写入立即可见
+	before, _ := m.Stat("docs/a.txt")
+	w.Seek(0, io.SeekEnd)
+	io.WriteString(w, "+gamma")
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(r, buf); err != nil || string(buf) != "alpha" {
+		t.Errorf("read %q, %v", buf, err)
+	}
+	rest, _ := io.ReadAll(r)
+	if string(rest) != "+gamma" {
+		t.Errorf("rest = %q", rest)
+	}
+	if _, err := r.Write([]byte("x")); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("write to read-only handle: %v", err)
+	}
+	after, _ := m.Stat("docs/a.txt")
+	if !after.ModTime().After(before.ModTime()) || after.Size() != 11 {
+		t.Errorf("after write: size %d, mtime %v (before %v)", after.Size(), after.ModTime(), before.ModTime())
+	}
+
+	// 越过末尾写入中间补零，ReadAt 不影响偏移量
+	w.Seek(13, io.SeekStart)
+	w.Write([]byte("!"))
+	at := make([]byte, 4)
+	if n, err := w.ReadAt(at, 10); n != 4 || err != nil || !bytes.Equal(at, []byte("a\x00\x00!")) {
+		t.Errorf("ReadAt = %q, %d, %v", at, n, err)
+	}
+	if off, _ := w.Seek(0, io.SeekCurrent); off != 14 {
+		t.Errorf("offset after ReadAt = %d", off)
+	}
+	if _, err := w.Seek(-1, io.SeekStart); err == nil {
+		t.Error("negative seek: want error")
+	}
+	section, _ := io.ReadAll(io.NewSectionReader(w, 6, 5))
+	if string(section) != "gamma" {
+		t.Errorf("section = %q", section)
+	}
+
+	// 删除后已经打开的句柄仍然可以读
+	if err := m.Remove("docs/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Stat("docs/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat removed file: %v", err)
+	}
+	if n, _ := r.ReadAt(buf, 0); n != 5 {
+		t.Errorf("read removed file: %d bytes", n)
+	}
+	w.Close()
+	if err := w.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second close: %v", err)
+	}
+
+	// O_APPEND 的并发写入不会互相覆盖，创建文件会更新目录的 mtime
+	root, _ := m.Stat(".")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := m.OpenFile("log.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			for j := 0; j < 100; j++ {
+				f.Write([]byte("0123456789\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	data, _ := m.ReadFile("log.txt")
+	if len(data) != 8*100*11 || strings.Count(string(data), "0123456789\n") != 800 {
+		t.Errorf("log has %d bytes", len(data))
+	}
+	if fi, _ := m.Stat("."); !fi.ModTime().After(root.ModTime()) || !fi.IsDir() {
+		t.Errorf("root mtime %v, was %v", fi.ModTime(), root.ModTime())
+	}
+
+	// 同一个文件的不同句柄共享 Lock
+	a, _ := m.OpenFile("log.txt", os.O_RDONLY, 0)
+	b, _ := m.OpenFile("log.txt", os.O_RDONLY, 0)
+	a.Lock()
+	locked := make(chan struct{})
+	go func() {
+		b.Lock()
+		close(locked)
+		b.Unlock()
+	}()
+	select {
+	case <-locked:
+		t.Error("second handle acquired the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	a.Unlock()
+	<-locked
+
+	// FileLock 通过可选接口获取，同一个文件的句柄共享同一个 RWLock
+	fa, ok1 := a.(FileLocker)
+	fb, ok2 := b.(FileLocker)
+	if !ok1 || !ok2 || fa.FileLock() != fb.FileLock() {
+		t.Error("handles of one file should share a FileLock")
+	}
+}
+
+func TestMemFSPermissions(t *testing.T) {
+	m := testMemFS(t)
+	tests := []struct {
+		name string
+		op   func() error
+		want error
+	}{
+		{"read-only file", func() error {
+			m.Chmod("readme.txt", 0o444)
+			_, err := m.OpenFile("readme.txt", os.O_WRONLY, 0)
+			return err
+		}, fs.ErrPermission},
+		{"write-only file", func() error {
+			m.Chmod("readme.txt", 0o200)
+			_, err := m.ReadFile("readme.txt")
+			return err
+		}, fs.ErrPermission},
+		{"read-only dir", func() error {
+			m.Chmod("docs", 0o555)
+			return m.WriteFile("docs/new.txt", nil, 0o644)
+		}, fs.ErrPermission},
+		{"remove from read-only dir", func() error { return m.Remove("docs/a.txt") }, fs.ErrPermission},
+		{"untraversable dir", func() error {
+			m.Chmod("docs", 0o644)
+			_, err := m.Stat("docs/a.txt")
+			return err
+		}, fs.ErrPermission},
+		{"create exclusive", func() error {
+			m.Chmod("docs", 0o755)
+			_, err := m.OpenFile("docs/a.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+			return err
+		}, fs.ErrExist},
+		{"missing parent", func() error { return m.WriteFile("nope/a.txt", nil, 0o644) }, fs.ErrNotExist},
+		{"file as dir", func() error { return m.Mkdir("readme.txt/x", 0o755) }, ErrNotDir},
+		{"open dir for writing", func() error {
+			_, err := m.OpenFile("docs", os.O_RDWR, 0)
+			return err
+		}, ErrIsDir},
+		{"remove non-empty dir", func() error { return m.Remove("docs") }, ErrDirNotEmpty},
+		{"invalid path", func() error { _, err := m.Stat("/docs"); return err }, fs.ErrInvalid},
+		{"rename into itself", func() error { return m.Rename("docs", "docs/drafts/docs") }, fs.ErrInvalid},
+	}
+	for _, tt := range tests {
+		err := tt.op()
+		var pe *fs.PathError
+		if !errors.Is(err, tt.want) || !errors.As(err, &pe) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := m.Rename("docs/drafts", "drafts"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := m.ReadFile("drafts/b.txt"); err != nil || string(data) != "beta" {
+		t.Errorf("after rename: %q, %v", data, err)
+	}
+	mtime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.Chtimes("drafts/b.txt", mtime)
+	if fi, _ := m.Stat("drafts/b.txt"); !fi.ModTime().Equal(mtime) || fi.Mode() != 0o644 {
+		t.Errorf("stat = %v %v", fi.ModTime(), fi.Mode())
+	}
+}
+
+func TestWordFile(t *testing.T) {
+	var w Word
+	io.WriteString(&w, "abc")
+	w.Seek(5, io.SeekStart)
+	w.Write([]byte("z"))
+	if got := w.Bytes(); !bytes.Equal(got, []byte("abc\x00\x00z")) {
+		t.Errorf("content = %q", got)
+	}
+	w.Seek(0, io.SeekStart)
+	var out bytes.Buffer
+	if n, err := io.Copy(&out, &w); n != 6 || err != nil {
+		t.Errorf("io.Copy = %d, %v", n, err)
+	}
+	if _, err := w.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Error("negative ReadAt offset: want error")
+	}
+}
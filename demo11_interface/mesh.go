@@ -0,0 +1,251 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 网格
+/*
+1:Mesh 由顶点和多边形面组成，边由面的相邻顶点推导出来，面的顶点顺序决定法向（右手定则）。
+2:LoadOBJ 读取 Wavefront OBJ 的 v 和 f 指令，支持 f 1/2/3、f 1//3 和负数索引，其余指令被忽略。
+3:LoadOFF 读取 Object File Format，支持 # 注释和面后面可选的颜色。
+4:顶点索引在 Mesh 中从 0 开始，错误信息中的行号从 1 开始。
+*/
+
+// Vec3 空间中的点
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// Mesh 多边形网格
+type Mesh struct {
+	Vertices []Vec3
+	// Faces 每个面是顶点索引的列表，至少 3 个顶点
+	Faces [][]int
+}
+
+// PolygonMesh 由一个多边形构成的平面网格
+func PolygonMesh(points []Point) *Mesh {
+	m := &Mesh{Vertices: make([]Vec3, len(points)), Faces: [][]int{make([]int, len(points))}}
+	for i, p := range points {
+		m.Vertices[i] = Vec3{p.X, p.Y, 0}
+		m.Faces[0][i] = i
+	}
+	return m
+}
+
+// Edges 所有不重复的边，每条边的两个顶点按索引从小到大排列，按第一次出现的顺序返回
+func (m *Mesh) Edges() [][2]int {
+	seen := make(map[[2]int]bool)
+	var edges [][2]int
+	for _, f := range m.Faces {
+		for i, a := range f {
+			e := edgeKey(a, f[(i+1)%len(f)])
+			if !seen[e] {
+				seen[e] = true
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// Validate 检查面的顶点数量、索引范围和重复顶点
+func (m *Mesh) Validate() error {
+	for i, f := range m.Faces {
+		if err := validateFace(f, len(m.Vertices)); err != nil {
+			return fmt.Errorf("mesh: face %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateFace(face []int, vertices int) error {
+	if len(face) < 3 {
+		return fmt.Errorf("face has %d vertices, need at least 3", len(face))
+	}
+	seen := make(map[int]bool, len(face))
+	for _, v := range face {
+		if v < 0 || v >= vertices {
+			return fmt.Errorf("vertex index %d out of range [0, %d)", v, vertices)
+		}
+		if seen[v] {
+			return fmt.Errorf("vertex %d repeated", v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// LoadMesh 按扩展名（.obj 或 .off）读取网格文件
+func LoadMesh(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m *Mesh
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".obj":
+		m, err = LoadOBJ(f)
+	case ".off":
+		m, err = LoadOFF(f)
+	default:
+		return nil, fmt.Errorf("mesh: unsupported file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// LoadOBJ 读取 OBJ 格式
+func LoadOBJ(r io.Reader) (*Mesh, error) {
+	m := &Mesh{}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text, _, _ := strings.Cut(s.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("obj: line %d: vertex needs 3 coordinates", line)
+			}
+			v, err := parseVec3(fields[1:4])
+			if err != nil {
+				return nil, fmt.Errorf("obj: line %d: %w", line, err)
+			}
+			m.Vertices = append(m.Vertices, v)
+		case "f":
+			face := make([]int, 0, len(fields)-1)
+			for _, field := range fields[1:] {
+				ref, _, _ := strings.Cut(field, "/")
+				i, err := strconv.Atoi(ref)
+				if err != nil || i == 0 {
+					return nil, fmt.Errorf("obj: line %d: invalid vertex reference %q", line, field)
+				}
+				// 负数表示相对于当前已经读取的顶点
+				if i < 0 {
+					i += len(m.Vertices)
+				} else {
+					i--
+				}
+				face = append(face, i)
+			}
+			if err := validateFace(face, len(m.Vertices)); err != nil {
+				return nil, fmt.Errorf("obj: line %d: %w", line, err)
+			}
+			m.Faces = append(m.Faces, face)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadOFF 读取 OFF 格式
+func LoadOFF(r io.Reader) (*Mesh, error) {
+	s := bufio.NewScanner(r)
+	line := 0
+	// next 返回下一个非空行的字段
+	next := func() ([]string, error) {
+		for s.Scan() {
+			line++
+			text, _, _ := strings.Cut(s.Text(), "#")
+			if fields := strings.Fields(text); len(fields) > 0 {
+				return fields, nil
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("off: line %d: unexpected end of file", line)
+	}
+
+	fields, err := next()
+	if err != nil {
+		return nil, err
+	}
+	if fields[0] != "OFF" {
+		return nil, fmt.Errorf("off: line %d: missing OFF header", line)
+	}
+	// 计数可以和 OFF 写在同一行
+	if fields = fields[1:]; len(fields) == 0 {
+		if fields, err = next(); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("off: line %d: expected vertex and face counts", line)
+	}
+	nv, err1 := strconv.Atoi(fields[0])
+	nf, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || nv < 0 || nf < 0 {
+		return nil, fmt.Errorf("off: line %d: invalid counts %q", line, strings.Join(fields, " "))
+	}
+
+	m := &Mesh{Vertices: make([]Vec3, 0, nv), Faces: make([][]int, 0, nf)}
+	for i := 0; i < nv; i++ {
+		if fields, err = next(); err != nil {
+			return nil, err
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("off: line %d: vertex needs 3 coordinates", line)
+		}
+		v, err := parseVec3(fields[:3])
+		if err != nil {
+			return nil, fmt.Errorf("off: line %d: %w", line, err)
+		}
+		m.Vertices = append(m.Vertices, v)
+	}
+	for i := 0; i < nf; i++ {
+		if fields, err = next(); err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 || len(fields) < n+1 {
+			return nil, fmt.Errorf("off: line %d: invalid face %q", line, strings.Join(fields, " "))
+		}
+		face := make([]int, n)
+		for j := range face {
+			if face[j], err = strconv.Atoi(fields[j+1]); err != nil {
+				return nil, fmt.Errorf("off: line %d: invalid vertex index %q", line, fields[j+1])
+			}
+		}
+		if err := validateFace(face, len(m.Vertices)); err != nil {
+			return nil, fmt.Errorf("off: line %d: %w", line, err)
+		}
+		m.Faces = append(m.Faces, face)
+	}
+	return m, nil
+}
+
+func parseVec3(fields []string) (Vec3, error) {
+	var xyz [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return Vec3{}, fmt.Errorf("invalid coordinate %q", f)
+		}
+		xyz[i] = v
+	}
+	return Vec3{xyz[0], xyz[1], xyz[2]}, nil
+}
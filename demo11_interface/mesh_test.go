@@ -0,0 +1,186 @@
+package demo11_interface
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gridSurface 由 n*m 个四边形组成的网格，wrapU、wrapV 决定两个方向是否首尾相接，
+// twist 表示沿 u 方向接回时把 v 方向反过来（莫比乌斯带、克莱因瓶）
+func gridSurface(n, m int, wrapU, wrapV, twist bool) string {
+	rows := m + 1
+	if wrapV {
+		rows = m
+	}
+	cols := n + 1
+	if wrapU {
+		cols = n
+	}
+	id := func(i, j int) int {
+		if wrapU && i == n {
+			i = 0
+			if twist {
+				j = (m - j) % rows
+			}
+		}
+		if wrapV {
+			j %= m
+		}
+		return i*rows + j + 1
+	}
+	var b strings.Builder
+	for i := 0; i < cols; i++ {
+		for j := 0; j < rows; j++ {
+			fmt.Fprintf(&b, "v %d %d 0\n", i, j)
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			fmt.Fprintf(&b, "f %d %d %d %d\n", id(i, j), id(i+1, j), id(i+1, j+1), id(i, j+1))
+		}
+	}
+	return b.String()
+}
+
+func TestMeshTopology(t *testing.T) {
+	// 两个四面体共享一个顶点以外的部分，组成两个连通分量
+	twoTetrahedra := "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 0 0 1\nf 1 3 2\nf 1 2 4\nf 2 3 4\nf 3 1 4\n" +
+		"v 5 0 0\nv 6 0 0\nv 5 1 0\nv 5 0 1\nf 5 7 6\nf 5 6 8\nf 6 7 8\nf 7 5 8\n"
+	tests := []struct {
+		name       string
+		obj        string
+		chi        int
+		boundaries int
+		orientable bool
+		genus      int
+		surfaces   int
+	}{
+		{"disk", gridSurface(3, 3, false, false, false), 1, 1, true, 0, 1},
+		{"annulus", gridSurface(4, 2, true, false, false), 0, 2, true, 0, 1},
+		{"mobius", gridSurface(4, 2, true, false, true), 0, 1, false, 1, 1},
+		{"torus", gridSurface(4, 3, true, true, false), 0, 0, true, 1, 1},
+		{"klein bottle", gridSurface(4, 3, true, true, true), 0, 0, false, 2, 1},
+		{"two spheres", twoTetrahedra, 4, 0, true, 0, 2},
+	}
+	for _, tt := range tests {
+		m, err := LoadOBJ(strings.NewReader(tt.obj))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		topo, err := m.Topology()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if topo.EulerCharacteristic != tt.chi || topo.BoundaryComponents != tt.boundaries ||
+			topo.Orientable != tt.orientable || topo.Genus != tt.genus || len(topo.Surfaces) != tt.surfaces {
+			t.Errorf("%s: got χ=%d b=%d orientable=%v genus=%d surfaces=%d", tt.name,
+				topo.EulerCharacteristic, topo.BoundaryComponents, topo.Orientable, topo.Genus, len(topo.Surfaces))
+		}
+		if tt.orientable && m.Rank() != tt.genus {
+			t.Errorf("%s: Rank = %d, want %d", tt.name, m.Rank(), tt.genus)
+		}
+	}
+}
+
+func TestLoadMeshFiles(t *testing.T) {
+	tests := []struct {
+		file                string
+		vertices, edges     int
+		faces, genus        int
+		orientable          bool
+		boundaryComponents  int
+		eulerCharacteristic int
+	}{
+		{"cube.obj", 8, 12, 6, 0, true, 0, 2},
+		{"mobius.off", 6, 9, 3, 1, false, 1, 0},
+	}
+	for _, tt := range tests {
+		m, err := LoadMesh(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Edges()) != tt.edges {
+			t.Errorf("%s: %d edges, want %d", tt.file, len(m.Edges()), tt.edges)
+		}
+		topo, err := m.Topology()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Topology{
+			Vertices: tt.vertices, Edges: tt.edges, Faces: tt.faces, Genus: tt.genus, Orientable: tt.orientable,
+			BoundaryComponents: tt.boundaryComponents, EulerCharacteristic: tt.eulerCharacteristic,
+		}
+		topo.Surfaces = nil
+		if !reflect.DeepEqual(topo, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.file, topo, want)
+		}
+	}
+
+	// 立方体每个面的朝向一致，翻转一个面后仍然可定向
+	m, _ := LoadMesh(filepath.Join("testdata", "cube.obj"))
+	m.Faces[0] = []int{0, 1, 2, 3}
+	if topo, err := m.Topology(); err != nil || !topo.Orientable {
+		t.Errorf("flipped cube: %+v, %v", topo, err)
+	}
+
+	var shape TopologicalGenus = &Square{Side: 4.5}
+	if shape.Rank() != 0 || (Rectangle{Length: 1, Width: 2}).Rank() != 0 {
+		t.Error("squares and rectangles are disks with genus 0")
+	}
+}
+
+func TestMeshErrors(t *testing.T) {
+	tests := []struct {
+		load func(string) error
+		src  string
+		msg  string
+	}{
+		{objErr, "v 0 0 0\nv 1 0 0\nf 1 2\n", "obj: line 3: face has 2 vertices"},
+		{objErr, "v 0 0 0\nv 1 0 0\nv 1 1 0\nf 1 2 4\n", "obj: line 4: vertex index 3 out of range [0, 3)"},
+		{objErr, "v 0 0\n", "obj: line 1: vertex needs 3 coordinates"},
+		{objErr, "v 0 0 x\n", `obj: line 1: invalid coordinate "x"`},
+		{objErr, "v 0 0 0\nf 1 0 1\n", `obj: line 2: invalid vertex reference "0"`},
+		{offErr, "PLY\n", "off: line 1: missing OFF header"},
+		{offErr, "OFF 3 1 0\n0 0 0\n1 0 0\n0 1 0\n3 0 1\n", `off: line 5: invalid face "3 0 1"`},
+		{offErr, "OFF\n# 注释\n3 1\n0 0 0\n1 0 0\n", "off: line 5: unexpected end of file"},
+		{offErr, "OFF\n3 1\n0 0 0\n1 0 0\n0 1 0\n3 0 1 1\n", "off: line 6: vertex 1 repeated"},
+	}
+	for _, tt := range tests {
+		if err := tt.load(tt.src); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%q: got %v, want %q", tt.src, err, tt.msg)
+		}
+	}
+
+	// 三个面共享一条边
+	fin := &Mesh{Vertices: make([]Vec3, 5), Faces: [][]int{{0, 1, 2}, {1, 0, 3}, {0, 1, 4}}}
+	var nme *NonManifoldError
+	if _, err := fin.Topology(); !errors.As(err, &nme) || nme.Edge == nil || *nme.Edge != [2]int{0, 1} {
+		t.Errorf("got %v, want non-manifold edge 0-1", err)
+	}
+	// 两个三角形只共享一个顶点
+	bowtie := &Mesh{Vertices: make([]Vec3, 5), Faces: [][]int{{0, 1, 2}, {0, 3, 4}}}
+	if _, err := bowtie.Topology(); !errors.Is(err, ErrNonManifold) || !strings.Contains(err.Error(), "vertex 0") {
+		t.Errorf("got %v, want non-manifold vertex 0", err)
+	}
+	if bowtie.Rank() != -1 {
+		t.Errorf("Rank of non-manifold mesh = %d, want -1", bowtie.Rank())
+	}
+	if _, err := LoadMesh("cube.stl"); err == nil {
+		t.Error("want error for unsupported extension")
+	}
+}
+
+func objErr(src string) error {
+	_, err := LoadOBJ(strings.NewReader(src))
+	return err
+}
+
+func offErr(src string) error {
+	_, err := LoadOFF(strings.NewReader(src))
+	return err
+}
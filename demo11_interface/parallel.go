@@ -0,0 +1,163 @@
+package demo11_interface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// 并行处理
+/*
+1:ProcessParallel 和 MapParallel 用固定数量的 worker 并发处理元素，同时运行的 f 不超过 workers 个。
+2:默认在第一个错误出现时取消 ctx，不再分发新的元素，返回第一个错误；CollectAllErrors 会处理完全部元素并汇总所有错误。
+3:每个元素的 panic 都会被恢复并转换为 *PanicError，不会影响其他元素。
+4:MapParallel 默认按输入顺序返回结果（失败的位置是零值），Unordered 按完成顺序只返回成功的结果。
+5:返回的错误可以用 errors.As 取出 *ItemError 得到出错元素的下标。
+*/
+
+// ItemError 某个元素处理失败
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError 处理元素时发生的 panic
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ParallelOption 并行处理的选项
+type ParallelOption func(*parallelConfig)
+
+type parallelConfig struct {
+	collectAll bool
+	unordered  bool
+}
+
+// CollectAllErrors 出错后继续处理剩下的元素，返回所有错误
+func CollectAllErrors() ParallelOption {
+	return func(c *parallelConfig) { c.collectAll = true }
+}
+
+// Unordered 结果按完成顺序返回，只包含成功的元素
+func Unordered() ParallelOption {
+	return func(c *parallelConfig) { c.unordered = true }
+}
+
+// ProcessParallel 用 workers 个 goroutine 并发处理每辆车，workers <= 0 时使用 GOMAXPROCS
+func (cs Cars) ProcessParallel(ctx context.Context, workers int, f func(ctx context.Context, c *Car) error, opts ...ParallelOption) error {
+	_, err := MapParallel(ctx, cs, workers, func(ctx context.Context, c *Car) (struct{}, error) {
+		return struct{}{}, f(ctx, c)
+	}, opts...)
+	return err
+}
+
+// MapParallel 并发地对每个元素调用 f 并收集结果
+func MapParallel[T, R any](ctx context.Context, items []T, workers int, f func(ctx context.Context, item T) (R, error), opts ...ParallelOption) ([]R, error) {
+	var cfg parallelConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(items))
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		errs     []*ItemError
+		ordered  []R
+		finished []R
+	)
+	if !cfg.unordered {
+		ordered = make([]R, len(items))
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// 已经取消时不再调用 f
+				if ctx.Err() != nil {
+					continue
+				}
+				r, err := callRecover(ctx, items[i], f)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, &ItemError{Index: i, Err: err})
+					if !cfg.collectAll {
+						cancel()
+					}
+				} else if cfg.unordered {
+					finished = append(finished, r)
+				} else {
+					ordered[i] = r
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range items {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := ordered
+	if cfg.unordered {
+		results = finished
+	}
+	if len(errs) > 0 {
+		if !cfg.collectAll {
+			return results, errs[0]
+		}
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+		joined := make([]error, len(errs))
+		for i, e := range errs {
+			joined[i] = e
+		}
+		return results, errors.Join(joined...)
+	}
+	// 没有元素失败时内部的 ctx 只会因为调用方取消而结束，此时部分元素没有被处理
+	return results, parent.Err()
+}
+
+// callRecover 调用 f，把 panic 转换为 *PanicError
+func callRecover[T, R any](ctx context.Context, item T, f func(context.Context, T) (R, error)) (r R, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return f(ctx, item)
+}
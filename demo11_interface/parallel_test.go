@@ -0,0 +1,163 @@
+package demo11_interface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func manyCars(n int) Cars {
+	cars := make(Cars, n)
+	for i := range cars {
+		cars[i] = &Car{Module: fmt.Sprint(i), Manufacturer: "BYD", BuildYear: 2000 + i}
+	}
+	return cars
+}
+
+func TestProcessParallelBounded(t *testing.T) {
+	cars := manyCars(200)
+	var running, peak, done atomic.Int32
+	err := cars.ProcessParallel(context.Background(), 4, func(ctx context.Context, c *Car) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Microsecond)
+		running.Add(-1)
+		done.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Load() != 200 {
+		t.Errorf("processed %d cars", done.Load())
+	}
+	if peak.Load() > 4 {
+		t.Errorf("%d workers ran at once, want at most 4", peak.Load())
+	}
+}
+
+func TestProcessParallelFailFast(t *testing.T) {
+	cars := manyCars(1000)
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	err := cars.ProcessParallel(context.Background(), 4, func(ctx context.Context, c *Car) error {
+		calls.Add(1)
+		if c.BuildYear == 2010 {
+			return boom
+		}
+		time.Sleep(50 * time.Microsecond)
+		return nil
+	})
+	var ie *ItemError
+	if !errors.As(err, &ie) || ie.Index != 10 || !errors.Is(err, boom) {
+		t.Fatalf("got %v, want item 10: boom", err)
+	}
+	if calls.Load() > 100 {
+		t.Errorf("%d calls after the first error, want dispatch to stop", calls.Load())
+	}
+}
+
+func TestProcessParallelCollectAll(t *testing.T) {
+	cars := manyCars(50)
+	err := cars.ProcessParallel(context.Background(), 8, func(ctx context.Context, c *Car) error {
+		switch {
+		case c.BuildYear%10 == 3:
+			return fmt.Errorf("bad year %d", c.BuildYear)
+		case c.BuildYear == 2025:
+			var m map[string]int
+			m["x"] = 1 // panic
+		}
+		return nil
+	}, CollectAllErrors())
+	if err == nil {
+		t.Fatal("want error")
+	}
+
+	var indexes []int
+	var panics int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		ie := e.(*ItemError)
+		indexes = append(indexes, ie.Index)
+		var pe *PanicError
+		if errors.As(ie, &pe) {
+			panics++
+			if len(pe.Stack) == 0 {
+				t.Error("PanicError without stack")
+			}
+		}
+	}
+	if want := []int{3, 13, 23, 25, 33, 43}; !slices.Equal(indexes, want) {
+		t.Errorf("failed indexes = %v, want %v", indexes, want)
+	}
+	if panics != 1 {
+		t.Errorf("got %d panics, want 1", panics)
+	}
+}
+
+func TestMapParallelOrdering(t *testing.T) {
+	cars := manyCars(100)
+	years := func(ctx context.Context, c *Car) (int, error) {
+		// 让靠前的元素更晚完成
+		time.Sleep(time.Duration(100-c.BuildYear+2000) * 10 * time.Microsecond)
+		if c.BuildYear == 2050 {
+			return 0, errors.New("skip")
+		}
+		return c.BuildYear, nil
+	}
+
+	ordered, err := MapParallel(context.Background(), cars, 8, years, CollectAllErrors())
+	if err == nil {
+		t.Fatal("want error for item 50")
+	}
+	for i, y := range ordered {
+		want := 2000 + i
+		if i == 50 {
+			want = 0
+		}
+		if y != want {
+			t.Fatalf("ordered[%d] = %d, want %d", i, y, want)
+		}
+	}
+
+	unordered, _ := MapParallel(context.Background(), cars, 8, years, CollectAllErrors(), Unordered())
+	if len(unordered) != 99 {
+		t.Fatalf("got %d unordered results, want 99", len(unordered))
+	}
+	if slices.IsSorted(unordered) {
+		t.Error("unordered results came back in input order")
+	}
+	slices.Sort(unordered)
+	if unordered[0] != 2000 || unordered[98] != 2099 {
+		t.Errorf("unexpected unordered results %v", unordered)
+	}
+}
+
+func TestProcessParallelContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := manyCars(1000).ProcessParallel(ctx, 2, func(ctx context.Context, c *Car) error {
+		if calls.Add(1) == 5 {
+			cancel()
+		}
+		return nil
+	}, CollectAllErrors())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if calls.Load() > 20 {
+		t.Errorf("%d calls after cancel", calls.Load())
+	}
+
+	if err := (Cars{}).ProcessParallel(context.Background(), 0, func(context.Context, *Car) error { return nil }); err != nil {
+		t.Errorf("empty Cars: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package demo11_interface
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// 多边形
+/*
+1:Polygon 是任意简单多边形（边只在相邻顶点处相交），可以是凹多边形，顶点顺序可以是顺时针或逆时针。
+2:NewPolygon 会校验顶点数量、重复顶点、面积为零和自相交；直接构造的 Polygon 可以调用 Validate 校验。
+3:面积用鞋带公式计算，Contains 先检查边界，再用射线法判断。
+*/
+
+var (
+	// ErrDegenerate 多边形顶点不足或面积为零
+	ErrDegenerate = errors.New("degenerate polygon")
+	// ErrSelfIntersecting 多边形的边在顶点以外的位置相交
+	ErrSelfIntersecting = errors.New("self-intersecting polygon")
+)
+
+// Polygon 简单多边形
+type Polygon struct {
+	Points []Point `json:"points"`
+}
+
+// NewPolygon 用顶点构造多边形并校验
+func NewPolygon(points ...Point) (Polygon, error) {
+	p := Polygon{Points: slices.Clone(points)}
+	if err := p.Validate(); err != nil {
+		return Polygon{}, err
+	}
+	return p, nil
+}
+
+// Validate 检查多边形是否是简单多边形
+func (p Polygon) Validate() error {
+	n := len(p.Points)
+	if n < 3 {
+		return fmt.Errorf("polygon: %d points: %w", n, ErrDegenerate)
+	}
+	for i, a := range p.Points {
+		if b := p.Points[(i+1)%n]; a.Dist(b) <= geomEpsilon {
+			return fmt.Errorf("polygon: points %d and %d coincide: %w", i, (i+1)%n, ErrDegenerate)
+		}
+	}
+	// 三角形不会自相交，三个顶点共线时只报告面积为零
+	if n == 3 && math.Abs(polygonArea(p.Points)) <= geomEpsilon {
+		return fmt.Errorf("polygon: zero area: %w", ErrDegenerate)
+	}
+	for i := 0; i < n; i++ {
+		a, b := p.Points[i], p.Points[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			c, d := p.Points[j], p.Points[(j+1)%n]
+			var bad bool
+			switch {
+			case j == i+1:
+				// 相邻的边只能共享顶点 b，不能折返重叠
+				bad = orientation(a, b, d) == 0 && b.Sub(a).Dot(d.Sub(c)) < 0
+			case i == 0 && j == n-1:
+				// 第一条边和最后一条边共享顶点 a
+				bad = orientation(c, d, b) == 0 && d.Sub(c).Dot(b.Sub(a)) < 0
+			default:
+				bad = segmentsIntersect(a, b, c, d)
+			}
+			if bad {
+				return fmt.Errorf("polygon: edges %d and %d intersect: %w", i, j, ErrSelfIntersecting)
+			}
+		}
+	}
+	// 蝴蝶结形状的有向面积也可能为零，所以放在自相交检查之后
+	if math.Abs(polygonArea(p.Points)) <= geomEpsilon {
+		return fmt.Errorf("polygon: zero area: %w", ErrDegenerate)
+	}
+	return nil
+}
+
+func (p Polygon) Area() float64 {
+	return math.Abs(polygonArea(p.Points))
+}
+
+func (p Polygon) Perimeter() float64 {
+	return polygonPerimeter(p.Points)
+}
+
+// Vertices 逆时针顺序的顶点，顺时针的多边形会被反转
+func (p Polygon) Vertices() []Point {
+	points := slices.Clone(p.Points)
+	if polygonArea(points) < 0 {
+		slices.Reverse(points)
+	}
+	return points
+}
+
+func (p Polygon) Bounds() Box {
+	return BoxOf(p.Points...)
+}
+
+func (p Polygon) Contains(pt Point) bool {
+	return polygonContains(p.Points, pt)
+}
+
+func (p Polygon) Translate(dx, dy float64) Shaper {
+	return Polygon{transformPoints(p.Points, func(q Point) Point { return q.Add(Pt(dx, dy)) })}
+}
+
+func (p Polygon) Rotate(angle float64, pivot Point) Shaper {
+	return Polygon{transformPoints(p.Points, func(q Point) Point { return q.Rotate(angle, pivot) })}
+}
+
+func (p Polygon) Scale(k float64, pivot Point) Shaper {
+	return Polygon{transformPoints(p.Points, func(q Point) Point { return q.Scale(k, pivot) })}
+}
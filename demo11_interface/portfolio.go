@@ -0,0 +1,465 @@
+package demo11_interface
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"sync"
+)
+
+// 投资组合
+/*
+1:Portfolio 由多个持仓组成，每个持仓持有一个 valuable 作为估值，估值对应的是整个持仓。
+  成交时可以给出成交后的新估值，不给时按单位数等比例缩放原来的估值；Mark 只更新估值。
+2:每个持仓有自己的货币，成本、已实现和未实现盈亏都以持仓货币记录，生成报表时按当时的汇率换算到报表货币。
+3:卖出按平均成本法结转成本：已实现盈亏 = 卖出金额 - 卖出单位数 × 平均成本。
+4:资产类别优先使用成交时指定的 Class，其次是资产实现的 classifier 接口，都没有时为 "other"。
+5:Report 可以导出为 JSON，持仓明细和资产配置也可以分别导出为 CSV。
+6:单位数、金额、汇率和权重都是 Decimal，只在导出 CSV 时舍入。金额最多保留 amountScale 位小数，
+  这样小数单位数（例如 1/3 股）乘出来的 16 位小数不会在求和时溢出；权重保留 weightScale 位小数。都按 RoundHalfEven 舍入。
+*/
+
+var (
+	// ErrHoldingNotFound 指定名称的持仓不存在
+	ErrHoldingNotFound = errors.New("holding not found")
+	// ErrInsufficientUnits 卖出的单位数超过持仓
+	ErrInsufficientUnits = errors.New("insufficient units")
+)
+
+const (
+	// amountScale 金额最多保留的小数位数
+	amountScale = 6
+	// weightScale 权重保留的小数位数
+	weightScale = 6
+)
+
+// Trade 一笔成交
+type Trade struct {
+	Holding string
+	// Class 和 Currency 只在建仓时使用，Currency 为空时使用组合的报表货币
+	Class    string
+	Currency string
+	// Units 成交的单位数，必须大于 0
+	Units Decimal
+	// Amount 成交金额（持仓货币），买入时是成本，卖出时是收入
+	Amount Decimal
+	// Asset 成交后整个持仓的估值，为 nil 时按单位数缩放原来的估值
+	Asset valuable
+}
+
+// Holding 一个持仓
+type Holding struct {
+	Name     string
+	Class    string
+	Currency string
+	Units    Decimal
+	// CostBasis 当前持有部分的总成本
+	CostBasis Decimal
+	// Realized 累计已实现盈亏
+	Realized Decimal
+	Asset    valuable
+	// assetUnits Asset 的估值对应的单位数
+	assetUnits Decimal
+}
+
+// MarketValue 持仓市值（持仓货币）
+func (h *Holding) MarketValue() (Decimal, error) {
+	if h.Asset == nil || h.assetUnits.IsZero() {
+		return Decimal{}, nil
+	}
+	v, err := h.Asset.getValue()
+	if err != nil {
+		return Decimal{}, err
+	}
+	if v.Scale() > amountScale {
+		v, _ = v.Round(amountScale, RoundHalfEven)
+	}
+	return prorate(v, h.Units, h.assetUnits)
+}
+
+// Unrealized 未实现盈亏（持仓货币）
+func (h *Holding) Unrealized() (Decimal, error) {
+	v, err := h.MarketValue()
+	if err != nil {
+		return Decimal{}, err
+	}
+	return v.Sub(h.CostBasis)
+}
+
+// Portfolio 投资组合，可以被多个 goroutine 同时使用
+type Portfolio struct {
+	mu       sync.RWMutex
+	base     string
+	fx       FXRates
+	holdings map[string]*Holding
+	order    []string
+}
+
+// NewPortfolio 创建以 base 为报表货币的组合，fx 为 nil 时只能持有 base 货币的资产
+func NewPortfolio(base string, fx FXRates) *Portfolio {
+	return &Portfolio{base: base, fx: fx, holdings: make(map[string]*Holding)}
+}
+
+// Base 报表货币
+func (p *Portfolio) Base() string {
+	return p.base
+}
+
+// Buy 买入，持仓不存在时建仓
+func (p *Portfolio) Buy(t Trade) error {
+	if err := checkTrade(t); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.holdings[t.Holding]
+	if !ok {
+		if t.Asset == nil {
+			return fmt.Errorf("buy %q: new holding needs an asset", t.Holding)
+		}
+		h = &Holding{Name: t.Holding, Class: t.Class, Currency: t.Currency}
+		if h.Currency == "" {
+			h.Currency = p.base
+		}
+		if h.Class == "" {
+			h.Class = "other"
+			if c, ok := t.Asset.(classifier); ok {
+				h.Class = c.assetClass()
+			}
+		}
+	}
+	var c decimalCalc
+	units := c.add(h.Units, t.Units)
+	cost := c.add(h.CostBasis, t.Amount)
+	if c.err != nil {
+		return fmt.Errorf("buy %q: %w", t.Holding, c.err)
+	}
+	if !ok {
+		p.holdings[t.Holding] = h
+		p.order = append(p.order, t.Holding)
+	}
+	h.Units, h.CostBasis = units, cost
+	h.revalue(t.Asset)
+	return nil
+}
+
+// Sell 卖出，按平均成本结转已实现盈亏
+func (p *Portfolio) Sell(t Trade) error {
+	if err := checkTrade(t); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.holdings[t.Holding]
+	if !ok {
+		return fmt.Errorf("sell %q: %w", t.Holding, ErrHoldingNotFound)
+	}
+	if t.Units.Cmp(h.Units) > 0 {
+		return fmt.Errorf("sell %q: %v units, holding %v: %w", t.Holding, t.Units, h.Units, ErrInsufficientUnits)
+	}
+	cost, err := prorate(h.CostBasis, t.Units, h.Units)
+	if err != nil {
+		return fmt.Errorf("sell %q: %w", t.Holding, err)
+	}
+	var c decimalCalc
+	realized := c.add(h.Realized, c.sub(t.Amount, cost))
+	costBasis := c.sub(h.CostBasis, cost)
+	units := c.sub(h.Units, t.Units)
+	if c.err != nil {
+		return fmt.Errorf("sell %q: %w", t.Holding, c.err)
+	}
+	h.Realized, h.CostBasis, h.Units = realized, costBasis, units
+	h.revalue(t.Asset)
+	return nil
+}
+
+// Mark 用新的估值更新持仓，例如股价变动后
+func (p *Portfolio) Mark(name string, asset valuable) error {
+	if asset == nil {
+		return fmt.Errorf("mark %q: nil asset", name)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.holdings[name]
+	if !ok {
+		return fmt.Errorf("mark %q: %w", name, ErrHoldingNotFound)
+	}
+	h.revalue(asset)
+	return nil
+}
+
+// Get 返回持仓的副本
+func (p *Portfolio) Get(name string) (Holding, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	h, ok := p.holdings[name]
+	if !ok {
+		return Holding{}, fmt.Errorf("get %q: %w", name, ErrHoldingNotFound)
+	}
+	return *h, nil
+}
+
+// Holdings 按建仓顺序返回所有持仓的副本，包括已经清仓的
+func (p *Portfolio) Holdings() []Holding {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	hs := make([]Holding, len(p.order))
+	for i, name := range p.order {
+		hs[i] = *p.holdings[name]
+	}
+	return hs
+}
+
+func checkTrade(t Trade) error {
+	if t.Holding == "" {
+		return errors.New("trade: empty holding name")
+	}
+	if t.Units.Sign() <= 0 {
+		return fmt.Errorf("trade %q: invalid units %v", t.Holding, t.Units)
+	}
+	if t.Amount.Sign() < 0 {
+		return fmt.Errorf("trade %q: invalid amount %v", t.Holding, t.Amount)
+	}
+	return nil
+}
+
+// revalue 成交或者重新估值后更新估值，asset 为 nil 时保持原来的单位估值
+func (h *Holding) revalue(asset valuable) {
+	if asset != nil {
+		h.Asset = asset
+		h.assetUnits = h.Units
+	}
+}
+
+// mulAmount 计算金额 a × b，结果的 scale 是两者中较大的一个，但不超过 amountScale
+func mulAmount(a, b Decimal) (Decimal, error) {
+	scale := min(max(a.Scale(), b.Scale()), amountScale)
+	return DecimalContext{Scale: scale, Mode: RoundHalfEven}.Mul(a, b)
+}
+
+// prorate 计算 amount × part / whole，part 等于 whole 时原样返回 amount
+func prorate(amount, part, whole Decimal) (Decimal, error) {
+	if part.Cmp(whole) == 0 {
+		return amount, nil
+	}
+	// 先精确相乘再除，只在除法时舍入一次
+	p := new(big.Int).Mul(amount.big(), part.big())
+	q, err := quo(p, amount.Scale()+part.Scale(), whole, amountScale, RoundHalfEven)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return q.Reduce(), nil
+}
+
+// decimalCalc 连续做多次 Decimal 运算，记录第一个错误，出错后的运算都返回零值
+type decimalCalc struct {
+	err error
+}
+
+func (c *decimalCalc) do(d Decimal, err error) Decimal {
+	if c.err != nil {
+		return Decimal{}
+	}
+	c.err = err
+	return d
+}
+
+func (c *decimalCalc) add(a, b Decimal) Decimal {
+	return c.do(a.Add(b))
+}
+
+func (c *decimalCalc) sub(a, b Decimal) Decimal {
+	return c.do(a.Sub(b))
+}
+
+func (c *decimalCalc) mul(a, b Decimal) Decimal {
+	return c.do(mulAmount(a, b))
+}
+
+// HoldingReport 报表中的一个持仓，Base 开头的字段已经换算为报表货币
+type HoldingReport struct {
+	Name         string  `json:"name"`
+	Class        string  `json:"class"`
+	Currency     string  `json:"currency"`
+	Units        Decimal `json:"units"`
+	CostBasis    Decimal `json:"costBasis"`
+	MarketValue  Decimal `json:"marketValue"`
+	Unrealized   Decimal `json:"unrealized"`
+	Realized     Decimal `json:"realized"`
+	Rate         Decimal `json:"rate"`
+	BaseCost     Decimal `json:"baseCost"`
+	BaseValue    Decimal `json:"baseValue"`
+	BaseUnreal   Decimal `json:"baseUnrealized"`
+	BaseRealized Decimal `json:"baseRealized"`
+	// Weight 市值占组合总市值的比例
+	Weight Decimal `json:"weight"`
+}
+
+// Allocation 一个资产类别的市值和占比
+type Allocation struct {
+	Class       string  `json:"class"`
+	MarketValue Decimal `json:"marketValue"`
+	Weight      Decimal `json:"weight"`
+}
+
+// Report 组合报表，金额都是报表货币
+type Report struct {
+	Currency    string          `json:"currency"`
+	Holdings    []HoldingReport `json:"holdings"`
+	Allocation  []Allocation    `json:"allocation"`
+	CostBasis   Decimal         `json:"costBasis"`
+	MarketValue Decimal         `json:"marketValue"`
+	Unrealized  Decimal         `json:"unrealized"`
+	Realized    Decimal         `json:"realized"`
+}
+
+// Report 按当前估值和汇率生成报表，资产配置按市值从大到小排列
+func (p *Portfolio) Report() (*Report, error) {
+	holdings := p.Holdings()
+	r := &Report{Currency: p.base, Holdings: make([]HoldingReport, 0, len(holdings))}
+	classes := make(map[string]Decimal)
+	for _, h := range holdings {
+		rate, err := p.rate(h.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("report %q: %w", h.Name, err)
+		}
+		hr := HoldingReport{
+			Name: h.Name, Class: h.Class, Currency: h.Currency, Units: h.Units,
+			CostBasis: h.CostBasis, Realized: h.Realized, Rate: rate,
+		}
+		if hr.MarketValue, err = h.MarketValue(); err != nil {
+			return nil, fmt.Errorf("report %q: %w", h.Name, err)
+		}
+		var c decimalCalc
+		hr.Unrealized = c.sub(hr.MarketValue, hr.CostBasis)
+		hr.BaseCost = c.mul(hr.CostBasis, rate)
+		hr.BaseValue = c.mul(hr.MarketValue, rate)
+		hr.BaseUnreal = c.mul(hr.Unrealized, rate)
+		hr.BaseRealized = c.mul(hr.Realized, rate)
+		r.CostBasis = c.add(r.CostBasis, hr.BaseCost)
+		r.MarketValue = c.add(r.MarketValue, hr.BaseValue)
+		r.Unrealized = c.add(r.Unrealized, hr.BaseUnreal)
+		r.Realized = c.add(r.Realized, hr.BaseRealized)
+		if h.Units.Sign() > 0 {
+			classes[h.Class] = c.add(classes[h.Class], hr.BaseValue)
+		}
+		if c.err != nil {
+			return nil, fmt.Errorf("report %q: %w", h.Name, c.err)
+		}
+		r.Holdings = append(r.Holdings, hr)
+	}
+
+	weight := func(v Decimal) (Decimal, error) {
+		if r.MarketValue.IsZero() {
+			return Decimal{}, nil
+		}
+		w, err := v.Div(r.MarketValue, weightScale, RoundHalfEven)
+		return w.Reduce(), err
+	}
+	for i := range r.Holdings {
+		w, err := weight(r.Holdings[i].BaseValue)
+		if err != nil {
+			return nil, fmt.Errorf("report %q: %w", r.Holdings[i].Name, err)
+		}
+		r.Holdings[i].Weight = w
+	}
+	for class, v := range classes {
+		w, err := weight(v)
+		if err != nil {
+			return nil, fmt.Errorf("report class %q: %w", class, err)
+		}
+		r.Allocation = append(r.Allocation, Allocation{Class: class, MarketValue: v, Weight: w})
+	}
+	slices.SortFunc(r.Allocation, func(a, b Allocation) int {
+		if c := b.MarketValue.Cmp(a.MarketValue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Class, b.Class)
+	})
+	return r, nil
+}
+
+func (p *Portfolio) rate(currency string) (Decimal, error) {
+	if currency == p.base {
+		return NewDecimal(1, 0), nil
+	}
+	if p.fx == nil {
+		return Decimal{}, fmt.Errorf("fx: %s/%s: %w", currency, p.base, ErrNoRate)
+	}
+	return p.fx.Rate(currency, p.base)
+}
+
+var holdingCSVHeader = []string{
+	"name", "class", "currency", "units", "cost_basis", "market_value", "unrealized", "realized",
+	"rate", "base_cost", "base_value", "base_unrealized", "base_realized", "weight",
+}
+
+// WriteJSON 以缩进的 JSON 写出整个报表
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 写出持仓明细，最后一行是报表货币的合计，金额保留两位小数，权重保留四位小数
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(holdingCSVHeader); err != nil {
+		return err
+	}
+	for _, h := range r.Holdings {
+		row := []string{h.Name, h.Class, h.Currency, h.Units.String()}
+		row = appendRounded(row, 2, h.CostBasis, h.MarketValue, h.Unrealized, h.Realized)
+		row = append(row, h.Rate.String())
+		row = appendRounded(row, 2, h.BaseCost, h.BaseValue, h.BaseUnreal, h.BaseRealized)
+		row = appendRounded(row, 4, h.Weight)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	total := []string{"total", "", r.Currency, "", "", "", "", "", ""}
+	total = appendRounded(total, 2, r.CostBasis, r.MarketValue, r.Unrealized, r.Realized)
+	total = append(total, "")
+	if err := cw.Write(total); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteAllocationCSV 写出按资产类别的配置
+func (r *Report) WriteAllocationCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"class", "market_value", "weight"}); err != nil {
+		return err
+	}
+	for _, a := range r.Allocation {
+		row := appendRounded([]string{a.Class}, 2, a.MarketValue)
+		if err := cw.Write(appendRounded(row, 4, a.Weight)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// appendRounded 把每个值舍入到 scale 位小数后追加到 row，补零后溢出的值原样输出
+func appendRounded(row []string, scale int, vs ...Decimal) []string {
+	for _, v := range vs {
+		if rounded, err := v.Round(scale, RoundHalfEven); err == nil {
+			v = rounded
+		}
+		row = append(row, v.String())
+	}
+	return row
+}
@@ -0,0 +1,228 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testPortfolio(t *testing.T) *Portfolio {
+	t.Helper()
+	d := MustParseDecimal
+	fx, err := NewRateTable("USD", map[string]Decimal{"EUR": d("1.1"), "CNY": d("0.14")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPortfolio("USD", fx)
+	steps := []error{
+		p.Buy(Trade{Holding: "ESOP", Currency: "EUR", Units: d("10"), Amount: d("800"), Asset: stockPosition{ticker: "ESOP", sharePrice: d("80"), count: d("10")}}),
+		p.Mark("ESOP", stockPosition{ticker: "ESOP", sharePrice: d("90"), count: d("10")}),
+		// 卖出 4 股，成本 320，已实现盈亏 60，剩余 6 股的估值按比例缩放为 540
+		p.Sell(Trade{Holding: "ESOP", Units: d("4"), Amount: d("380")}),
+		p.Buy(Trade{Holding: "BMW", Currency: "CNY", Units: d("1"), Amount: d("500000"), Asset: car{make: "Ben", model: "BMW", price: d("459800")}}),
+		p.Buy(Trade{Holding: "T-Bond", Class: "fixed income", Units: d("1"), Amount: d("1000"), Asset: bond{Face: d("1000"), Coupon: d("0.05")}}),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+	return p
+}
+
+// decimalCheck 按值比较 Decimal，不考虑 scale
+type decimalCheck struct {
+	name string
+	got  Decimal
+	want string
+}
+
+func checkDecimals(t *testing.T, checks []decimalCheck) {
+	t.Helper()
+	for _, c := range checks {
+		if c.got.Cmp(MustParseDecimal(c.want)) != 0 {
+			t.Errorf("%s = %v, want %s", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestPortfolioReport(t *testing.T) {
+	p := testPortfolio(t)
+	h, err := p.Get("ESOP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := h.MarketValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrealized, _ := h.Unrealized()
+	checkDecimals(t, []decimalCheck{
+		{"ESOP units", h.Units, "6"},
+		{"ESOP cost basis", h.CostBasis, "480"},
+		{"ESOP realized", h.Realized, "60"},
+		{"ESOP market value", value, "540"},
+		{"ESOP unrealized", unrealized, "60"},
+	})
+
+	r, err := p.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantClasses := []string{"equity", "vehicle", "fixed income"}
+	for i, hr := range r.Holdings {
+		if hr.Class != wantClasses[i] {
+			t.Errorf("%s: class %q, want %q", hr.Name, hr.Class, wantClasses[i])
+		}
+	}
+	checkDecimals(t, []decimalCheck{
+		{"cost basis", r.CostBasis, "71528"},
+		{"market value", r.MarketValue, "66016"},
+		{"unrealized", r.Unrealized, "-5512"},
+		{"realized", r.Realized, "66"},
+		// 64372 / 66016 保留 6 位小数
+		{"BMW weight", r.Holdings[1].Weight, "0.975097"},
+	})
+	var order []string
+	var sum Decimal
+	for _, a := range r.Allocation {
+		order = append(order, a.Class)
+		sum, _ = sum.Add(a.Weight)
+	}
+	if want := []string{"vehicle", "fixed income", "equity"}; !reflect.DeepEqual(order, want) || sum.String() != "1.000000" {
+		t.Errorf("allocation %v (weights sum to %v), want %v", order, sum, want)
+	}
+
+	// 清仓后保留已实现盈亏，不再出现在资产配置中
+	if err := p.Sell(Trade{Holding: "ESOP", Units: NewDecimal(6, 0), Amount: NewDecimal(600, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = p.Report()
+	if h := r.Holdings[0]; !h.Units.IsZero() || !h.MarketValue.IsZero() || !h.CostBasis.IsZero() || h.Realized.String() != "180" || len(r.Allocation) != 2 {
+		t.Errorf("closed holding %+v, allocation %+v", h, r.Allocation)
+	}
+}
+
+// TestPortfolioFractionalUnits 小数单位数按比例结转成本，估值和报表不会溢出
+func TestPortfolioFractionalUnits(t *testing.T) {
+	d := MustParseDecimal
+	third, _ := DecimalFromFloat(1.0 / 3)
+	p := NewPortfolio("USD", nil)
+	if err := p.Buy(Trade{Holding: "ESOP", Units: d("1"), Amount: d("100.00"), Asset: stockPosition{ticker: "ESOP", sharePrice: d("88.99"), count: d("1")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Sell(Trade{Holding: "ESOP", Units: third, Amount: d("30.00")}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := r.Holdings[0]
+	checkDecimals(t, []decimalCheck{
+		{"units", h.Units, "0.6666666666666667"},
+		// 卖出部分的成本 100 × 0.3333333333333333 = 33.333333（保留 6 位小数）
+		{"cost basis", h.CostBasis, "66.666667"},
+		{"realized", h.Realized, "-3.333333"},
+		{"market value", h.MarketValue, "59.326667"},
+	})
+	if err := p.Sell(Trade{Holding: "ESOP", Units: h.Units, Amount: d("60")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.Get("ESOP"); !got.Units.IsZero() || !got.CostBasis.IsZero() {
+		t.Errorf("after closing: %+v", got)
+	}
+}
+
+func TestPortfolioExport(t *testing.T) {
+	r, err := testPortfolio(t).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"name,class,currency,units,cost_basis,market_value,unrealized,realized,rate,base_cost,base_value,base_unrealized,base_realized,weight",
+		"ESOP,equity,EUR,6,480.00,540.00,60.00,60.00,1.1,528.00,594.00,66.00,66.00,0.0090",
+		"BMW,vehicle,CNY,1,500000.00,459800.00,-40200.00,0.00,0.14,70000.00,64372.00,-5628.00,0.00,0.9751",
+		"T-Bond,fixed income,USD,1,1000.00,1050.00,50.00,0.00,1,1000.00,1050.00,50.00,0.00,0.0159",
+		"total,,USD,,,,,,,71528.00,66016.00,-5512.00,66.00,",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("CSV:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	if err := r.WriteAllocationCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "class,market_value,weight\nvehicle,64372.00,0.9751\nfixed income,1050.00,0.0159\nequity,594.00,0.0090\n"; buf.String() != want {
+		t.Errorf("allocation CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Errorf("JSON round trip:\n%+v\nwant:\n%+v", got, *r)
+	}
+}
+
+func TestPortfolioErrors(t *testing.T) {
+	p := testPortfolio(t)
+	d := MustParseDecimal
+	if err := p.Sell(Trade{Holding: "ESOP", Units: d("6.000001"), Amount: d("700")}); !errors.Is(err, ErrInsufficientUnits) {
+		t.Errorf("oversell: got %v", err)
+	}
+	if err := p.Mark("AAPL", stockPosition{}); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("mark: got %v", err)
+	}
+	if err := p.Buy(Trade{Holding: "cash", Units: d("1"), Amount: d("10")}); err == nil {
+		t.Error("new holding without asset: want error")
+	}
+	if err := p.Buy(Trade{Holding: "ESOP", Units: d("-1"), Amount: d("10")}); err == nil {
+		t.Error("negative units: want error")
+	}
+	if err := p.Buy(Trade{Holding: "ESOP", Units: d("1"), Amount: NewDecimal(math.MaxInt64, 0)}); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("cost overflow: got %v", err)
+	}
+	if h, _ := p.Get("ESOP"); h.Units.String() != "6" {
+		t.Errorf("failed buy changed units to %v", h.Units)
+	}
+
+	if err := p.Buy(Trade{Holding: "Toyota", Currency: "JPY", Units: d("1"), Amount: d("3000000"), Asset: car{price: d("2800000")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Report(); !errors.Is(err, ErrNoRate) || !strings.Contains(err.Error(), "Toyota") {
+		t.Errorf("missing rate: got %v", err)
+	}
+
+	// 任意函数都可以作为汇率来源
+	fixed := FXFunc(func(from, to string) (Decimal, error) { return d("0.5"), nil })
+	q := NewPortfolio("GBP", fixed)
+	q.Buy(Trade{Holding: "Toyota", Currency: "JPY", Units: d("1"), Amount: d("3000000"), Asset: car{price: d("2800000")}})
+	if r, err := q.Report(); err != nil || r.MarketValue.String() != "1400000.0" {
+		t.Errorf("FXFunc report: %+v, %v", r, err)
+	}
+	if _, err := NewRateTable("USD", map[string]Decimal{"EUR": d("-1")}); err == nil {
+		t.Error("negative rate: want error")
+	}
+	// 交叉汇率保留 CrossRateScale 位小数
+	fx, _ := NewRateTable("USD", map[string]Decimal{"EUR": d("1.1"), "CNY": d("0.14")})
+	if rate, err := fx.Rate("EUR", "CNY"); err != nil || rate.String() != "7.8571428571" {
+		t.Errorf("EUR/CNY = %v, %v", rate, err)
+	}
+}
@@ -0,0 +1,756 @@
+package demo11_interface
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 谓词表达式
+/*
+1:用字符串描述过滤条件，例如：
+	Manufacturer == "BYD" && BuildYear > 2020 || Module in ("1", "2")
+2:处理分为四步：词法分析得到 token，语法分析得到语法树，类型检查通过反射校验字段名和操作数类型，最后编译为闭包。
+3:支持的语法，优先级从低到高：
+	a:||
+	b:&&
+	c:!
+	d:比较 == != < <= > >=，以及 in (...) 和 not in (...)
+	e:字段名、字符串 "..."、整数、浮点数、true、false、括号
+4:字段可以是字符串、整数、浮点数和布尔类型；整数和浮点数之间可以比较，其他类型必须一致。
+5:所有错误都是 *PredicateError，带有出错的列号（从 1 开始）。
+*/
+
+// PredicateError 谓词表达式的错误
+type PredicateError struct {
+	// Column 出错位置的列号，从 1 开始，按字符计算
+	Column int
+	Msg    string
+}
+
+func (e *PredicateError) Error() string {
+	return fmt.Sprintf("predicate: column %d: %s", e.Column, e.Msg)
+}
+
+// CompileCarPredicate 把表达式编译为可以传给 Cars.FindAll 的函数
+func CompileCarPredicate(expr string) (func(c *Car) bool, error) {
+	return CompilePredicate[Car](expr)
+}
+
+// FindAllWhere 按字符串表达式过滤
+func (cs Cars) FindAllWhere(expr string) (Cars, error) {
+	pred, err := CompileCarPredicate(expr)
+	if err != nil {
+		return nil, err
+	}
+	return cs.FindAll(pred), nil
+}
+
+// CompilePredicate 把表达式编译为结构体 T 上的谓词，字段名对应 T 的导出字段；nil 指针总是返回 false
+func CompilePredicate[T any](expr string) (func(v *T) bool, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("predicate: %s is not a struct type", t)
+	}
+	root, err := parsePredicate(expr, t)
+	if err != nil {
+		return nil, err
+	}
+	return bindPredicate[T](root), nil
+}
+
+// parsePredicate 对表达式做词法分析、语法分析和类型检查，返回已经标注好类型的语法树
+func parsePredicate(expr string, t reflect.Type) (predNode, error) {
+	tokens, err := lexPredicate(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &predParser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	typ, err := checkPredicate(root, t)
+	if err != nil {
+		return nil, err
+	}
+	if typ != predBool {
+		return nil, predErrorf(root.pos(), "expression has type %s, want bool", typ)
+	}
+	return root, nil
+}
+
+// bindPredicate 把检查过的语法树编译为 *T 上的谓词
+func bindPredicate[T any](root predNode) func(v *T) bool {
+	eval := compilePredicate(root).(func(reflect.Value) bool)
+	return func(v *T) bool {
+		if v == nil {
+			return false
+		}
+		return eval(reflect.ValueOf(v).Elem())
+	}
+}
+
+func predErrorf(pos int, format string, args ...any) error {
+	return &PredicateError{Column: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 词法分析
+
+type predTokenKind int
+
+const (
+	tokEOF predTokenKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokFloat
+	tokTrue
+	tokFalse
+	tokAnd    // &&
+	tokOr     // ||
+	tokNot    // !
+	tokEq     // ==
+	tokNe     // !=
+	tokLt     // <
+	tokLe     // <=
+	tokGt     // >
+	tokGe     // >=
+	tokIn     // in
+	tokNotKw  // not
+	tokLParen // (
+	tokRParen // )
+	tokComma  // ,
+)
+
+var predTokenNames = map[predTokenKind]string{
+	tokEOF: "end of expression", tokIdent: "identifier", tokString: "string", tokInt: "integer", tokFloat: "number",
+	tokTrue: "true", tokFalse: "false", tokAnd: "&&", tokOr: "||", tokNot: "!", tokEq: "==", tokNe: "!=",
+	tokLt: "<", tokLe: "<=", tokGt: ">", tokGe: ">=", tokIn: "in", tokNotKw: "not", tokLParen: "(", tokRParen: ")", tokComma: ",",
+}
+
+func (k predTokenKind) String() string {
+	return predTokenNames[k]
+}
+
+type predToken struct {
+	kind predTokenKind
+	text string
+	// pos 列号，从 1 开始
+	pos int
+}
+
+func (t predToken) describe() string {
+	switch t.kind {
+	case tokIdent, tokString, tokInt, tokFloat:
+		return fmt.Sprintf("%s %s", t.kind, t.text)
+	case tokEOF:
+		return t.kind.String()
+	}
+	return strconv.Quote(t.kind.String())
+}
+
+var predOperators = []struct {
+	text string
+	kind predTokenKind
+}{
+	{"&&", tokAnd}, {"||", tokOr}, {"==", tokEq}, {"!=", tokNe}, {"<=", tokLe}, {">=", tokGe},
+	{"!", tokNot}, {"<", tokLt}, {">", tokGt}, {"(", tokLParen}, {")", tokRParen}, {",", tokComma},
+}
+
+func lexPredicate(expr string) ([]predToken, error) {
+	var tokens []predToken
+	col := 1
+	for i := 0; i < len(expr); {
+		r, width := utf8.DecodeRuneInString(expr[i:])
+		start := col
+		switch {
+		case unicode.IsSpace(r):
+			i += width
+			col++
+			continue
+		case r == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, predErrorf(start, "unterminated string")
+			}
+			s, err := strconv.Unquote(expr[i : j+1])
+			if err != nil {
+				return nil, predErrorf(start, "invalid string %s", expr[i:j+1])
+			}
+			tokens = append(tokens, predToken{kind: tokString, text: s, pos: start})
+			col += utf8.RuneCountInString(expr[i : j+1])
+			i = j + 1
+			continue
+		case r == '-' || unicode.IsDigit(r):
+			j := i + 1
+			kind := tokInt
+			for j < len(expr) && (isDigit(expr[j]) || expr[j] == '.' || expr[j] == 'e' || expr[j] == 'E' ||
+				(expr[j] == '-' || expr[j] == '+') && (expr[j-1] == 'e' || expr[j-1] == 'E')) {
+				if !isDigit(expr[j]) {
+					kind = tokFloat
+				}
+				j++
+			}
+			text := expr[i:j]
+			var err error
+			if kind == tokInt {
+				_, err = strconv.ParseInt(text, 10, 64)
+			} else {
+				_, err = strconv.ParseFloat(text, 64)
+			}
+			if err != nil || text == "-" {
+				return nil, predErrorf(start, "invalid number %q", text)
+			}
+			tokens = append(tokens, predToken{kind: kind, text: text, pos: start})
+			col += j - i
+			i = j
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(expr) {
+				r, w := utf8.DecodeRuneInString(expr[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += w
+			}
+			text := expr[i:j]
+			kind := tokIdent
+			switch text {
+			case "true":
+				kind = tokTrue
+			case "false":
+				kind = tokFalse
+			case "in":
+				kind = tokIn
+			case "not":
+				kind = tokNotKw
+			}
+			tokens = append(tokens, predToken{kind: kind, text: text, pos: start})
+			col += utf8.RuneCountInString(text)
+			i = j
+			continue
+		}
+
+		matched := false
+		for _, op := range predOperators {
+			if strings.HasPrefix(expr[i:], op.text) {
+				tokens = append(tokens, predToken{kind: op.kind, text: op.text, pos: start})
+				i += len(op.text)
+				col += len(op.text)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, predErrorf(start, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, predToken{kind: tokEOF, pos: col}), nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// 语法树
+
+type predNode interface {
+	pos() int
+}
+
+// predField 字段引用
+type predField struct {
+	name  string
+	p     int
+	index []int
+	typ   predType
+}
+
+// predLiteral 字面量，value 的类型是 string、int64、float64 或 bool
+type predLiteral struct {
+	value any
+	p     int
+	typ   predType
+}
+
+// predUnary 逻辑非
+type predUnary struct {
+	x predNode
+	p int
+}
+
+// predBinary 逻辑运算或比较
+type predBinary struct {
+	op   predTokenKind
+	x, y predNode
+	p    int
+	// operand 比较时操作数统一后的类型
+	operand predType
+}
+
+// predIn x in (...) 或 x not in (...)
+type predIn struct {
+	x       predNode
+	list    []*predLiteral
+	negate  bool
+	p       int
+	operand predType
+}
+
+func (n *predField) pos() int   { return n.p }
+func (n *predLiteral) pos() int { return n.p }
+func (n *predUnary) pos() int   { return n.p }
+func (n *predBinary) pos() int  { return n.x.pos() }
+func (n *predIn) pos() int      { return n.x.pos() }
+
+// 语法分析
+
+type predParser struct {
+	tokens []predToken
+	i      int
+}
+
+func (p *predParser) peek() predToken {
+	return p.tokens[p.i]
+}
+
+func (p *predParser) next() predToken {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *predParser) expect(kind predTokenKind) (predToken, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, predErrorf(t.pos, "expected %q, found %s", kind.String(), t.describe())
+	}
+	return t, nil
+}
+
+func (p *predParser) parse() (predNode, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, predErrorf(t.pos, "unexpected %s", t.describe())
+	}
+	return n, nil
+}
+
+func (p *predParser) parseOr() (predNode, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		op := p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &predBinary{op: tokOr, x: x, y: y, p: op.pos}
+	}
+	return x, nil
+}
+
+func (p *predParser) parseAnd() (predNode, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		op := p.next()
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &predBinary{op: tokAnd, x: x, y: y, p: op.pos}
+	}
+	return x, nil
+}
+
+func (p *predParser) parseNot() (predNode, error) {
+	if t := p.peek(); t.kind == tokNot {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &predUnary{x: x, p: t.pos}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *predParser) parseComparison() (predNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch t.kind {
+	case tokEq, tokNe, tokLt, tokLe, tokGt, tokGe:
+		p.next()
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &predBinary{op: t.kind, x: x, y: y, p: t.pos}, nil
+	case tokIn, tokNotKw:
+		p.next()
+		negate := t.kind == tokNotKw
+		if negate {
+			if _, err := p.expect(tokIn); err != nil {
+				return nil, err
+			}
+		}
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &predIn{x: x, list: list, negate: negate, p: t.pos}, nil
+	}
+	return x, nil
+}
+
+func (p *predParser) parseList() ([]*predLiteral, error) {
+	if _, err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+	var list []*predLiteral
+	for {
+		t := p.next()
+		lit, ok := literalFromToken(t)
+		if !ok {
+			return nil, predErrorf(t.pos, "expected literal in list, found %s", t.describe())
+		}
+		list = append(list, lit)
+		t = p.next()
+		if t.kind == tokRParen {
+			return list, nil
+		}
+		if t.kind != tokComma {
+			return nil, predErrorf(t.pos, "expected \",\" or \")\", found %s", t.describe())
+		}
+	}
+}
+
+func (p *predParser) parsePrimary() (predNode, error) {
+	t := p.next()
+	if lit, ok := literalFromToken(t); ok {
+		return lit, nil
+	}
+	switch t.kind {
+	case tokIdent:
+		return &predField{name: t.text, p: t.pos}, nil
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return nil, predErrorf(t.pos, "unexpected %s", t.describe())
+}
+
+func literalFromToken(t predToken) (*predLiteral, bool) {
+	switch t.kind {
+	case tokString:
+		return &predLiteral{value: t.text, p: t.pos}, true
+	case tokInt:
+		i, _ := strconv.ParseInt(t.text, 10, 64)
+		return &predLiteral{value: i, p: t.pos}, true
+	case tokFloat:
+		f, _ := strconv.ParseFloat(t.text, 64)
+		return &predLiteral{value: f, p: t.pos}, true
+	case tokTrue, tokFalse:
+		return &predLiteral{value: t.kind == tokTrue, p: t.pos}, true
+	}
+	return nil, false
+}
+
+// 类型检查
+
+type predType int
+
+const (
+	predInvalid predType = iota
+	predString
+	predInt
+	predFloat
+	predBool
+)
+
+func (t predType) String() string {
+	return [...]string{"invalid", "string", "int", "float", "bool"}[t]
+}
+
+func (t predType) numeric() bool {
+	return t == predInt || t == predFloat
+}
+
+// checkPredicate 校验字段名和类型，并把结果记录在语法树节点上
+func checkPredicate(n predNode, t reflect.Type) (predType, error) {
+	switch n := n.(type) {
+	case *predField:
+		f, ok := t.FieldByName(n.name)
+		if !ok || !f.IsExported() {
+			return predInvalid, predErrorf(n.p, "unknown field %s of %s (fields: %s)", n.name, t.Name(), strings.Join(exportedFields(t), ", "))
+		}
+		n.index = f.Index
+		switch f.Type.Kind() {
+		case reflect.String:
+			n.typ = predString
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			n.typ = predInt
+		case reflect.Float32, reflect.Float64:
+			n.typ = predFloat
+		case reflect.Bool:
+			n.typ = predBool
+		default:
+			return predInvalid, predErrorf(n.p, "field %s has unsupported type %s", n.name, f.Type)
+		}
+		return n.typ, nil
+
+	case *predLiteral:
+		switch n.value.(type) {
+		case string:
+			n.typ = predString
+		case int64:
+			n.typ = predInt
+		case float64:
+			n.typ = predFloat
+		case bool:
+			n.typ = predBool
+		}
+		return n.typ, nil
+
+	case *predUnary:
+		xt, err := checkPredicate(n.x, t)
+		if err != nil {
+			return predInvalid, err
+		}
+		if xt != predBool {
+			return predInvalid, predErrorf(n.p, "operator ! requires bool, found %s", xt)
+		}
+		return predBool, nil
+
+	case *predBinary:
+		xt, err := checkPredicate(n.x, t)
+		if err != nil {
+			return predInvalid, err
+		}
+		yt, err := checkPredicate(n.y, t)
+		if err != nil {
+			return predInvalid, err
+		}
+		if n.op == tokAnd || n.op == tokOr {
+			if xt != predBool {
+				return predInvalid, predErrorf(n.x.pos(), "operator %s requires bool operands, found %s", n.op, xt)
+			}
+			if yt != predBool {
+				return predInvalid, predErrorf(n.y.pos(), "operator %s requires bool operands, found %s", n.op, yt)
+			}
+			return predBool, nil
+		}
+		operand, ok := unifyPredTypes(xt, yt)
+		if !ok {
+			return predInvalid, predErrorf(n.p, "mismatched types %s and %s for %s", xt, yt, n.op)
+		}
+		if operand == predBool && n.op != tokEq && n.op != tokNe {
+			return predInvalid, predErrorf(n.p, "operator %s is not defined on bool", n.op)
+		}
+		n.operand = operand
+		return predBool, nil
+
+	case *predIn:
+		xt, err := checkPredicate(n.x, t)
+		if err != nil {
+			return predInvalid, err
+		}
+		operand := xt
+		for _, lit := range n.list {
+			lt, _ := checkPredicate(lit, t)
+			u, ok := unifyPredTypes(operand, lt)
+			if !ok {
+				return predInvalid, predErrorf(lit.p, "mismatched types %s and %s in list", xt, lt)
+			}
+			operand = u
+		}
+		n.operand = operand
+		return predBool, nil
+	}
+	panic(fmt.Sprintf("predicate: unexpected node %T", n))
+}
+
+// unifyPredTypes 比较时两边的类型必须相同，整数和浮点数统一为浮点数
+func unifyPredTypes(a, b predType) (predType, bool) {
+	if a == b {
+		return a, true
+	}
+	if a.numeric() && b.numeric() {
+		return predFloat, true
+	}
+	return predInvalid, false
+}
+
+func exportedFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// 编译：每个节点编译为一个闭包，返回值按类型分别是
+// func(reflect.Value) bool / string / int64 / float64，参数是结构体的值
+
+func compilePredicate(n predNode) any {
+	switch n := n.(type) {
+	case *predField:
+		index := n.index
+		switch n.typ {
+		case predString:
+			return func(v reflect.Value) string { return v.FieldByIndex(index).String() }
+		case predInt:
+			return func(v reflect.Value) int64 {
+				f := v.FieldByIndex(index)
+				if f.CanInt() {
+					return f.Int()
+				}
+				return int64(f.Uint())
+			}
+		case predFloat:
+			return func(v reflect.Value) float64 { return v.FieldByIndex(index).Float() }
+		default:
+			return func(v reflect.Value) bool { return v.FieldByIndex(index).Bool() }
+		}
+
+	case *predLiteral:
+		switch x := n.value.(type) {
+		case string:
+			return func(reflect.Value) string { return x }
+		case int64:
+			return func(reflect.Value) int64 { return x }
+		case float64:
+			return func(reflect.Value) float64 { return x }
+		case bool:
+			return func(reflect.Value) bool { return x }
+		}
+
+	case *predUnary:
+		x := compilePredicate(n.x).(func(reflect.Value) bool)
+		return func(v reflect.Value) bool { return !x(v) }
+
+	case *predBinary:
+		switch n.op {
+		case tokAnd:
+			x := compilePredicate(n.x).(func(reflect.Value) bool)
+			y := compilePredicate(n.y).(func(reflect.Value) bool)
+			return func(v reflect.Value) bool { return x(v) && y(v) }
+		case tokOr:
+			x := compilePredicate(n.x).(func(reflect.Value) bool)
+			y := compilePredicate(n.y).(func(reflect.Value) bool)
+			return func(v reflect.Value) bool { return x(v) || y(v) }
+		}
+		switch n.operand {
+		case predString:
+			return compileCompare(n.op, compilePredicate(n.x).(func(reflect.Value) string), compilePredicate(n.y).(func(reflect.Value) string))
+		case predInt:
+			return compileCompare(n.op, compilePredicate(n.x).(func(reflect.Value) int64), compilePredicate(n.y).(func(reflect.Value) int64))
+		case predFloat:
+			return compileCompare(n.op, compileFloat(n.x), compileFloat(n.y))
+		default:
+			x := compilePredicate(n.x).(func(reflect.Value) bool)
+			y := compilePredicate(n.y).(func(reflect.Value) bool)
+			if n.op == tokEq {
+				return func(v reflect.Value) bool { return x(v) == y(v) }
+			}
+			return func(v reflect.Value) bool { return x(v) != y(v) }
+		}
+
+	case *predIn:
+		var match func(reflect.Value) bool
+		switch n.operand {
+		case predString:
+			match = compileIn(compilePredicate(n.x).(func(reflect.Value) string), n.list, func(l *predLiteral) string { return l.value.(string) })
+		case predInt:
+			match = compileIn(compilePredicate(n.x).(func(reflect.Value) int64), n.list, func(l *predLiteral) int64 { return l.value.(int64) })
+		case predFloat:
+			match = compileIn(compileFloat(n.x), n.list, literalFloat)
+		default:
+			match = compileIn(compilePredicate(n.x).(func(reflect.Value) bool), n.list, func(l *predLiteral) bool { return l.value.(bool) })
+		}
+		if n.negate {
+			return func(v reflect.Value) bool { return !match(v) }
+		}
+		return match
+	}
+	panic(fmt.Sprintf("predicate: unexpected node %T", n))
+}
+
+// compileFloat 把整数或浮点数表达式编译为浮点数
+func compileFloat(n predNode) func(reflect.Value) float64 {
+	switch f := compilePredicate(n).(type) {
+	case func(reflect.Value) float64:
+		return f
+	case func(reflect.Value) int64:
+		return func(v reflect.Value) float64 { return float64(f(v)) }
+	}
+	panic("predicate: operand is not numeric")
+}
+
+func literalFloat(l *predLiteral) float64 {
+	if i, ok := l.value.(int64); ok {
+		return float64(i)
+	}
+	return l.value.(float64)
+}
+
+func compileCompare[V string | int64 | float64](op predTokenKind, x, y func(reflect.Value) V) func(reflect.Value) bool {
+	switch op {
+	case tokEq:
+		return func(v reflect.Value) bool { return x(v) == y(v) }
+	case tokNe:
+		return func(v reflect.Value) bool { return x(v) != y(v) }
+	case tokLt:
+		return func(v reflect.Value) bool { return x(v) < y(v) }
+	case tokLe:
+		return func(v reflect.Value) bool { return x(v) <= y(v) }
+	case tokGt:
+		return func(v reflect.Value) bool { return x(v) > y(v) }
+	default:
+		return func(v reflect.Value) bool { return x(v) >= y(v) }
+	}
+}
+
+func compileIn[V comparable](x func(reflect.Value) V, list []*predLiteral, value func(*predLiteral) V) func(reflect.Value) bool {
+	set := make(map[V]struct{}, len(list))
+	for _, l := range list {
+		set[value(l)] = struct{}{}
+	}
+	return func(v reflect.Value) bool {
+		_, ok := set[x(v)]
+		return ok
+	}
+}
@@ -0,0 +1,98 @@
+package demo11_interface
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileCarPredicate(t *testing.T) {
+	cars := testCars()
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`Manufacturer == "BYD" && BuildYear > 2020 || Module in ("1","2")`, "BYD/3 BMW/1 BYD/2 /1 BYD/1"},
+		{`Manufacturer == "BYD" && (BuildYear > 2021 || Module == "3")`, "BYD/3 BYD/2 BYD/1"},
+		{`!(Manufacturer == "BMW") && Module not in ("1")`, "BYD/3 BYD/2"},
+		{`BuildYear >= 2021.5 && Manufacturer < "BYD"`, "BMW/1 BMW/"},
+		{`Module != "" && BuildYear <= 2019`, "/1"},
+		{`true`, "BYD/3 BMW/1 BYD/2 BMW/ /1 BYD/1"},
+		{`Manufacturer == "B\u0059D" && BuildYear in (2021, 2024.0)`, "BYD/3 BYD/2 BYD/1"},
+	}
+	for _, tt := range tests {
+		pred, err := CompileCarPredicate(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := modules(cars.FindAll(pred)); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.expr, got, tt.want)
+		}
+	}
+
+	got, err := cars.FindAllWhere(`Manufacturer == "BYD" && BuildYear > 2020`)
+	if err != nil || modules(got) != "BYD/3 BYD/2 BYD/1" {
+		t.Errorf("FindAllWhere = %s, %v", modules(got), err)
+	}
+}
+
+func TestCompileCarPredicateErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		column int
+		msg    string
+	}{
+		{`Colour == "red"`, 1, "unknown field Colour of Car (fields: Module, Manufacturer, BuildYear)"},
+		{`BuildYear > "2020"`, 11, "mismatched types int and string"},
+		{`Manufacturer == "BYD" && BuildYear`, 26, "requires bool operands, found int"},
+		{`Module in ("1", 2)`, 17, "mismatched types string and int in list"},
+		{`Module == "1`, 11, "unterminated string"},
+		{`BuildYear > 2020 &&`, 20, "unexpected end of expression"},
+		{`(BuildYear > 2020`, 18, `expected ")"`},
+		{`BuildYear > 2020 Module`, 18, "unexpected identifier Module"},
+		{`BuildYear # 1`, 11, "unexpected character '#'"},
+		{`Module`, 1, "expression has type string, want bool"},
+		{`!BuildYear`, 1, "operator ! requires bool"},
+		{`true < false`, 6, "not defined on bool"},
+		{`Module not ("1")`, 12, `expected "in"`},
+		{`Module in (Module)`, 12, "expected literal in list"},
+		{`厂商 == 1`, 1, "unknown field 厂商"},
+		{`"车" == 1`, 5, "mismatched types string and int"},
+	}
+	for _, tt := range tests {
+		_, err := CompileCarPredicate(tt.expr)
+		var pe *PredicateError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: want PredicateError, got %v", tt.expr, err)
+			continue
+		}
+		if pe.Column != tt.column || !strings.Contains(pe.Msg, tt.msg) {
+			t.Errorf("%s: got %v, want column %d: %s", tt.expr, err, tt.column, tt.msg)
+		}
+	}
+}
+
+type sensor struct {
+	Name   string
+	Value  float32
+	Count  uint16
+	Active bool
+	secret string
+}
+
+func TestCompilePredicateGeneric(t *testing.T) {
+	pred, err := CompilePredicate[sensor](`Active == true && Value > 1 && Count < 10`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pred(&sensor{Active: true, Value: 1.5, Count: 3}) || pred(&sensor{Active: true, Value: 0.5}) || pred(nil) {
+		t.Error("unexpected predicate result")
+	}
+	if _, err := CompilePredicate[sensor](`secret == ""`); err == nil {
+		t.Error("want error for unexported field")
+	}
+	if _, err := CompilePredicate[int](`true`); err == nil {
+		t.Error("want error for non-struct type")
+	}
+}
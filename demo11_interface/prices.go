@@ -0,0 +1,133 @@
+package demo11_interface
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 历史行情文件
+/*
+1:支持两种 CSV 格式，由表头区分（列名不区分大小写，列的顺序任意）：
+	a:逐笔成交 time,ticker,price
+	b:K 线 time,ticker,open,high,low,close，可以有 volume 等其他列，K 线按收盘价在 time 时刻产生一个价格
+2:time 可以是 RFC 3339、"2006-01-02 15:04:05"、"2006-01-02"（UTC）或者 Unix 秒数。
+3:文件内的记录不要求有序，MergePrices 按时间稳定排序，同一时刻的价格保持文件中的先后顺序。
+*/
+
+// PriceTick 某个时刻某只股票的价格
+type PriceTick struct {
+	Time   time.Time
+	Ticker string
+	Price  Decimal
+}
+
+// LoadPrices 读取行情文件
+func LoadPrices(path string) ([]PriceTick, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ticks, err := ReadPrices(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ticks, nil
+}
+
+// ReadPrices 读取逐笔成交或者 K 线格式的 CSV
+func ReadPrices(r io.Reader) ([]PriceTick, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("prices: empty file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("prices: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	need := []string{"time", "ticker", "price"}
+	if _, ok := cols["price"]; !ok {
+		need = []string{"time", "ticker", "open", "high", "low", "close"}
+	}
+	for _, name := range need {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("prices: line 1: missing column %q", name)
+		}
+	}
+
+	var ticks []PriceTick
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return ticks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("prices: %w", err)
+		}
+		field := func(name string) string {
+			if i := cols[name]; i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		tick := PriceTick{Ticker: field("ticker")}
+		if tick.Ticker == "" {
+			return nil, fmt.Errorf("prices: line %d: empty ticker", line)
+		}
+		if tick.Time, err = parseTickTime(field("time")); err != nil {
+			return nil, fmt.Errorf("prices: line %d: %w", line, err)
+		}
+		values := make([]Decimal, len(need)-2)
+		for i, name := range need[2:] {
+			v, err := ParseDecimal(field(name))
+			if err != nil || v.Sign() <= 0 {
+				return nil, fmt.Errorf("prices: line %d: invalid %s %q", line, name, field(name))
+			}
+			values[i] = v
+		}
+		if len(values) == 4 {
+			open, high, low, closing := values[0], values[1], values[2], values[3]
+			if high.Cmp(open) < 0 || high.Cmp(closing) < 0 || high.Cmp(low) < 0 || low.Cmp(open) > 0 || low.Cmp(closing) > 0 {
+				return nil, fmt.Errorf("prices: line %d: inconsistent bar o=%v h=%v l=%v c=%v", line, open, high, low, closing)
+			}
+		}
+		tick.Price = values[len(values)-1]
+		ticks = append(ticks, tick)
+	}
+}
+
+var tickTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTickTime(s string) (time.Time, error) {
+	for _, layout := range tickTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// MergePrices 把多个行情按时间稳定地合并为一个序列
+func MergePrices(feeds ...[]PriceTick) []PriceTick {
+	merged := slices.Concat(feeds...)
+	slices.SortStableFunc(merged, func(a, b PriceTick) int {
+		return a.Time.Compare(b.Time)
+	})
+	return merged
+}
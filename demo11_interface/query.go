@@ -0,0 +1,265 @@
+package demo11_interface
+
+import "iter"
+
+// 惰性查询
+/*
+1:Query[T] 包装一个 iter.Seq[T]，Where、Map、Skip、Take 等操作只是把迭代器一层层包起来，不会立即执行。
+2:只有在 ToSlice、First、Any、Reduce 等终结操作，或者用 for range 遍历 Seq() 时才真正开始迭代。
+3:迭代是短路的：Take(10) 取够 10 个元素后就停止拉取上游，First、Any、All 得到结果后也会立即停止。
+4:OrderBy、GroupBy 需要看到全部元素才能输出，每次迭代都会重新拉取并缓存上游的所有元素，结果不会在多次迭代之间复用。
+5:Go 的方法不能有自己的类型参数，所以改变元素类型的 Select、GroupBy、Join、Reduce 等是普通函数。
+*/
+
+// Query 惰性求值的查询
+type Query[T any] struct {
+	seq iter.Seq[T]
+}
+
+// From 从切片创建查询
+func From[T any](items []T) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	}}
+}
+
+// FromSeq 从迭代器创建查询
+func FromSeq[T any](seq iter.Seq[T]) Query[T] {
+	return Query[T]{seq: seq}
+}
+
+// Seq 返回底层迭代器，可以直接用于 for range
+func (q Query[T]) Seq() iter.Seq[T] {
+	if q.seq == nil {
+		return func(func(T) bool) {}
+	}
+	return q.seq
+}
+
+// Where 过滤出满足 pred 的元素
+func (q Query[T]) Where(pred func(T) bool) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		for item := range q.Seq() {
+			if pred(item) && !yield(item) {
+				return
+			}
+		}
+	}}
+}
+
+// Map 对每个元素做变换，元素类型不变；需要改变类型时使用 Select
+func (q Query[T]) Map(f func(T) T) Query[T] {
+	return Select(q, f)
+}
+
+// Select 把每个元素变换为另一种类型
+func Select[T, U any](q Query[T], f func(T) U) Query[U] {
+	return Query[U]{seq: func(yield func(U) bool) {
+		for item := range q.Seq() {
+			if !yield(f(item)) {
+				return
+			}
+		}
+	}}
+}
+
+// Skip 跳过前 n 个元素
+func (q Query[T]) Skip(n int) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		i := 0
+		for item := range q.Seq() {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(item) {
+				return
+			}
+		}
+	}}
+}
+
+// Take 只取前 n 个元素，取够后不再拉取上游
+func (q Query[T]) Take(n int) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for item := range q.Seq() {
+			if !yield(item) {
+				return
+			}
+			i++
+			if i >= n {
+				return
+			}
+		}
+	}}
+}
+
+// OrderBy 按 compare 稳定排序，compare(a, b) < 0 表示 a 排在前面
+func (q Query[T]) OrderBy(compare func(a, b T) int) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		items := q.ToSlice()
+		Stable(funcSorter[T]{items: items, compare: compare})
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	}}
+}
+
+// funcSorter 用比较函数实现 Sorter
+type funcSorter[T any] struct {
+	items   []T
+	compare func(a, b T) int
+}
+
+func (s funcSorter[T]) Len() int           { return len(s.items) }
+func (s funcSorter[T]) Less(i, j int) bool { return s.compare(s.items[i], s.items[j]) < 0 }
+func (s funcSorter[T]) Swap(i, j int)      { s.items[i], s.items[j] = s.items[j], s.items[i] }
+
+// Distinct 去掉重复元素，保留第一次出现的
+func Distinct[T comparable](q Query[T]) Query[T] {
+	return DistinctBy(q, func(item T) T { return item })
+}
+
+// DistinctBy 按 key 去重，保留第一次出现的
+func DistinctBy[T any, K comparable](q Query[T], key func(T) K) Query[T] {
+	return Query[T]{seq: func(yield func(T) bool) {
+		seen := make(map[K]struct{})
+		for item := range q.Seq() {
+			k := key(item)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(item) {
+				return
+			}
+		}
+	}}
+}
+
+// Group GroupBy 的一个分组
+type Group[K comparable, T any] struct {
+	Key   K
+	Items []T
+}
+
+// GroupBy 按 key 分组，分组按 key 第一次出现的顺序输出
+func GroupBy[T any, K comparable](q Query[T], key func(T) K) Query[Group[K, T]] {
+	return Query[Group[K, T]]{seq: func(yield func(Group[K, T]) bool) {
+		index := make(map[K]int)
+		var groups []Group[K, T]
+		for item := range q.Seq() {
+			k := key(item)
+			i, ok := index[k]
+			if !ok {
+				i = len(groups)
+				index[k] = i
+				groups = append(groups, Group[K, T]{Key: k})
+			}
+			groups[i].Items = append(groups[i].Items, item)
+		}
+		for _, g := range groups {
+			if !yield(g) {
+				return
+			}
+		}
+	}}
+}
+
+// Pair Join 的结果
+type Pair[L, R any] struct {
+	Left  L
+	Right R
+}
+
+// Join 内连接：对 outer 中的每个元素，按 inner 中的顺序输出所有 key 相等的元素。
+// inner 在第一次迭代时被完整读取并建立哈希索引，outer 仍然是惰性的。
+func Join[L, R any, K comparable](outer Query[L], inner Query[R], outerKey func(L) K, innerKey func(R) K) Query[Pair[L, R]] {
+	return Query[Pair[L, R]]{seq: func(yield func(Pair[L, R]) bool) {
+		index := make(map[K][]R)
+		for r := range inner.Seq() {
+			k := innerKey(r)
+			index[k] = append(index[k], r)
+		}
+		for l := range outer.Seq() {
+			for _, r := range index[outerKey(l)] {
+				if !yield(Pair[L, R]{Left: l, Right: r}) {
+					return
+				}
+			}
+		}
+	}}
+}
+
+// Reduce 从 init 开始依次用 f 累积每个元素
+func Reduce[T, A any](q Query[T], init A, f func(acc A, item T) A) A {
+	acc := init
+	for item := range q.Seq() {
+		acc = f(acc, item)
+	}
+	return acc
+}
+
+// ForEach 对每个元素执行 f
+func (q Query[T]) ForEach(f func(T)) {
+	for item := range q.Seq() {
+		f(item)
+	}
+}
+
+// ToSlice 执行查询，返回全部结果；没有结果时返回空切片而不是 nil
+func (q Query[T]) ToSlice() []T {
+	items := make([]T, 0)
+	for item := range q.Seq() {
+		items = append(items, item)
+	}
+	return items
+}
+
+// First 返回第一个元素
+func (q Query[T]) First() (T, bool) {
+	for item := range q.Seq() {
+		return item, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Any 是否存在满足 pred 的元素
+func (q Query[T]) Any(pred func(T) bool) bool {
+	for item := range q.Seq() {
+		if pred(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// All 是否所有元素都满足 pred，没有元素时返回 true
+func (q Query[T]) All(pred func(T) bool) bool {
+	for item := range q.Seq() {
+		if !pred(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Count 元素个数
+func (q Query[T]) Count() int {
+	n := 0
+	for range q.Seq() {
+		n++
+	}
+	return n
+}
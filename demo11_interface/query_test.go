@@ -0,0 +1,140 @@
+package demo11_interface
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// countingCars 生成 n 辆车，并记录实际被拉取了多少辆
+func countingCars(n int, pulled *int) Query[*Car] {
+	return FromSeq(func(yield func(*Car) bool) {
+		for i := 0; i < n; i++ {
+			*pulled++
+			c := &Car{Module: fmt.Sprint(i % 7), Manufacturer: []string{"BMW", "BYD", "NIO"}[i%3], BuildYear: 2000 + i%25}
+			if !yield(c) {
+				return
+			}
+		}
+	})
+}
+
+func TestQueryShortCircuit(t *testing.T) {
+	pulled := 0
+	got := countingCars(1_000_000, &pulled).
+		Where(func(c *Car) bool { return c.Manufacturer == "BYD" }).
+		Take(10).
+		ToSlice()
+	if len(got) != 10 {
+		t.Fatalf("got %d cars", len(got))
+	}
+	if pulled > 30 {
+		t.Errorf("pulled %d cars from upstream, want at most 30", pulled)
+	}
+
+	pulled = 0
+	if !countingCars(1_000_000, &pulled).Any(func(c *Car) bool { return c.BuildYear == 2003 }) || pulled != 4 {
+		t.Errorf("Any pulled %d cars", pulled)
+	}
+	pulled = 0
+	if countingCars(1_000_000, &pulled).All(func(c *Car) bool { return c.Manufacturer != "NIO" }) || pulled != 3 {
+		t.Errorf("All pulled %d cars", pulled)
+	}
+	pulled = 0
+	if c, ok := countingCars(1_000_000, &pulled).Skip(5).First(); !ok || c.Module != "5" || pulled != 6 {
+		t.Errorf("First after Skip pulled %d cars", pulled)
+	}
+}
+
+func TestQueryOperators(t *testing.T) {
+	nums := From([]int{5, 3, 8, 3, 1, 8, 9, 2})
+
+	if got := Distinct(nums).ToSlice(); !slices.Equal(got, []int{5, 3, 8, 1, 9, 2}) {
+		t.Errorf("Distinct = %v", got)
+	}
+	if got := nums.OrderBy(cmp.Compare[int]).Skip(2).Take(3).ToSlice(); !slices.Equal(got, []int{3, 3, 5}) {
+		t.Errorf("OrderBy/Skip/Take = %v", got)
+	}
+	if got := nums.Map(func(i int) int { return i * 2 }).Where(func(i int) bool { return i > 10 }).ToSlice(); !slices.Equal(got, []int{16, 16, 18}) {
+		t.Errorf("Map/Where = %v", got)
+	}
+	if got := Select(nums, func(i int) string { return fmt.Sprint(i) }).Take(2).ToSlice(); !slices.Equal(got, []string{"5", "3"}) {
+		t.Errorf("Select = %v", got)
+	}
+	if sum := Reduce(nums, 0, func(acc, i int) int { return acc + i }); sum != 39 {
+		t.Errorf("Reduce = %d", sum)
+	}
+	if n := nums.Count(); n != 8 {
+		t.Errorf("Count = %d", n)
+	}
+	if got := nums.Take(0).ToSlice(); got == nil || len(got) != 0 {
+		t.Errorf("Take(0) = %#v, want empty slice", got)
+	}
+	if _, ok := (Query[int]{}).First(); ok {
+		t.Error("First on empty query")
+	}
+
+	groups := GroupBy(nums, func(i int) bool { return i%2 == 0 }).ToSlice()
+	if len(groups) != 2 || groups[0].Key || !slices.Equal(groups[0].Items, []int{5, 3, 3, 1, 9}) || !slices.Equal(groups[1].Items, []int{8, 8, 2}) {
+		t.Errorf("GroupBy = %v", groups)
+	}
+}
+
+func TestQueryJoin(t *testing.T) {
+	type maker struct {
+		Name    string
+		Country string
+	}
+	makers := From([]maker{{"BYD", "CN"}, {"BMW", "DE"}, {"BYD", "CN-2"}})
+	cars := testCars().FindAll(func(c *Car) bool { return c != nil })
+
+	pairs := Join(cars.Query(), makers, func(c *Car) string { return c.Manufacturer }, func(m maker) string { return m.Name }).ToSlice()
+	var got []string
+	for _, p := range pairs {
+		got = append(got, p.Left.Module+"@"+p.Right.Country)
+	}
+	want := []string{"3@CN", "3@CN-2", "1@DE", "2@CN", "2@CN-2", "@DE", "1@CN", "1@CN-2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Join = %v, want %v", got, want)
+	}
+}
+
+func TestQueryRange(t *testing.T) {
+	var seq iter.Seq[*Car] = testCars().Query().Where(func(c *Car) bool { return c != nil }).Seq()
+	n := 0
+	for c := range seq {
+		if c.Module == "2" {
+			break
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("range stopped after %d cars", n)
+	}
+}
+
+func TestCarsAdapter(t *testing.T) {
+	cars := testCars()
+	none := cars.FindAll(func(c *Car) bool { return false })
+	if none == nil || len(none) != 0 {
+		t.Errorf("FindAll with no match = %#v, want empty Cars", none)
+	}
+	count := 0
+	cars.Process(func(c *Car) { count++ })
+	if count != len(cars) {
+		t.Errorf("Process visited %d cars", count)
+	}
+}
+
+func BenchmarkQueryTake(b *testing.B) {
+	cars := make(Cars, 1_000_000)
+	for i := range cars {
+		cars[i] = &Car{BuildYear: 2000 + i%25}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cars.Query().Where(func(c *Car) bool { return c.BuildYear > 2020 }).Take(10).ToSlice()
+	}
+}
@@ -0,0 +1,168 @@
+package demo11_interface
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+)
+
+// 扫描线光栅化
+/*
+1:每个像素行分成 rasterSubSamples 条子扫描线，求出每条子扫描线与轮廓的交点，按非零环绕规则得到覆盖的区间。
+2:区间在水平方向按实际长度累加到像素上（精确的水平抗锯齿），垂直方向由子扫描线平均（超采样抗锯齿），
+  得到的覆盖率作为 alpha 蒙版，用 image/draw 以 Over 方式合成颜色。
+3:描边由每条边两侧各扩展半个线宽、两端各延长半个线宽的长方形组成，所有长方形都是逆时针方向，
+  按非零环绕规则一起光栅化就是它们的并集，重叠的地方不会重复着色。
+*/
+
+const rasterSubSamples = 4
+
+// Image 把图形光栅化为 RGBA 位图
+func (r Renderer) Image(items []Styled) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	if r.Background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(r.Background), image.Point{}, draw.Src)
+	}
+	t := r.transform(items)
+	for _, it := range items {
+		outline := t.outline(it.Shaper)
+		if it.Fill != nil {
+			fillPath(img, [][]Point{outline}, it.Fill)
+		}
+		if it.Stroke != nil {
+			fillPath(img, strokeContours(outline, it.strokeWidth()), it.Stroke)
+		}
+	}
+	return img
+}
+
+// PNG 把图形光栅化后编码为 PNG
+func (r Renderer) PNG(w io.Writer, items []Styled) error {
+	return png.Encode(w, r.Image(items))
+}
+
+// fillPath 用非零环绕规则填充若干条闭合轮廓
+func fillPath(dst *image.RGBA, contours [][]Point, c color.Color) {
+	b := dst.Bounds()
+	box := Box{}
+	for i, contour := range contours {
+		if i == 0 {
+			box = BoxOf(contour...)
+		} else {
+			box = box.Union(BoxOf(contour...))
+		}
+	}
+	y0 := max(b.Min.Y, int(math.Floor(box.Min.Y)))
+	y1 := min(b.Max.Y, int(math.Ceil(box.Max.Y)))
+	x0 := max(b.Min.X, int(math.Floor(box.Min.X)))
+	x1 := min(b.Max.X, int(math.Ceil(box.Max.X)))
+	if y0 >= y1 || x0 >= x1 {
+		return
+	}
+
+	mask := image.NewAlpha(image.Rect(x0, y0, x1, y1))
+	cover := make([]float64, x1-x0)
+	var crossings []crossing
+	for y := y0; y < y1; y++ {
+		clear(cover)
+		for s := 0; s < rasterSubSamples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/rasterSubSamples
+			crossings = scanCrossings(crossings[:0], contours, sy)
+			accumulateSpans(cover, crossings, float64(x0), 1.0/rasterSubSamples)
+		}
+		for i, v := range cover {
+			mask.SetAlpha(x0+i, y, color.Alpha{uint8(math.Round(math.Min(v, 1) * 0xff))})
+		}
+	}
+	draw.DrawMask(dst, mask.Rect, image.NewUniform(c), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// crossing 扫描线与一条边的交点，dir 是边的方向（向下为 1，向上为 -1）
+type crossing struct {
+	x   float64
+	dir int
+}
+
+func scanCrossings(dst []crossing, contours [][]Point, y float64) []crossing {
+	for _, contour := range contours {
+		for i, a := range contour {
+			b := contour[(i+1)%len(contour)]
+			// 半开区间 [min, max) 保证经过顶点的扫描线只计一次
+			switch {
+			case a.Y <= y && y < b.Y:
+				dst = append(dst, crossing{a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X), 1})
+			case b.Y <= y && y < a.Y:
+				dst = append(dst, crossing{a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X), -1})
+			}
+		}
+	}
+	slices.SortFunc(dst, func(p, q crossing) int {
+		switch {
+		case p.x < q.x:
+			return -1
+		case p.x > q.x:
+			return 1
+		}
+		return 0
+	})
+	return dst
+}
+
+// accumulateSpans 把环绕数不为零的区间按长度累加到 cover，cover[0] 对应像素 x0
+func accumulateSpans(cover []float64, crossings []crossing, x0, weight float64) {
+	winding := 0
+	var start float64
+	for _, c := range crossings {
+		if winding == 0 {
+			start = c.x
+		}
+		winding += c.dir
+		if winding == 0 {
+			addSpan(cover, start-x0, c.x-x0, weight)
+		}
+	}
+}
+
+// addSpan 把区间 [a, b) 的覆盖长度累加到像素上
+func addSpan(cover []float64, a, b, weight float64) {
+	n := float64(len(cover))
+	a, b = math.Max(a, 0), math.Min(b, n)
+	if a >= b {
+		return
+	}
+	ia, ib := int(a), int(b)
+	if ia == ib {
+		cover[ia] += (b - a) * weight
+		return
+	}
+	cover[ia] += (float64(ia+1) - a) * weight
+	for i := ia + 1; i < ib; i++ {
+		cover[i] += weight
+	}
+	if ib < len(cover) {
+		cover[ib] += (b - float64(ib)) * weight
+	}
+}
+
+// strokeContours 闭合折线的描边，每条边对应一个逆时针的长方形
+func strokeContours(points []Point, width float64) [][]Point {
+	half := width / 2
+	contours := make([][]Point, 0, len(points))
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		d := b.Sub(a)
+		l := d.Len()
+		if l == 0 {
+			continue
+		}
+		u := d.Mul(half / l)
+		n := Point{-u.Y, u.X}
+		a, b = a.Sub(u), b.Add(u)
+		contours = append(contours, []Point{a.Sub(n), b.Sub(n), b.Add(n), a.Add(n)})
+	}
+	return contours
+}
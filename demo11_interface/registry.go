@@ -0,0 +1,271 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// 多态 JSON
+/*
+1:JSON 解码到接口类型时不知道应该创建哪个具体类型，TypeRegistry 记录接口 I 的每个实现类型对应的名字，
+  编码时在对象中加入一个鉴别字段，例如 {"type":"square","side":4.5}，解码时根据鉴别字段创建对应的类型。
+2:注册的类型可以是值类型也可以是指针类型，解码得到的值与注册时的类型完全一致，例如注册 &Square{} 解码得到 *Square。
+  编码时 T 和 *T 使用同一个名字，例如注册 Rectangle{} 后 &Rectangle{} 也能编码，解码仍然得到 Rectangle。
+3:具体类型必须编码为 JSON 对象，且不能有与鉴别字段同名的字段。
+4:解码后的值如果有 Validate() error 方法会被调用，校验失败时返回错误。
+5:null 与 nil 接口值互相转换。
+*/
+
+// UnregisteredTypeError 编码了没有注册的类型
+type UnregisteredTypeError struct {
+	Interface string
+	Type      reflect.Type
+}
+
+func (e *UnregisteredTypeError) Error() string {
+	return fmt.Sprintf("json: type %s is not registered as %s", e.Type, e.Interface)
+}
+
+// UnknownTypeError 解码时遇到没有注册的名字，Name 为空表示缺少鉴别字段
+type UnknownTypeError struct {
+	Interface string
+	Field     string
+	Name      string
+	// Known 已注册的名字
+	Known []string
+}
+
+func (e *UnknownTypeError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("json: missing %q field for %s", e.Field, e.Interface)
+	}
+	return fmt.Sprintf("json: unknown %s type %q (known: %s)", e.Interface, e.Name, strings.Join(e.Known, ", "))
+}
+
+// TypeRegistry 接口 I 的实现类型与名字的对应关系，可以被多个 goroutine 同时使用
+type TypeRegistry[I any] struct {
+	mu    sync.RWMutex
+	field string
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+// NewTypeRegistry 创建注册表，field 是鉴别字段的名字
+func NewTypeRegistry[I any](field string) *TypeRegistry[I] {
+	if reflect.TypeOf((*I)(nil)).Elem().Kind() != reflect.Interface {
+		panic("json: TypeRegistry requires an interface type")
+	}
+	return &TypeRegistry[I]{
+		field: field,
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Register 把 sample 的动态类型注册为 name，名字或类型重复、字段与鉴别字段冲突时 panic
+func (r *TypeRegistry[I]) Register(name string, sample I) {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		panic("json: Register of nil " + r.interfaceName())
+	}
+	if conflictsWithField(t, r.field) {
+		panic(fmt.Sprintf("json: %s has a field named %q", t, r.field))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.types[name]; ok {
+		panic(fmt.Sprintf("json: name %q registered for both %s and %s", name, old, t))
+	}
+	if old, ok := r.names[t]; ok {
+		panic(fmt.Sprintf("json: type %s registered as both %q and %q", t, old, name))
+	}
+	r.names[t] = name
+	r.types[name] = t
+}
+
+// Names 已注册的名字，按字母排序
+func (r *TypeRegistry[I]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Marshal 编码单个值，鉴别字段放在最前面
+func (r *TypeRegistry[I]) Marshal(v I) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal 解码单个值
+func (r *TypeRegistry[I]) Unmarshal(data []byte) (I, error) {
+	var zero I
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return zero, nil
+	}
+	var head map[string]json.RawMessage
+	if err := json.Unmarshal(data, &head); err != nil {
+		return zero, err
+	}
+	raw, ok := head[r.field]
+	if !ok {
+		return zero, &UnknownTypeError{Interface: r.interfaceName(), Field: r.field}
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return zero, fmt.Errorf("json: %q field must be a string: %w", r.field, err)
+	}
+
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return zero, &UnknownTypeError{Interface: r.interfaceName(), Field: r.field, Name: name, Known: r.Names()}
+	}
+
+	var v reflect.Value
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+		if err := json.Unmarshal(data, v.Interface()); err != nil {
+			return zero, fmt.Errorf("json: decode %s: %w", name, err)
+		}
+	} else {
+		p := reflect.New(t)
+		if err := json.Unmarshal(data, p.Interface()); err != nil {
+			return zero, fmt.Errorf("json: decode %s: %w", name, err)
+		}
+		v = p.Elem()
+	}
+	if val, ok := v.Interface().(interface{ Validate() error }); ok {
+		if err := val.Validate(); err != nil {
+			return zero, fmt.Errorf("json: invalid %s: %w", name, err)
+		}
+	}
+	return v.Interface().(I), nil
+}
+
+// MarshalSlice 编码为 JSON 数组
+func (r *TypeRegistry[I]) MarshalSlice(vs []I) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, v := range vs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := r.encode(&buf, v); err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSlice 解码 JSON 数组，出错时返回的 *ItemError 带有元素下标
+func (r *TypeRegistry[I]) UnmarshalSlice(data []byte) ([]I, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	if raws == nil {
+		return nil, nil
+	}
+	vs := make([]I, len(raws))
+	for i, raw := range raws {
+		v, err := r.Unmarshal(raw)
+		if err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+func (r *TypeRegistry[I]) encode(buf *bytes.Buffer, v I) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	name, ok := r.nameOf(t)
+	if !ok {
+		return &UnregisteredTypeError{Interface: r.interfaceName(), Type: t}
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(body) < 2 || body[0] != '{' {
+		return fmt.Errorf("json: %s does not encode as an object", t)
+	}
+	key, _ := json.Marshal(r.field)
+	value, _ := json.Marshal(name)
+	buf.WriteByte('{')
+	buf.Write(key)
+	buf.WriteByte(':')
+	buf.Write(value)
+	if len(body) > 2 {
+		buf.WriteByte(',')
+	}
+	buf.Write(body[1:])
+	return nil
+}
+
+// nameOf 查找 t 注册的名字，t 没有注册时再查找 *t 或 t 指向的类型
+func (r *TypeRegistry[I]) nameOf(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if name, ok := r.names[t]; ok {
+		return name, true
+	}
+	if t.Kind() == reflect.Pointer {
+		name, ok := r.names[t.Elem()]
+		return name, ok
+	}
+	name, ok := r.names[reflect.PointerTo(t)]
+	return name, ok
+}
+
+func (r *TypeRegistry[I]) interfaceName() string {
+	return reflect.TypeOf((*I)(nil)).Elem().Name()
+}
+
+// conflictsWithField 结构体是否有 JSON 名字与 field 相同的字段，encoding/json 解码时名字不区分大小写
+func conflictsWithField(t reflect.Type, field string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package demo11_interface
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShapesJSONRoundTrip(t *testing.T) {
+	shapes := []Shaper{
+		&Square{Side: 4.5},
+		Rectangle{Length: 4.5, Width: 5.5, Center: Pt(1, 2), Angle: math.Pi / 6},
+		Circle{Radius: 1},
+		Ellipse{Center: Pt(-1, 0), RX: 3, RY: 1},
+		Triangle{Pt(0, 0), Pt(3, 0), Pt(0, 4)},
+		Polygon{[]Point{Pt(0, 0), Pt(4, 0), Pt(4, 1), Pt(1, 1), Pt(1, 3), Pt(0, 3)}},
+		nil,
+	}
+	data, err := MarshalShapes(shapes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"type":"square","side":4.5,"center":{"x":0,"y":0}},`; !strings.HasPrefix(string(data), want) {
+		t.Errorf("got %s, want prefix %s", data, want)
+	}
+	got, err := UnmarshalShapes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, shapes) {
+		t.Errorf("round trip:\n got %#v\nwant %#v", got, shapes)
+	}
+	if _, ok := got[0].(*Square); !ok {
+		t.Errorf("square decoded as %T, want *Square", got[0])
+	}
+}
+
+func TestShapesJSONErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		msg  string
+	}{
+		{`[{"type":"square","side":1},{"type":"hexagon","side":1}]`, `item 1: json: unknown Shaper type "hexagon" (known: circle, ellipse, polygon, rectangle, square, triangle)`},
+		{`[{"side":1}]`, `item 0: json: missing "type" field for Shaper`},
+		{`[{"type":1}]`, `"type" field must be a string`},
+		{`[{"type":"circle","radius":"big"}]`, "decode circle"},
+		{`[{"type":"polygon","points":[{"x":0,"y":0},{"x":2,"y":2},{"x":2,"y":0},{"x":0,"y":2}]}]`, "invalid polygon: polygon: edges 0 and 2 intersect"},
+		{`{"type":"square"}`, "cannot unmarshal object"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalShapes([]byte(tt.json))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: got %v, want %q", tt.json, err, tt.msg)
+		}
+	}
+
+	var ute *UnknownTypeError
+	if _, err := UnmarshalShapes([]byte(`[null,{"type":"star"}]`)); !errors.As(err, &ute) || ute.Name != "star" {
+		t.Errorf("got %v, want UnknownTypeError", err)
+	}
+
+	var unreg *UnregisteredTypeError
+	_, err := MarshalShapes([]Shaper{Circle{}, Styled{Shaper: Circle{}}})
+	if !errors.As(err, &unreg) || unreg.Type != reflect.TypeOf(Styled{}) || !strings.HasPrefix(err.Error(), "item 1: ") {
+		t.Errorf("got %v, want UnregisteredTypeError", err)
+	}
+}
+
+// TestShapesJSONPointers 只注册了 Rectangle 时 &Rectangle{} 也能编码，解码得到注册时的形式
+func TestShapesJSONPointers(t *testing.T) {
+	data, err := MarshalShapes([]Shaper{&Rectangle{Length: 2, Width: 1}, &Circle{Radius: 1}, &Ellipse{RX: 2, RY: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"type":"rectangle","length":2,"width":1,`; !strings.HasPrefix(string(data), want) {
+		t.Errorf("got %s, want prefix %s", data, want)
+	}
+	got, err := UnmarshalShapes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Shaper{Rectangle{Length: 2, Width: 1}, Circle{Radius: 1}, Ellipse{RX: 2, RY: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+// bond 和 deposit 演示注册表用于其他接口
+type bond struct {
+	Face   Decimal `json:"face"`
+	Coupon Decimal `json:"coupon"`
+}
+
+func (b bond) getValue() (Decimal, error) {
+	rate, err := NewDecimal(1, 0).Add(b.Coupon)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return b.Face.Mul(rate, RoundHalfEven)
+}
+
+type deposit struct {
+	Amount Decimal
+}
+
+func (d *deposit) getValue() (Decimal, error) {
+	return d.Amount, nil
+}
+
+func TestTypeRegistryGeneric(t *testing.T) {
+	assets := NewTypeRegistry[valuable]("kind")
+	assets.Register("bond", bond{})
+	assets.Register("deposit", &deposit{})
+
+	in := []valuable{bond{Face: NewDecimal(100, 0), Coupon: MustParseDecimal("0.05")}, &deposit{Amount: NewDecimal(30, 0)}}
+	data, err := assets.MarshalSlice(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"kind":"bond","face":100,"coupon":0.05},{"kind":"deposit","Amount":30}]`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	out, err := assets.UnmarshalSlice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total Decimal
+	for _, v := range out {
+		value, err := v.getValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total, err = total.Add(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total.String() != "135.00" {
+		t.Errorf("total value = %v, want 135", total)
+	}
+
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: want panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("duplicate name", func() { assets.Register("bond", &deposit{}) })
+	mustPanic("duplicate type", func() { assets.Register("bond2", bond{}) })
+	mustPanic("field conflict", func() {
+		NewTypeRegistry[valuable]("face").Register("bond", bond{})
+	})
+	mustPanic("not an interface", func() { NewTypeRegistry[bond]("type") })
+}
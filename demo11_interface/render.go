@@ -0,0 +1,203 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 渲染
+/*
+1:Renderer 把带样式的图形渲染为 SVG 文档（SVG 方法）或者位图（Image、PNG 方法）。
+2:图形使用数学坐标系（y 轴向上），输出使用屏幕坐标系（y 轴向下），Viewport 区域等比缩放后居中放到 Width*Height 的画布上。
+3:SVG 中 Circle、Ellipse 输出为 <circle>、<ellipse>，其余图形输出为 <polygon>；
+  没有实现 Polygonal 的未知图形用包围盒代替。
+4:位图使用扫描线算法填充，只依赖标准库的 image 相关包，不绘制文字标签。
+*/
+
+// Style 图形的样式
+type Style struct {
+	// Fill 填充颜色，nil 表示不填充
+	Fill color.Color
+	// Stroke 描边颜色，nil 表示不描边
+	Stroke color.Color
+	// StrokeWidth 描边宽度，单位是像素，为 0 时使用 1
+	StrokeWidth float64
+	// Label 显示在包围盒中心的文字，只用于 SVG
+	Label string
+}
+
+// Styled 带样式的图形
+type Styled struct {
+	Shaper
+	Style
+}
+
+// Unwrap 返回被包装的图形，相交和距离检测按它计算，见 collision.go
+func (s Styled) Unwrap() Shaper {
+	return s.Shaper
+}
+
+// WithStyle 给所有图形使用同一种样式
+func WithStyle(shapes []Shaper, style Style) []Styled {
+	items := make([]Styled, len(shapes))
+	for i, s := range shapes {
+		items[i] = Styled{s, style}
+	}
+	return items
+}
+
+// Renderer 渲染参数
+type Renderer struct {
+	// Width、Height 画布大小，单位是像素
+	Width, Height int
+	// Viewport 要显示的区域，为空时使用所有图形包围盒的并集，四周留出 5% 的边距
+	Viewport Box
+	// Background 背景颜色，nil 表示透明
+	Background color.Color
+}
+
+// viewTransform 从图形坐标到像素坐标的变换
+type viewTransform struct {
+	scale          float64
+	originX, baseY float64
+	viewport       Box
+}
+
+func (r Renderer) transform(items []Styled) viewTransform {
+	vp := r.Viewport
+	if vp.Width() <= 0 || vp.Height() <= 0 {
+		vp = Box{}
+		for i, it := range items {
+			if i == 0 {
+				vp = it.Bounds()
+			} else {
+				vp = vp.Union(it.Bounds())
+			}
+		}
+		margin := math.Max(vp.Width(), vp.Height()) * 0.05
+		if margin == 0 {
+			margin = 1
+		}
+		vp = Box{vp.Min.Sub(Pt(margin, margin)), vp.Max.Add(Pt(margin, margin))}
+	}
+	w, h := float64(r.Width), float64(r.Height)
+	scale := math.Min(w/vp.Width(), h/vp.Height())
+	return viewTransform{
+		scale:    scale,
+		originX:  (w - vp.Width()*scale) / 2,
+		baseY:    h - (h-vp.Height()*scale)/2,
+		viewport: vp,
+	}
+}
+
+// apply 把图形坐标转换为像素坐标
+func (t viewTransform) apply(p Point) Point {
+	return Point{
+		X: t.originX + (p.X-t.viewport.Min.X)*t.scale,
+		Y: t.baseY - (p.Y-t.viewport.Min.Y)*t.scale,
+	}
+}
+
+// outline 图形在像素坐标中的轮廓，曲线按像素大小细分
+func (t viewTransform) outline(s Shaper) []Point {
+	var points []Point
+	switch s := normalizeShape(s).(type) {
+	case Circle:
+		points = ellipsePolygon(Ellipse{Center: s.Center, RX: s.Radius, RY: s.Radius}, t.segments(s.Radius))
+	case Ellipse:
+		points = ellipsePolygon(s, t.segments(math.Max(s.RX, s.RY)))
+	case Polygonal:
+		points = s.Vertices()
+	default:
+		b := s.Bounds()
+		points = []Point{b.Min, {b.Max.X, b.Min.Y}, b.Max, {b.Min.X, b.Max.Y}}
+	}
+	return transformPoints(points, t.apply)
+}
+
+// segments 半径为 r 的曲线细分的段数，每段大约 2 像素
+func (t viewTransform) segments(r float64) int {
+	n := int(math.Ceil(math.Pi * r * t.scale))
+	return min(max(n, 16), 1024)
+}
+
+// SVG 输出独立的 SVG 文档
+func (r Renderer) SVG(w io.Writer, items []Styled) error {
+	t := r.transform(items)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		r.Width, r.Height, r.Width, r.Height)
+	if r.Background != nil {
+		fmt.Fprintf(bw, `<rect width="100%%" height="100%%"%s/>`+"\n", svgPaint("fill", r.Background))
+	}
+	for _, it := range items {
+		paint := svgPaint("fill", it.Fill) + svgPaint("stroke", it.Stroke)
+		if it.Stroke != nil {
+			paint += ` stroke-width="` + svgNum(it.strokeWidth()) + `"`
+		}
+		switch s := it.Shaper.(type) {
+		case Circle:
+			c := t.apply(s.Center)
+			fmt.Fprintf(bw, `<circle cx="%s" cy="%s" r="%s"%s/>`+"\n", svgNum(c.X), svgNum(c.Y), svgNum(s.Radius*t.scale), paint)
+		case Ellipse:
+			c := t.apply(s.Center)
+			var rotate string
+			// y 轴翻转后逆时针变为顺时针
+			if deg := -s.Angle * 180 / math.Pi; svgNum(deg) != "0" {
+				rotate = fmt.Sprintf(` transform="rotate(%s %s %s)"`, svgNum(deg), svgNum(c.X), svgNum(c.Y))
+			}
+			fmt.Fprintf(bw, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s%s/>`+"\n",
+				svgNum(c.X), svgNum(c.Y), svgNum(s.RX*t.scale), svgNum(s.RY*t.scale), rotate, paint)
+		default:
+			var points []string
+			for _, p := range t.outline(s) {
+				points = append(points, svgNum(p.X)+","+svgNum(p.Y))
+			}
+			fmt.Fprintf(bw, `<polygon points="%s"%s/>`+"\n", strings.Join(points, " "), paint)
+		}
+		if it.Label != "" {
+			c := t.apply(it.Bounds().Center())
+			fmt.Fprintf(bw, `<text x="%s" y="%s" text-anchor="middle" dominant-baseline="central" font-family="sans-serif" font-size="12">`,
+				svgNum(c.X), svgNum(c.Y))
+			escapeXML(bw, it.Label, false)
+			bw.WriteString("</text>\n")
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func (s Style) strokeWidth() float64 {
+	if s.StrokeWidth <= 0 {
+		return 1
+	}
+	return s.StrokeWidth
+}
+
+// svgPaint 输出 fill 或 stroke 属性，带透明度时额外输出 -opacity 属性
+func svgPaint(attr string, c color.Color) string {
+	if c == nil {
+		return " " + attr + `="none"`
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	s := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, n.R, n.G, n.B)
+	if n.A != 0xff {
+		s += fmt.Sprintf(` %s-opacity="%s"`, attr, svgNum(float64(n.A)/0xff))
+	}
+	return s
+}
+
+// svgNum 保留两位小数并去掉多余的 0
+func svgNum(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
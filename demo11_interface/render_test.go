@@ -0,0 +1,126 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "重新生成 testdata 中的 golden 文件")
+
+func testLayout() []Styled {
+	red := color.NRGBA{0xd0, 0x30, 0x30, 0xff}
+	blue := color.NRGBA{0x20, 0x60, 0xc0, 0x99}
+	black := color.Black
+	return []Styled{
+		{&Square{Side: 4.5, Center: Pt(3, 3)}, Style{Fill: red, Stroke: black, StrokeWidth: 2, Label: "square"}},
+		{Rectangle{Length: 4.5, Width: 2, Center: Pt(9, 3), Angle: math.Pi / 6}, Style{Fill: blue, Label: "rect & co"}},
+		{Circle{Center: Pt(3, 9), Radius: 2}, Style{Stroke: red, StrokeWidth: 3}},
+		{Ellipse{Center: Pt(9, 9), RX: 3, RY: 1.5, Angle: -math.Pi / 4}, Style{Fill: blue, Stroke: black}},
+		{Triangle{Pt(5, 5), Pt(7, 5), Pt(6, 7)}, Style{Fill: black}},
+		{Polygon{[]Point{Pt(0, 11), Pt(4, 11), Pt(4, 12), Pt(1, 12), Pt(1, 14), Pt(0, 14)}}, Style{Fill: red, Label: "<L>"}},
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte, equal func(want []byte) bool) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !equal(want) {
+		out := filepath.Join(t.TempDir(), name)
+		os.WriteFile(out, got, 0o644)
+		t.Errorf("%s differs from golden file, output written to %s", name, out)
+	}
+}
+
+func TestRenderSVGGolden(t *testing.T) {
+	r := Renderer{Width: 300, Height: 300, Background: color.White}
+	var buf bytes.Buffer
+	if err := r.SVG(&buf, testLayout()); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "layout.svg", buf.Bytes(), func(want []byte) bool {
+		return bytes.Equal(buf.Bytes(), want)
+	})
+}
+
+func TestRenderPNGGolden(t *testing.T) {
+	r := Renderer{Width: 300, Height: 300, Background: color.White}
+	var buf bytes.Buffer
+	if err := r.PNG(&buf, testLayout()); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := png.Decode(bytes.NewReader(buf.Bytes()))
+	checkGolden(t, "layout.png", buf.Bytes(), func(data []byte) bool {
+		want, err := png.Decode(bytes.NewReader(data))
+		return err == nil && imagesClose(got, want, 2)
+	})
+}
+
+// imagesClose 逐像素比较，允许 tolerance 的误差，避免不同平台浮点运算的细微差别
+func imagesClose(a, b image.Image, tolerance uint32) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	diff := func(x, y uint32) uint32 {
+		if x > y {
+			return (x - y) >> 8
+		}
+		return (y - x) >> 8
+	}
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if diff(r1, r2) > tolerance || diff(g1, g2) > tolerance || diff(b1, b2) > tolerance || diff(a1, a2) > tolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestRasterCoverage(t *testing.T) {
+	// 视口与画布一一对应，y 轴翻转
+	r := Renderer{Width: 10, Height: 10, Viewport: Box{Pt(0, 0), Pt(10, 10)}}
+	img := r.Image([]Styled{
+		{Rectangle{Length: 4, Width: 3, Center: Pt(3, 7.5)}, Style{Fill: color.White}},
+		{Rectangle{Length: 3, Width: 4, Center: Pt(7, 2)}, Style{Fill: color.White}},
+	})
+	tests := []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{1, 1, 0xff}, {4, 3, 0xff}, {0, 1, 0}, {2, 4, 0}, // 第一个长方形 x∈[1,5]、像素 y∈[1,4]
+		{5, 8, 0x80}, {8, 8, 0x80}, {6, 6, 0xff}, {7, 5, 0}, // 第二个长方形 x∈[5.5,8.5]，左右两列各覆盖一半
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y).A; got != tt.alpha {
+			t.Errorf("alpha at (%d,%d) = %#x, want %#x", tt.x, tt.y, got, tt.alpha)
+		}
+	}
+
+	// 重叠的描边不会叠加透明度
+	img = r.Image([]Styled{{&Square{Side: 6, Center: Pt(5, 5)}, Style{Stroke: color.NRGBA{0, 0, 0, 0x80}, StrokeWidth: 2}}})
+	if got := img.RGBAAt(2, 2).A; got != 0x80 {
+		t.Errorf("stroke corner alpha = %#x, want 0x80", got)
+	}
+}
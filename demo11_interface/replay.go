@@ -0,0 +1,256 @@
+package demo11_interface
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 行情回放
+/*
+1:Replay 持有一个回放时钟，按时间顺序把历史价格写入 Portfolio 中对应股票的 stockPosition，不依赖任何实时行情服务。
+2:FastForward 把时钟直接拨到某个时刻，途中的价格都会被应用；Step 前进到下一个有价格的时刻。
+  先 FastForward 到窗口起点再 Run 到窗口终点，就得到只包含这段时间的回测结果。
+3:Run 在回放过程中按固定间隔（或者每个有价格的时刻）记录组合市值，得到 Series。
+4:Series 的收益率是相邻两个点的简单收益率，回撤是相对于之前最高点的跌幅，波动率是收益率的样本标准差，
+  都没有年化，年化波动率需要乘以每年期数的平方根。
+*/
+
+// Replay 在 Portfolio 上回放历史价格
+type Replay struct {
+	portfolio *Portfolio
+	ticks     []PriceTick
+	next      int
+	now       time.Time
+}
+
+// NewReplay 创建回放，feeds 合并后按时间排序，时钟停在第一个价格之前
+func NewReplay(p *Portfolio, feeds ...[]PriceTick) *Replay {
+	return &Replay{portfolio: p, ticks: MergePrices(feeds...)}
+}
+
+// Now 回放时钟的当前时间，还没有开始时是零值
+func (r *Replay) Now() time.Time {
+	return r.now
+}
+
+// Done 所有价格都已经回放
+func (r *Replay) Done() bool {
+	return r.next >= len(r.ticks)
+}
+
+// Step 前进到下一个有价格的时刻，回放这一时刻的所有价格
+func (r *Replay) Step() error {
+	if r.Done() {
+		return nil
+	}
+	return r.FastForward(r.ticks[r.next].Time)
+}
+
+// FastForward 回放时间不晚于 t 的所有价格并把时钟拨到 t，t 早于当前时间时时钟不会倒退
+func (r *Replay) FastForward(t time.Time) error {
+	for r.next < len(r.ticks) && !r.ticks[r.next].Time.After(t) {
+		tick := r.ticks[r.next]
+		if err := r.apply(tick); err != nil {
+			return fmt.Errorf("replay %s at %s: %w", tick.Ticker, tick.Time.Format(time.RFC3339), err)
+		}
+		r.next++
+		r.now = tick.Time
+	}
+	if t.After(r.now) {
+		r.now = t
+	}
+	return nil
+}
+
+// apply 更新所有持有这只股票的持仓，股数取持仓当前的单位数（可以是小数），
+// 持仓是 *stockPosition 时写入新的指针，不修改原来指向的值
+func (r *Replay) apply(tick PriceTick) error {
+	for _, h := range r.portfolio.Holdings() {
+		var sp stockPosition
+		switch a := h.Asset.(type) {
+		case stockPosition:
+			sp = a
+		case *stockPosition:
+			if a == nil {
+				continue
+			}
+			sp = *a
+		default:
+			continue
+		}
+		if sp.ticker != tick.Ticker || h.Units.IsZero() {
+			continue
+		}
+		sp.sharePrice, sp.count = tick.Price, h.Units
+		var asset valuable = sp
+		if _, ok := h.Asset.(*stockPosition); ok {
+			asset = &sp
+		}
+		if err := r.portfolio.Mark(h.Name, asset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 从当前时间回放到 until（零值表示回放到最后一个价格），每隔 interval 记录一次组合市值，
+// interval 为 0 时在每个有价格的时刻记录。第一个点是开始时的市值，还没有开始时先回放第一个时刻，
+// 没有可回放的价格时返回空的 Series
+func (r *Replay) Run(until time.Time, interval time.Duration) (Series, error) {
+	if interval < 0 {
+		return nil, fmt.Errorf("replay: negative interval %v", interval)
+	}
+	if r.now.IsZero() {
+		if err := r.Step(); err != nil {
+			return nil, err
+		}
+		if r.now.IsZero() {
+			return nil, nil
+		}
+	}
+	var series Series
+	record := func() error {
+		rep, err := r.portfolio.Report()
+		if err != nil {
+			return err
+		}
+		series = append(series, ValuePoint{Time: r.now, Value: rep.MarketValue.Float64()})
+		return nil
+	}
+	if err := record(); err != nil {
+		return nil, err
+	}
+	for {
+		var next time.Time
+		switch {
+		case interval > 0 && until.IsZero():
+			if r.Done() {
+				return series, nil
+			}
+			next = r.now.Add(interval)
+		case interval > 0:
+			if !r.now.Before(until) {
+				return series, nil
+			}
+			next = r.now.Add(interval)
+			if next.After(until) {
+				next = until
+			}
+		default:
+			if r.Done() || (!until.IsZero() && r.ticks[r.next].Time.After(until)) {
+				if until.After(r.now) {
+					r.now = until
+				}
+				return series, nil
+			}
+			next = r.ticks[r.next].Time
+		}
+		if err := r.FastForward(next); err != nil {
+			return series, err
+		}
+		if err := record(); err != nil {
+			return series, err
+		}
+	}
+}
+
+// ValuePoint 某个时刻的组合市值，用于统计收益率和波动率，所以是 float64
+type ValuePoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series 按时间排列的组合市值
+type Series []ValuePoint
+
+// SeriesStats 市值序列的统计
+type SeriesStats struct {
+	Start, End           time.Time
+	StartValue, EndValue float64
+	// TotalReturn 整个序列的收益率
+	TotalReturn float64
+	// MeanReturn、Volatility 每期收益率的均值和样本标准差
+	MeanReturn float64
+	Volatility float64
+	// MaxDrawdown 最大回撤，Peak 和 Trough 是对应的最高点和最低点
+	MaxDrawdown float64
+	Peak        time.Time
+	Trough      time.Time
+}
+
+// Window 时间在 [from, to) 内的点，零值表示不限制
+func (s Series) Window(from, to time.Time) Series {
+	var w Series
+	for _, p := range s {
+		if (from.IsZero() || !p.Time.Before(from)) && (to.IsZero() || p.Time.Before(to)) {
+			w = append(w, p)
+		}
+	}
+	return w
+}
+
+// Returns 相邻两个点的简单收益率，前一个点市值为 0 时收益率记为 0
+func (s Series) Returns() []float64 {
+	if len(s) < 2 {
+		return nil
+	}
+	returns := make([]float64, len(s)-1)
+	for i := 1; i < len(s); i++ {
+		if prev := s[i-1].Value; prev != 0 {
+			returns[i-1] = s[i].Value/prev - 1
+		}
+	}
+	return returns
+}
+
+// Drawdowns 每个点相对于之前最高点的跌幅，在 [0, 1] 之间
+func (s Series) Drawdowns() []float64 {
+	drawdowns := make([]float64, len(s))
+	peak := math.Inf(-1)
+	for i, p := range s {
+		peak = math.Max(peak, p.Value)
+		if peak > 0 {
+			drawdowns[i] = (peak - p.Value) / peak
+		}
+	}
+	return drawdowns
+}
+
+// Stats 计算收益、回撤和波动率
+func (s Series) Stats() SeriesStats {
+	if len(s) == 0 {
+		return SeriesStats{}
+	}
+	first, last := s[0], s[len(s)-1]
+	st := SeriesStats{Start: first.Time, End: last.Time, StartValue: first.Value, EndValue: last.Value}
+	if first.Value != 0 {
+		st.TotalReturn = last.Value/first.Value - 1
+	}
+
+	returns := s.Returns()
+	for _, r := range returns {
+		st.MeanReturn += r
+	}
+	if n := float64(len(returns)); n > 0 {
+		st.MeanReturn /= n
+	}
+	if len(returns) > 1 {
+		var ss float64
+		for _, r := range returns {
+			ss += (r - st.MeanReturn) * (r - st.MeanReturn)
+		}
+		st.Volatility = math.Sqrt(ss / float64(len(returns)-1))
+	}
+
+	peak := 0
+	for i, d := range s.Drawdowns() {
+		if s[i].Value >= s[peak].Value {
+			peak = i
+		}
+		if d > st.MaxDrawdown {
+			st.MaxDrawdown, st.Peak, st.Trough = d, s[peak].Time, s[i].Time
+		}
+	}
+	return st
+}
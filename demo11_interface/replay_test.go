@@ -0,0 +1,181 @@
+package demo11_interface
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(d, hour int) time.Time {
+	return time.Date(2024, 1, d, hour, 0, 0, 0, time.UTC)
+}
+
+func testReplay(t *testing.T) (*Replay, *Portfolio) {
+	t.Helper()
+	var feeds [][]PriceTick
+	for _, name := range []string{"esop_ticks.csv", "acme_daily.csv"} {
+		ticks, err := LoadPrices(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeds = append(feeds, ticks)
+	}
+	p := NewPortfolio("USD", nil)
+	p.Buy(Trade{Holding: "ESOP", Units: NewDecimal(10, 0), Amount: NewDecimal(800, 0), Asset: stockPosition{ticker: "ESOP", sharePrice: NewDecimal(75, 0), count: NewDecimal(10, 0)}})
+	p.Buy(Trade{Holding: "ACME", Units: NewDecimal(5, 0), Amount: NewDecimal(500, 0), Asset: stockPosition{ticker: "ACME", sharePrice: NewDecimal(100, 0), count: NewDecimal(5, 0)}})
+	return NewReplay(p, feeds...), p
+}
+
+func TestReplayRun(t *testing.T) {
+	r, p := testReplay(t)
+	series, err := r.Run(time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Series{{day(1, 0), 1300}, {day(2, 0), 1450}, {day(3, 0), 1150}, {day(4, 0), 1325}}
+	if len(series) != len(want) {
+		t.Fatalf("got %v, want %v", series, want)
+	}
+	for i := range want {
+		if !series[i].Time.Equal(want[i].Time) || !near(series[i].Value, want[i].Value) {
+			t.Errorf("point %d = %v, want %v", i, series[i], want[i])
+		}
+	}
+	if !r.Done() || !r.Now().Equal(day(4, 0)) {
+		t.Errorf("clock at %v, done %v", r.Now(), r.Done())
+	}
+	if h, _ := p.Get("ESOP"); h.Asset.(stockPosition).sharePrice.Cmp(NewDecimal(85, 0)) != 0 {
+		t.Errorf("ESOP asset = %+v", h.Asset)
+	}
+
+	st := series.Stats()
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"total return", st.TotalReturn, 1325.0/1300 - 1},
+		{"mean return", st.MeanReturn, (1450.0/1300 + 1150.0/1450 + 1325.0/1150 - 3) / 3},
+		{"max drawdown", st.MaxDrawdown, 300.0 / 1450},
+		{"last drawdown", series.Drawdowns()[3], 125.0 / 1450},
+	}
+	for _, c := range checks {
+		if !near(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if !st.Peak.Equal(day(2, 0)) || !st.Trough.Equal(day(3, 0)) || !(st.Volatility > 0.15 && st.Volatility < 0.22) {
+		t.Errorf("stats = %+v", st)
+	}
+	if w := series.Window(day(2, 0), day(4, 0)); len(w) != 2 || !w[0].Time.Equal(day(2, 0)) {
+		t.Errorf("window = %v", w)
+	}
+}
+
+func TestReplayFastForward(t *testing.T) {
+	// 每 12 小时记录一次，价格之间的点沿用上一个价格
+	r, _ := testReplay(t)
+	series, err := r.Run(time.Time{}, 12*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 7 || series[1].Value != series[0].Value || !series[6].Time.Equal(day(4, 0)) {
+		t.Errorf("12h series = %v", series)
+	}
+
+	// 只回测 1 月 2 日中午到 1 月 4 日，之前的价格仍然生效
+	r, p := testReplay(t)
+	if err := r.FastForward(day(2, 12)); err != nil {
+		t.Fatal(err)
+	}
+	// 回放途中卖出一半 ESOP，之后的价格按剩余的股数计算
+	p.Sell(Trade{Holding: "ESOP", Units: NewDecimal(5, 0), Amount: NewDecimal(450, 0)})
+	series, err = r.Run(day(4, 0), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Series{{day(2, 12), 1000}, {day(3, 12), 800}, {day(4, 0), 900}}
+	if len(series) != len(want) {
+		t.Fatalf("got %v, want %v", series, want)
+	}
+	for i := range want {
+		if !series[i].Time.Equal(want[i].Time) || !near(series[i].Value, want[i].Value) {
+			t.Errorf("point %d = %v, want %v", i, series[i], want[i])
+		}
+	}
+
+	// 时钟不会倒退
+	r.FastForward(day(1, 0))
+	if !r.Now().Equal(day(4, 0)) {
+		t.Errorf("clock moved back to %v", r.Now())
+	}
+}
+
+// TestReplayFractionalShares 卖出 1/3 股后剩余的小数股数作为 stockPosition 的股数，估值不会溢出
+func TestReplayFractionalShares(t *testing.T) {
+	r, p := testReplay(t)
+	third, _ := DecimalFromFloat(1.0 / 3)
+	if err := p.Sell(Trade{Holding: "ESOP", Units: third, Amount: NewDecimal(25, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	series, err := r.Run(time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 最后 ESOP 9.6666666666666667 股 × 85，ACME 5 股 × 95
+	if last := series[len(series)-1]; !near(last.Value, 9.6666666666666667*85+475) {
+		t.Errorf("last point = %v", last)
+	}
+	h, _ := p.Get("ESOP")
+	if sp := h.Asset.(stockPosition); sp.count.String() != "9.6666666666666667" {
+		t.Errorf("ESOP count = %v", sp.count)
+	}
+}
+
+// TestReplayEmptyFeed 没有价格时时钟不会从零值开始逐步前进
+func TestReplayEmptyFeed(t *testing.T) {
+	p := NewPortfolio("USD", nil)
+	p.Buy(Trade{Holding: "ESOP", Units: NewDecimal(10, 0), Amount: NewDecimal(800, 0), Asset: stockPosition{ticker: "ESOP", sharePrice: NewDecimal(75, 0), count: NewDecimal(10, 0)}})
+	r := NewReplay(p)
+	series, err := r.Run(day(1, 0), 24*time.Hour)
+	if err != nil || len(series) != 0 || !r.Now().IsZero() {
+		t.Errorf("got %v, %v, clock at %v", series, err, r.Now())
+	}
+}
+
+// TestReplayPointerAsset 持仓是 *stockPosition 时也会更新价格，原来的值不变
+func TestReplayPointerAsset(t *testing.T) {
+	ticks, err := LoadPrices(filepath.Join("testdata", "esop_ticks.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := &stockPosition{ticker: "ESOP", sharePrice: NewDecimal(75, 0), count: NewDecimal(10, 0)}
+	p := NewPortfolio("USD", nil)
+	p.Buy(Trade{Holding: "ESOP", Units: NewDecimal(10, 0), Amount: NewDecimal(800, 0), Asset: orig})
+	if _, err := NewReplay(p, ticks).Run(time.Time{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	h, _ := p.Get("ESOP")
+	sp, ok := h.Asset.(*stockPosition)
+	if !ok || sp == orig || sp.sharePrice.Cmp(NewDecimal(85, 0)) != 0 || orig.sharePrice.Cmp(NewDecimal(75, 0)) != 0 {
+		t.Errorf("ESOP asset = %+v, original %+v", h.Asset, orig)
+	}
+}
+
+func TestReadPricesErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{"", "empty file"},
+		{"time,ticker\n", `line 1: missing column "open"`},
+		{"time,ticker,price\n2024-01-01,ESOP,-1\n", `line 2: invalid price "-1"`},
+		{"time,ticker,price\n2024-01-01,,1\n", "line 2: empty ticker"},
+		{"time,ticker,price\n2024-01-01,A,1\nyesterday,A,1\n", `line 3: invalid time "yesterday"`},
+		{"time,ticker,open,high,low,close\n2024-01-01,A,10,11,9,12\n", "line 2: inconsistent bar"},
+	}
+	for _, tt := range tests {
+		if _, err := ReadPrices(strings.NewReader(tt.src)); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%q: got %v, want %q", tt.src, err, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package demo11_interface
+
+import "math"
+
+// 图形
+/*
+1:所有图形都实现 Shaper，面积、周长、坐标都使用 float64。
+2:Translate、Rotate、Scale 不修改原来的图形，返回变换后的新图形，类型与原来相同：
+	a:Square 的方法接收者是指针，返回 *Square
+	b:其余图形的方法接收者是值，返回值类型
+3:Square、Rectangle、Ellipse 由中心、尺寸和旋转角描述，旋转后仍然是同一种图形；Circle 旋转只移动圆心。
+4:Scale 的系数为负数时相当于缩放 |k| 倍后再旋转 180 度。
+5:顶点可以列举的图形实现 Polygonal，顶点按逆时针顺序排列。
+*/
+
+// Shaper 图形
+type Shaper interface {
+	// Area 面积
+	Area() float64
+	// Perimeter 周长
+	Perimeter() float64
+	// Bounds 包围盒
+	Bounds() Box
+	// Contains 点是否在图形内，包括边界
+	Contains(p Point) bool
+	// Translate 平移
+	Translate(dx, dy float64) Shaper
+	// Rotate 绕 pivot 逆时针旋转 angle 弧度
+	Rotate(angle float64, pivot Point) Shaper
+	// Scale 以 pivot 为中心缩放 k 倍
+	Scale(k float64, pivot Point) Shaper
+}
+
+// Polygonal 由有限个顶点围成的图形
+type Polygonal interface {
+	Shaper
+	// Vertices 逆时针顺序的顶点
+	Vertices() []Point
+}
+
+// TopologicalGenus 拓扑级
+type TopologicalGenus interface {
+	// Rank 等级
+	Rank() int
+}
+
+// Square 正方形
+type Square struct {
+	// Side 边
+	Side float64 `json:"side"`
+	// Center 中心
+	Center Point `json:"center"`
+	// Angle 旋转角
+	Angle float64 `json:"angle,omitempty"`
+}
+
+func (s *Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s *Square) Perimeter() float64 {
+	return 4 * s.Side
+}
+
+func (s *Square) Vertices() []Point {
+	return boxVertices(s.Center, s.Side, s.Side, s.Angle)
+}
+
+func (s *Square) Bounds() Box {
+	return BoxOf(s.Vertices()...)
+}
+
+func (s *Square) Contains(p Point) bool {
+	return boxContains(s.Center, s.Side, s.Side, s.Angle, p)
+}
+
+func (s *Square) Translate(dx, dy float64) Shaper {
+	t := *s
+	t.Center = t.Center.Add(Pt(dx, dy))
+	return &t
+}
+
+func (s *Square) Rotate(angle float64, pivot Point) Shaper {
+	t := *s
+	t.Center = t.Center.Rotate(angle, pivot)
+	t.Angle += angle
+	return &t
+}
+
+func (s *Square) Scale(k float64, pivot Point) Shaper {
+	t := *s
+	t.Center = t.Center.Scale(k, pivot)
+	t.Side *= math.Abs(k)
+	if k < 0 {
+		t.Angle += math.Pi
+	}
+	return &t
+}
+
+// Rank 由网格计算拓扑亏格，正方形是圆盘，亏格为 0
+func (s *Square) Rank() int {
+	return PolygonMesh(s.Vertices()).Rank()
+}
+
+// Rectangle 长方形，未旋转时 Length 沿 x 轴，Width 沿 y 轴
+type Rectangle struct {
+	// 长，宽
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	// Center 中心
+	Center Point `json:"center"`
+	// Angle 旋转角
+	Angle float64 `json:"angle,omitempty"`
+}
+
+func (r Rectangle) Area() float64 {
+	return r.Length * r.Width
+}
+
+func (r Rectangle) Perimeter() float64 {
+	return 2 * (r.Length + r.Width)
+}
+
+func (r Rectangle) Vertices() []Point {
+	return boxVertices(r.Center, r.Length, r.Width, r.Angle)
+}
+
+func (r Rectangle) Bounds() Box {
+	return BoxOf(r.Vertices()...)
+}
+
+func (r Rectangle) Contains(p Point) bool {
+	return boxContains(r.Center, r.Length, r.Width, r.Angle, p)
+}
+
+func (r Rectangle) Translate(dx, dy float64) Shaper {
+	r.Center = r.Center.Add(Pt(dx, dy))
+	return r
+}
+
+func (r Rectangle) Rotate(angle float64, pivot Point) Shaper {
+	r.Center = r.Center.Rotate(angle, pivot)
+	r.Angle += angle
+	return r
+}
+
+func (r Rectangle) Scale(k float64, pivot Point) Shaper {
+	r.Center = r.Center.Scale(k, pivot)
+	r.Length *= math.Abs(k)
+	r.Width *= math.Abs(k)
+	if k < 0 {
+		r.Angle += math.Pi
+	}
+	return r
+}
+
+// Rank 由网格计算拓扑亏格，长方形是圆盘，亏格为 0
+func (r Rectangle) Rank() int {
+	return PolygonMesh(r.Vertices()).Rank()
+}
+
+// Circle 圆
+type Circle struct {
+	Center Point   `json:"center"`
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (c Circle) Perimeter() float64 {
+	return 2 * math.Pi * c.Radius
+}
+
+func (c Circle) Bounds() Box {
+	return Box{c.Center.Sub(Pt(c.Radius, c.Radius)), c.Center.Add(Pt(c.Radius, c.Radius))}
+}
+
+func (c Circle) Contains(p Point) bool {
+	return p.Dist(c.Center) <= c.Radius+geomEpsilon
+}
+
+func (c Circle) Translate(dx, dy float64) Shaper {
+	c.Center = c.Center.Add(Pt(dx, dy))
+	return c
+}
+
+func (c Circle) Rotate(angle float64, pivot Point) Shaper {
+	c.Center = c.Center.Rotate(angle, pivot)
+	return c
+}
+
+func (c Circle) Scale(k float64, pivot Point) Shaper {
+	c.Center = c.Center.Scale(k, pivot)
+	c.Radius *= math.Abs(k)
+	return c
+}
+
+// Ellipse 椭圆，未旋转时 RX 沿 x 轴，RY 沿 y 轴
+type Ellipse struct {
+	Center Point `json:"center"`
+	// RX、RY 半轴长
+	RX float64 `json:"rx"`
+	RY float64 `json:"ry"`
+	// Angle 旋转角
+	Angle float64 `json:"angle,omitempty"`
+}
+
+func (e Ellipse) Area() float64 {
+	return math.Pi * e.RX * e.RY
+}
+
+// Perimeter 使用 Ramanujan 第二近似公式，误差在 h^5 量级
+func (e Ellipse) Perimeter() float64 {
+	a, b := e.RX, e.RY
+	if a+b == 0 {
+		return 0
+	}
+	h := (a - b) * (a - b) / ((a + b) * (a + b))
+	return math.Pi * (a + b) * (1 + 3*h/(10+math.Sqrt(4-3*h)))
+}
+
+func (e Ellipse) Bounds() Box {
+	sin, cos := math.Sincos(e.Angle)
+	w := math.Hypot(e.RX*cos, e.RY*sin)
+	h := math.Hypot(e.RX*sin, e.RY*cos)
+	return Box{e.Center.Sub(Pt(w, h)), e.Center.Add(Pt(w, h))}
+}
+
+func (e Ellipse) Contains(p Point) bool {
+	if e.RX == 0 || e.RY == 0 {
+		return false
+	}
+	l := p.Rotate(-e.Angle, e.Center).Sub(e.Center)
+	return (l.X*l.X)/(e.RX*e.RX)+(l.Y*l.Y)/(e.RY*e.RY) <= 1+geomEpsilon
+}
+
+func (e Ellipse) Translate(dx, dy float64) Shaper {
+	e.Center = e.Center.Add(Pt(dx, dy))
+	return e
+}
+
+func (e Ellipse) Rotate(angle float64, pivot Point) Shaper {
+	e.Center = e.Center.Rotate(angle, pivot)
+	e.Angle += angle
+	return e
+}
+
+func (e Ellipse) Scale(k float64, pivot Point) Shaper {
+	e.Center = e.Center.Scale(k, pivot)
+	e.RX *= math.Abs(k)
+	e.RY *= math.Abs(k)
+	if k < 0 {
+		e.Angle += math.Pi
+	}
+	return e
+}
+
+// Triangle 三角形
+type Triangle struct {
+	A Point `json:"a"`
+	B Point `json:"b"`
+	C Point `json:"c"`
+}
+
+func (t Triangle) Area() float64 {
+	return math.Abs(t.B.Sub(t.A).Cross(t.C.Sub(t.A))) / 2
+}
+
+func (t Triangle) Perimeter() float64 {
+	return t.A.Dist(t.B) + t.B.Dist(t.C) + t.C.Dist(t.A)
+}
+
+func (t Triangle) Vertices() []Point {
+	if orientation(t.A, t.B, t.C) < 0 {
+		return []Point{t.A, t.C, t.B}
+	}
+	return []Point{t.A, t.B, t.C}
+}
+
+func (t Triangle) Bounds() Box {
+	return BoxOf(t.A, t.B, t.C)
+}
+
+func (t Triangle) Contains(p Point) bool {
+	return polygonContains([]Point{t.A, t.B, t.C}, p)
+}
+
+func (t Triangle) Translate(dx, dy float64) Shaper {
+	return t.transform(func(p Point) Point { return p.Add(Pt(dx, dy)) })
+}
+
+func (t Triangle) Rotate(angle float64, pivot Point) Shaper {
+	return t.transform(func(p Point) Point { return p.Rotate(angle, pivot) })
+}
+
+func (t Triangle) Scale(k float64, pivot Point) Shaper {
+	return t.transform(func(p Point) Point { return p.Scale(k, pivot) })
+}
+
+func (t Triangle) transform(f func(Point) Point) Triangle {
+	return Triangle{f(t.A), f(t.B), f(t.C)}
+}
+
+// boxVertices 中心为 center、尺寸为 w*h、旋转 angle 的长方形的四个顶点
+func boxVertices(center Point, w, h, angle float64) []Point {
+	corners := []Point{{-w / 2, -h / 2}, {w / 2, -h / 2}, {w / 2, h / 2}, {-w / 2, h / 2}}
+	return transformPoints(corners, func(p Point) Point {
+		return center.Add(p).Rotate(angle, center)
+	})
+}
+
+// boxContains 把 p 转换到长方形的局部坐标系后比较
+func boxContains(center Point, w, h, angle float64, p Point) bool {
+	l := p.Rotate(-angle, center).Sub(center)
+	return math.Abs(l.X) <= w/2+geomEpsilon && math.Abs(l.Y) <= h/2+geomEpsilon
+}
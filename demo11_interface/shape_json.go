@@ -0,0 +1,25 @@
+package demo11_interface
+
+// ShapeTypes Shaper 的 JSON 类型注册表，新的图形类型可以调用 ShapeTypes.Register 注册
+var ShapeTypes = newShapeTypes()
+
+func newShapeTypes() *TypeRegistry[Shaper] {
+	r := NewTypeRegistry[Shaper]("type")
+	r.Register("square", &Square{})
+	r.Register("rectangle", Rectangle{})
+	r.Register("circle", Circle{})
+	r.Register("ellipse", Ellipse{})
+	r.Register("triangle", Triangle{})
+	r.Register("polygon", Polygon{})
+	return r
+}
+
+// MarshalShapes 把图形编码为 JSON 数组，每个元素带有 "type" 字段
+func MarshalShapes(shapes []Shaper) ([]byte, error) {
+	return ShapeTypes.MarshalSlice(shapes)
+}
+
+// UnmarshalShapes 解码 MarshalShapes 的输出
+func UnmarshalShapes(data []byte) ([]Shaper, error) {
+	return ShapeTypes.UnmarshalSlice(data)
+}
@@ -0,0 +1,143 @@
+package demo11_interface
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
+
+func nearBox(a, b Box) bool {
+	return near(a.Min.X, b.Min.X) && near(a.Min.Y, b.Min.Y) && near(a.Max.X, b.Max.X) && near(a.Max.Y, b.Max.Y)
+}
+
+func TestShapeMeasures(t *testing.T) {
+	lShape, err := NewPolygon(Pt(0, 0), Pt(4, 0), Pt(4, 1), Pt(1, 1), Pt(1, 3), Pt(0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		shape     Shaper
+		area      float64
+		perimeter float64
+		bounds    Box
+	}{
+		{"square", &Square{Side: 2, Center: Pt(1, 1)}, 4, 8, Box{Pt(0, 0), Pt(2, 2)}},
+		{"rotated square", &Square{Side: 2, Angle: math.Pi / 4}, 4, 8, Box{Pt(-math.Sqrt2, -math.Sqrt2), Pt(math.Sqrt2, math.Sqrt2)}},
+		{"rectangle", Rectangle{Length: 4, Width: 2}, 8, 12, Box{Pt(-2, -1), Pt(2, 1)}},
+		{"circle", Circle{Center: Pt(1, 2), Radius: 1}, math.Pi, 2 * math.Pi, Box{Pt(0, 1), Pt(2, 3)}},
+		{"ellipse", Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2}, 3 * math.Pi, 13.364893220555258, Box{Pt(-1, -3), Pt(1, 3)}},
+		{"circular ellipse", Ellipse{RX: 2, RY: 2}, 4 * math.Pi, 4 * math.Pi, Box{Pt(-2, -2), Pt(2, 2)}},
+		{"triangle", Triangle{Pt(0, 0), Pt(3, 0), Pt(0, 4)}, 6, 12, Box{Pt(0, 0), Pt(3, 4)}},
+		{"L polygon", lShape, 6, 14, Box{Pt(0, 0), Pt(4, 3)}},
+	}
+	for _, tt := range tests {
+		if got := tt.shape.Area(); !near(got, tt.area) {
+			t.Errorf("%s: area = %v, want %v", tt.name, got, tt.area)
+		}
+		if got := tt.shape.Perimeter(); !near(got, tt.perimeter) {
+			t.Errorf("%s: perimeter = %v, want %v", tt.name, got, tt.perimeter)
+		}
+		if got := tt.shape.Bounds(); !nearBox(got, tt.bounds) {
+			t.Errorf("%s: bounds = %v, want %v", tt.name, got, tt.bounds)
+		}
+	}
+}
+
+func TestShapeContains(t *testing.T) {
+	lShape := Polygon{[]Point{Pt(0, 0), Pt(0, 3), Pt(1, 3), Pt(1, 1), Pt(4, 1), Pt(4, 0)}}
+	tests := []struct {
+		shape Shaper
+		in    []Point
+		out   []Point
+	}{
+		{&Square{Side: 2, Angle: math.Pi / 4}, []Point{Pt(0, 0), Pt(math.Sqrt2, 0), Pt(0.5, 0.5)}, []Point{Pt(1, 1), Pt(1.5, 0)}},
+		{Rectangle{Length: 4, Width: 2, Center: Pt(2, 1)}, []Point{Pt(0, 0), Pt(4, 2), Pt(3, 1)}, []Point{Pt(-0.1, 1), Pt(2, 2.1)}},
+		{Circle{Radius: 1}, []Point{Pt(1, 0), Pt(0.6, 0.6)}, []Point{Pt(0.8, 0.8)}},
+		{Ellipse{RX: 3, RY: 1, Angle: math.Pi / 2}, []Point{Pt(0, 3), Pt(0.9, 0)}, []Point{Pt(3, 0), Pt(0.9, 1.5)}},
+		{Triangle{Pt(0, 0), Pt(0, 4), Pt(3, 0)}, []Point{Pt(1, 1), Pt(1.5, 2), Pt(0, 4)}, []Point{Pt(2, 2), Pt(-1, 0)}},
+		{lShape, []Point{Pt(0.5, 2), Pt(3, 0.5), Pt(1, 2), Pt(4, 0)}, []Point{Pt(2, 2), Pt(5, 0.5), Pt(-1, 0)}},
+	}
+	for _, tt := range tests {
+		for _, p := range tt.in {
+			if !tt.shape.Contains(p) {
+				t.Errorf("%T %v should contain %v", tt.shape, tt.shape, p)
+			}
+		}
+		for _, p := range tt.out {
+			if tt.shape.Contains(p) {
+				t.Errorf("%T %v should not contain %v", tt.shape, tt.shape, p)
+			}
+		}
+	}
+}
+
+func TestShapeTransforms(t *testing.T) {
+	shapes := []Shaper{
+		&Square{Side: 2, Center: Pt(1, 1)},
+		Rectangle{Length: 4, Width: 2, Center: Pt(3, 1)},
+		Circle{Center: Pt(2, 0), Radius: 1},
+		Ellipse{Center: Pt(0, 2), RX: 2, RY: 1},
+		Triangle{Pt(0, 0), Pt(3, 0), Pt(0, 4)},
+		Polygon{[]Point{Pt(0, 0), Pt(4, 0), Pt(4, 1), Pt(1, 1), Pt(1, 3), Pt(0, 3)}},
+	}
+	pivot := Pt(1, -1)
+	probe := Pt(1, 0.5)
+	for _, s := range shapes {
+		moved := s.Translate(2, -3).Rotate(math.Pi/3, pivot).Scale(-1.5, pivot)
+		if got, want := moved.Area(), s.Area()*2.25; !near(got, want) {
+			t.Errorf("%T: area after transform = %v, want %v", s, got, want)
+		}
+		if got, want := moved.Perimeter(), s.Perimeter()*1.5; !near(got, want) {
+			t.Errorf("%T: perimeter after transform = %v, want %v", s, got, want)
+		}
+		p := probe.Add(Pt(2, -3)).Rotate(math.Pi/3, pivot).Scale(-1.5, pivot)
+		if s.Contains(probe) != moved.Contains(p) {
+			t.Errorf("%T: transformed probe %v not mapped consistently", s, p)
+		}
+		if b := moved.Bounds(); !b.Contains(p) && moved.Contains(p) {
+			t.Errorf("%T: bounds %v miss contained point %v", s, b, p)
+		}
+	}
+
+	// 变换不修改原来的图形
+	sq := &Square{Side: 1}
+	if moved := sq.Translate(1, 1).(*Square); moved == sq || sq.Center != (Point{}) {
+		t.Error("Translate modified the receiver")
+	}
+	if r := (Rectangle{Length: 1, Width: 2}).Rotate(math.Pi/2, Point{}); !nearBox(r.Bounds(), Box{Pt(-1, -0.5), Pt(1, 0.5)}) {
+		t.Errorf("rotated rectangle bounds = %v", r.Bounds())
+	}
+}
+
+func TestPolygonValidate(t *testing.T) {
+	tests := []struct {
+		points []Point
+		err    error
+	}{
+		{[]Point{Pt(0, 0), Pt(1, 0), Pt(0, 1)}, nil},
+		{[]Point{Pt(0, 0), Pt(0, 2), Pt(1, 1), Pt(2, 2), Pt(2, 0)}, nil},
+		{[]Point{Pt(0, 0), Pt(1, 0)}, ErrDegenerate},
+		{[]Point{Pt(0, 0), Pt(1, 0), Pt(1, 0), Pt(0, 1)}, ErrDegenerate},
+		{[]Point{Pt(0, 0), Pt(1, 1), Pt(2, 2)}, ErrDegenerate},
+		{[]Point{Pt(0, 0), Pt(2, 2), Pt(2, 0), Pt(0, 2)}, ErrSelfIntersecting},
+		{[]Point{Pt(0, 0), Pt(4, 0), Pt(4, 4), Pt(2, 0), Pt(0, 4)}, ErrSelfIntersecting},
+		{[]Point{Pt(0, 0), Pt(2, 0), Pt(1, 0), Pt(1, 2)}, ErrSelfIntersecting},
+		{[]Point{Pt(1, 0), Pt(1, 2), Pt(0, 0), Pt(2, 0)}, ErrSelfIntersecting},
+	}
+	for _, tt := range tests {
+		_, err := NewPolygon(tt.points...)
+		if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Errorf("%v: got %v, want %v", tt.points, err, tt.err)
+		}
+	}
+
+	cw := Polygon{[]Point{Pt(0, 0), Pt(0, 1), Pt(1, 0)}}
+	if v := cw.Vertices(); polygonArea(v) <= 0 {
+		t.Errorf("Vertices %v not counter-clockwise", v)
+	}
+}
@@ -0,0 +1,52 @@
+package demo11_interface
+
+import "math/bits"
+
+// Demo8:使用Sorter接口排序
+/*
+1:Sort、Stable、IsSorted 只依赖 Sorter 的 Len/Less/Swap，任何实现了这三个方法的类型都可以排序。
+2:Less(i, j) 为 true 表示第 i 个元素应该排在第 j 个元素前面，排序完成后对任意 i < j 都有 !Less(j, i)。
+3:Sort 是模式消除快速排序（pdqsort）：
+	a:长度不超过 12 的区间直接用插入排序
+	b:递归层数超过 log2(n) 时退化为堆排序，保证最坏 O(n*log(n))
+	c:对已有序、逆序、大量重复元素的输入有专门的快速路径
+4:Stable 是稳定排序：先对每 20 个元素的块做插入排序，再用 SymMerge 原地两两合并，不申请额外内存，
+  调用 Less 的次数为 O(n*log(n))，调用 Swap 的次数为 O(n*log(n)*log(n))。
+5:排序算法移植自 Go 标准库的 sort 包，见 sort_impl.go 和 LICENSE.golang。
+6:原来的冒泡排序在 Less(i, i+1) 为 true 时交换，Less 的含义和 sort.Interface 正好相反；
+  现在按 sort.Interface 的约定，IntArray 的 Less 相应地改成 a[i] < a[j]，TestIntArray 仍然输出升序。
+*/
+
+// Sorter 可排序的集合
+type Sorter interface {
+	// Len 元素个数
+	Len() int
+	// Less 第 i 个元素是否应该排在第 j 个元素前面
+	Less(i, j int) bool
+	// Swap 交换第 i 个和第 j 个元素
+	Swap(i, j int)
+}
+
+// Sort 不稳定排序，时间复杂度 O(n*log(n))
+func Sort(data Sorter) {
+	n := data.Len()
+	if n <= 1 {
+		return
+	}
+	pdqsort(data, 0, n, bits.Len(uint(n)))
+}
+
+// Stable 稳定排序，相等元素保持原来的相对顺序
+func Stable(data Sorter) {
+	stable(data, data.Len())
+}
+
+// IsSorted 判断 data 是否已经有序
+func IsSorted(data Sorter) bool {
+	for i := data.Len() - 1; i > 0; i-- {
+		if data.Less(i, i-1) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package demo11_interface
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// 测试数据的几种典型模式
+func sortInputs(n int) map[string][]int {
+	r := rand.New(rand.NewSource(int64(n)))
+	random := make([]int, n)
+	sorted := make([]int, n)
+	reversed := make([]int, n)
+	equal := make([]int, n)
+	sawtooth := make([]int, n)
+	fewUnique := make([]int, n)
+	almost := make([]int, n)
+	for i := range random {
+		random[i] = r.Intn(n * 4)
+		sorted[i] = i
+		reversed[i] = n - i
+		equal[i] = 7
+		sawtooth[i] = i % 17
+		fewUnique[i] = r.Intn(4)
+		almost[i] = i
+	}
+	if n > 2 {
+		almost[0], almost[n-1] = almost[n-1], almost[0]
+	}
+	return map[string][]int{
+		"random":    random,
+		"sorted":    sorted,
+		"reversed":  reversed,
+		"equal":     equal,
+		"sawtooth":  sawtooth,
+		"fewUnique": fewUnique,
+		"almost":    almost,
+	}
+}
+
+func TestSort(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 11, 12, 13, 49, 50, 51, 1000, 20000} {
+		for name, data := range sortInputs(n) {
+			want := slices.Clone(data)
+			slices.Sort(want)
+
+			got := IntArray(slices.Clone(data))
+			Sort(got)
+			if !IsSorted(got) || !slices.Equal(got, want) {
+				t.Errorf("Sort %s n=%d: not sorted", name, n)
+			}
+
+			got = IntArray(slices.Clone(data))
+			Stable(got)
+			if !IsSorted(got) || !slices.Equal(got, want) {
+				t.Errorf("Stable %s n=%d: not sorted", name, n)
+			}
+		}
+	}
+}
+
+// countingArray 统计比较次数，用来确认最坏情况也是 O(n*log(n))
+type countingArray struct {
+	IntArray
+	less int
+}
+
+func (c *countingArray) Less(i, j int) bool {
+	c.less++
+	return c.IntArray.Less(i, j)
+}
+
+func TestSortComparisonBound(t *testing.T) {
+	const n = 1 << 14
+	for name, data := range sortInputs(n) {
+		c := &countingArray{IntArray: IntArray(data)}
+		Sort(c)
+		// n*log2(n) 的一个宽松上界
+		if limit := 4 * n * 14; c.less > limit {
+			t.Errorf("%s: %d comparisons, want <= %d", name, c.less, limit)
+		}
+	}
+}
+
+type keyed struct {
+	key, seq int
+}
+
+type byKey []keyed
+
+func (b byKey) Len() int           { return len(b) }
+func (b byKey) Less(i, j int) bool { return b[i].key < b[j].key }
+func (b byKey) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+func TestStable(t *testing.T) {
+	for _, n := range []int{0, 1, 19, 20, 21, 100, 5000} {
+		r := rand.New(rand.NewSource(int64(n)))
+		data := make(byKey, n)
+		for i := range data {
+			data[i] = keyed{key: r.Intn(10), seq: i}
+		}
+		Stable(data)
+		if !IsSorted(data) {
+			t.Fatalf("n=%d: not sorted", n)
+		}
+		for i := 1; i < n; i++ {
+			if data[i].key == data[i-1].key && data[i].seq < data[i-1].seq {
+				t.Fatalf("n=%d: equal keys reordered at %d", n, i)
+			}
+		}
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	if !IsSorted(IntArray{}) || !IsSorted(IntArray{1}) || !IsSorted(IntArray{2, 3, 3}) {
+		t.Error("want sorted")
+	}
+	if IsSorted(IntArray{2, 1}) {
+		t.Error("want unsorted")
+	}
+}
+
+func benchmarkSort(b *testing.B, n int, sort func(Sorter)) {
+	data := make([]int, n)
+	r := rand.New(rand.NewSource(1))
+	for i := range data {
+		data[i] = r.Int()
+	}
+	a := make(IntArray, n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(a, data)
+		b.StartTimer()
+		sort(a)
+	}
+}
+
+func BenchmarkSort1K(b *testing.B)     { benchmarkSort(b, 1e3, Sort) }
+func BenchmarkSort100K(b *testing.B)   { benchmarkSort(b, 1e5, Sort) }
+func BenchmarkSort1M(b *testing.B)     { benchmarkSort(b, 1e6, Sort) }
+func BenchmarkStable1K(b *testing.B)   { benchmarkSort(b, 1e3, Stable) }
+func BenchmarkStable100K(b *testing.B) { benchmarkSort(b, 1e5, Stable) }
+func BenchmarkStable1M(b *testing.B)   { benchmarkSort(b, 1e6, Stable) }
@@ -0,0 +1,452 @@
+package demo11_interface
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+)
+
+// 空间索引
+/*
+1:SpatialIndex 是以图形包围盒为键的 R 树，节点最多 rtreeMaxEntries 项，分裂使用 Guttman 的二次分裂算法。
+2:图形插入后用 ShapeID 标识，Remove、Update 通过 ID 找到所在的叶子节点，删除后不足 rtreeMinEntries 项的节点会被拆掉并重新插入。
+3:查询分为两个阶段：先用包围盒在 R 树中筛选候选（粗筛），再用 ShapesIntersect、ShapeDistance 做精确检测。
+4:SpatialIndex 不是并发安全的，多个 goroutine 同时读写时需要调用方加锁。
+*/
+
+const (
+	rtreeMaxEntries = 16
+	rtreeMinEntries = rtreeMaxEntries * 2 / 5
+)
+
+// ShapeID 图形在索引中的标识，从 1 开始递增，删除后不会复用
+type ShapeID int64
+
+// ShapePair 一对相交的图形，A < B
+type ShapePair struct {
+	A, B ShapeID
+}
+
+// Neighbor 最近邻查询的结果
+type Neighbor struct {
+	ID    ShapeID
+	Shape Shaper
+	// Dist 查询点到图形的距离
+	Dist float64
+}
+
+// SpatialIndex 图形的 R 树索引
+type SpatialIndex struct {
+	root    *rtreeNode
+	entries map[ShapeID]*rtreeEntry
+	nextID  ShapeID
+}
+
+type rtreeNode struct {
+	box      Box
+	parent   *rtreeNode
+	leaf     bool
+	children []*rtreeNode
+	entries  []*rtreeEntry
+}
+
+type rtreeEntry struct {
+	id    ShapeID
+	shape Shaper
+	box   Box
+	leaf  *rtreeNode
+}
+
+// NewSpatialIndex 创建空的索引，并插入 shapes，第 i 个图形的 ID 是 i+1
+func NewSpatialIndex(shapes ...Shaper) *SpatialIndex {
+	idx := &SpatialIndex{
+		root:    &rtreeNode{leaf: true},
+		entries: make(map[ShapeID]*rtreeEntry),
+	}
+	for _, s := range shapes {
+		idx.Insert(s)
+	}
+	return idx
+}
+
+// Len 图形数量
+func (idx *SpatialIndex) Len() int {
+	return len(idx.entries)
+}
+
+// Get 返回 ID 对应的图形
+func (idx *SpatialIndex) Get(id ShapeID) (Shaper, bool) {
+	e, ok := idx.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return e.shape, true
+}
+
+// Insert 插入图形，返回分配的 ID
+func (idx *SpatialIndex) Insert(s Shaper) ShapeID {
+	idx.nextID++
+	e := &rtreeEntry{id: idx.nextID, shape: s, box: s.Bounds()}
+	idx.entries[e.id] = e
+	idx.insertEntry(e)
+	return e.id
+}
+
+// Remove 删除图形，id 不存在时返回 false
+func (idx *SpatialIndex) Remove(id ShapeID) bool {
+	e, ok := idx.entries[id]
+	if !ok {
+		return false
+	}
+	delete(idx.entries, id)
+	idx.removeEntry(e)
+	return true
+}
+
+// Update 替换图形，ID 不变；id 不存在时返回 false
+func (idx *SpatialIndex) Update(id ShapeID, s Shaper) bool {
+	e, ok := idx.entries[id]
+	if !ok {
+		return false
+	}
+	box := s.Bounds()
+	e.shape = s
+	// 新的包围盒仍在原来的叶子节点内时只需要收紧祖先节点
+	if containsBox(e.leaf.box, box) {
+		e.box = box
+		for n := e.leaf; n != nil; n = n.parent {
+			n.recalc()
+		}
+		return true
+	}
+	idx.removeEntry(e)
+	e.box = box
+	idx.insertEntry(e)
+	return true
+}
+
+// Search 返回包围盒与 window 相交的图形 ID，按 ID 排序
+func (idx *SpatialIndex) Search(window Box) []ShapeID {
+	var ids []ShapeID
+	idx.search(window, func(e *rtreeEntry) {
+		ids = append(ids, e.id)
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// Intersecting 返回与 window 这个长方形真正相交的图形 ID，按 ID 排序
+func (idx *SpatialIndex) Intersecting(window Box) []ShapeID {
+	rect := Rectangle{Length: window.Width(), Width: window.Height(), Center: window.Center()}
+	var ids []ShapeID
+	idx.search(window, func(e *rtreeEntry) {
+		if ShapesIntersect(rect, e.shape) {
+			ids = append(ids, e.id)
+		}
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// Nearest 返回距离 p 最近的 k 个图形，按距离从小到大排序，距离相同时按 ID 排序
+func (idx *SpatialIndex) Nearest(p Point, k int) []Neighbor {
+	if k <= 0 || len(idx.entries) == 0 {
+		return nil
+	}
+	// 按距离下界做最佳优先搜索：节点用包围盒距离，图形用精确距离，
+	// 包围盒距离不会大于其中任意图形的距离，所以图形出队的顺序就是最终顺序
+	h := &nearestHeap{{dist: boxDistance(idx.root.box, p), node: idx.root}}
+	var result []Neighbor
+	for h.Len() > 0 && len(result) < k {
+		item := heap.Pop(h).(nearestItem)
+		switch {
+		case item.entry != nil:
+			result = append(result, Neighbor{ID: item.entry.id, Shape: item.entry.shape, Dist: item.dist})
+		case item.node.leaf:
+			for _, e := range item.node.entries {
+				heap.Push(h, nearestItem{dist: ShapeDistance(e.shape, p), entry: e})
+			}
+		default:
+			for _, c := range item.node.children {
+				heap.Push(h, nearestItem{dist: boxDistance(c.box, p), node: c})
+			}
+		}
+	}
+	return result
+}
+
+// IntersectingPairs 返回所有相交的图形对，按 (A, B) 排序
+func (idx *SpatialIndex) IntersectingPairs() []ShapePair {
+	var pairs []ShapePair
+	for _, a := range idx.entries {
+		idx.search(a.box, func(b *rtreeEntry) {
+			if a.id < b.id && ShapesIntersect(a.shape, b.shape) {
+				pairs = append(pairs, ShapePair{a.id, b.id})
+			}
+		})
+	}
+	slices.SortFunc(pairs, func(x, y ShapePair) int {
+		if x.A != y.A {
+			return int(x.A - y.A)
+		}
+		return int(x.B - y.B)
+	})
+	return pairs
+}
+
+func (idx *SpatialIndex) search(window Box, visit func(e *rtreeEntry)) {
+	if len(idx.entries) == 0 {
+		return
+	}
+	stack := []*rtreeNode{idx.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !n.box.Intersects(window) {
+			continue
+		}
+		if n.leaf {
+			for _, e := range n.entries {
+				if e.box.Intersects(window) {
+					visit(e)
+				}
+			}
+			continue
+		}
+		stack = append(stack, n.children...)
+	}
+}
+
+// insertEntry 选择面积增量最小的叶子插入，溢出时向上分裂
+func (idx *SpatialIndex) insertEntry(e *rtreeEntry) {
+	n := idx.root
+	for !n.leaf {
+		best := n.children[0]
+		bestGrowth, bestArea := math.Inf(1), math.Inf(1)
+		for _, c := range n.children {
+			area := boxArea(c.box)
+			growth := boxArea(c.box.Union(e.box)) - area
+			if growth < bestGrowth || growth == bestGrowth && area < bestArea {
+				best, bestGrowth, bestArea = c, growth, area
+			}
+		}
+		n = best
+	}
+	n.entries = append(n.entries, e)
+	e.leaf = n
+
+	for ; n != nil; n = n.parent {
+		if n.size() <= rtreeMaxEntries {
+			n.recalc()
+			continue
+		}
+		sibling := n.split()
+		if n.parent == nil {
+			idx.root = &rtreeNode{children: []*rtreeNode{n, sibling}}
+			n.parent, sibling.parent = idx.root, idx.root
+			idx.root.recalc()
+			return
+		}
+		sibling.parent = n.parent
+		n.parent.children = append(n.parent.children, sibling)
+	}
+}
+
+// removeEntry 从叶子节点删除，拆掉不足最小项数的节点并重新插入其中的图形
+func (idx *SpatialIndex) removeEntry(e *rtreeEntry) {
+	n := e.leaf
+	n.entries = slices.DeleteFunc(n.entries, func(x *rtreeEntry) bool { return x == e })
+	e.leaf = nil
+
+	var orphans []*rtreeEntry
+	for ; n.parent != nil; n = n.parent {
+		if n.size() < rtreeMinEntries {
+			p := n.parent
+			p.children = slices.DeleteFunc(p.children, func(x *rtreeNode) bool { return x == n })
+			orphans = n.collect(orphans)
+		}
+		n.parent.recalc()
+	}
+	n.recalc()
+	// 根节点只剩一个子节点时降低树高
+	for !idx.root.leaf && len(idx.root.children) == 1 {
+		idx.root = idx.root.children[0]
+		idx.root.parent = nil
+	}
+	if !idx.root.leaf && len(idx.root.children) == 0 {
+		idx.root = &rtreeNode{leaf: true}
+	}
+	for _, o := range orphans {
+		idx.insertEntry(o)
+	}
+}
+
+func (n *rtreeNode) size() int {
+	if n.leaf {
+		return len(n.entries)
+	}
+	return len(n.children)
+}
+
+func (n *rtreeNode) boxes() []Box {
+	boxes := make([]Box, 0, n.size())
+	for _, e := range n.entries {
+		boxes = append(boxes, e.box)
+	}
+	for _, c := range n.children {
+		boxes = append(boxes, c.box)
+	}
+	return boxes
+}
+
+func (n *rtreeNode) recalc() {
+	boxes := n.boxes()
+	if len(boxes) == 0 {
+		n.box = Box{}
+		return
+	}
+	n.box = boxes[0]
+	for _, b := range boxes[1:] {
+		n.box = n.box.Union(b)
+	}
+}
+
+// collect 收集子树中所有的图形
+func (n *rtreeNode) collect(dst []*rtreeEntry) []*rtreeEntry {
+	dst = append(dst, n.entries...)
+	for _, c := range n.children {
+		dst = c.collect(dst)
+	}
+	return dst
+}
+
+// split 二次分裂：n 保留第一组，返回包含第二组的新节点
+func (n *rtreeNode) split() *rtreeNode {
+	g1, g2 := quadraticSplit(n.boxes())
+	sibling := &rtreeNode{leaf: n.leaf}
+	if n.leaf {
+		entries := n.entries
+		n.entries = pick(entries, g1)
+		sibling.entries = pick(entries, g2)
+		for _, e := range n.entries {
+			e.leaf = n
+		}
+		for _, e := range sibling.entries {
+			e.leaf = sibling
+		}
+	} else {
+		children := n.children
+		n.children = pick(children, g1)
+		sibling.children = pick(children, g2)
+		for _, c := range sibling.children {
+			c.parent = sibling
+		}
+	}
+	n.recalc()
+	sibling.recalc()
+	return sibling
+}
+
+func pick[T any](items []T, indexes []int) []T {
+	out := make([]T, len(indexes), rtreeMaxEntries+1)
+	for i, j := range indexes {
+		out[i] = items[j]
+	}
+	return out
+}
+
+// quadraticSplit 选出合并后浪费面积最大的两项作为种子，
+// 再依次把对两组面积增量差别最大的项放进增量较小的一组，保证每组至少 rtreeMinEntries 项
+func quadraticSplit(boxes []Box) (g1, g2 []int) {
+	s1, s2 := 0, 1
+	worst := math.Inf(-1)
+	for i := range boxes {
+		for j := i + 1; j < len(boxes); j++ {
+			d := boxArea(boxes[i].Union(boxes[j])) - boxArea(boxes[i]) - boxArea(boxes[j])
+			if d > worst {
+				s1, s2, worst = i, j, d
+			}
+		}
+	}
+	g1, g2 = []int{s1}, []int{s2}
+	b1, b2 := boxes[s1], boxes[s2]
+	rest := make([]int, 0, len(boxes)-2)
+	for i := range boxes {
+		if i != s1 && i != s2 {
+			rest = append(rest, i)
+		}
+	}
+	for len(rest) > 0 {
+		if len(g1)+len(rest) == rtreeMinEntries {
+			g1 = append(g1, rest...)
+			break
+		}
+		if len(g2)+len(rest) == rtreeMinEntries {
+			g2 = append(g2, rest...)
+			break
+		}
+		next, nextDiff := 0, math.Inf(-1)
+		for k, i := range rest {
+			d1 := boxArea(b1.Union(boxes[i])) - boxArea(b1)
+			d2 := boxArea(b2.Union(boxes[i])) - boxArea(b2)
+			if diff := math.Abs(d1 - d2); diff > nextDiff {
+				next, nextDiff = k, diff
+			}
+		}
+		i := rest[next]
+		rest = slices.Delete(rest, next, next+1)
+		d1 := boxArea(b1.Union(boxes[i])) - boxArea(b1)
+		d2 := boxArea(b2.Union(boxes[i])) - boxArea(b2)
+		if d1 < d2 || d1 == d2 && len(g1) <= len(g2) {
+			g1 = append(g1, i)
+			b1 = b1.Union(boxes[i])
+		} else {
+			g2 = append(g2, i)
+			b2 = b2.Union(boxes[i])
+		}
+	}
+	return g1, g2
+}
+
+func boxArea(b Box) float64 {
+	return b.Width() * b.Height()
+}
+
+// containsBox o 是否完全在 b 内
+func containsBox(b, o Box) bool {
+	return b.Contains(o.Min) && b.Contains(o.Max)
+}
+
+type nearestItem struct {
+	dist  float64
+	node  *rtreeNode
+	entry *rtreeEntry
+}
+
+// nearestHeap 按距离排序的小顶堆
+type nearestHeap []nearestItem
+
+func (h nearestHeap) Len() int { return len(h) }
+
+func (h nearestHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.dist != b.dist {
+		return a.dist < b.dist
+	}
+	// 节点先出队，保证距离相同的图形都已入队后再按 ID 输出
+	if (a.entry == nil) != (b.entry == nil) {
+		return a.entry == nil
+	}
+	return a.entry != nil && a.entry.id < b.entry.id
+}
+
+func (h nearestHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *nearestHeap) Push(x any) { *h = append(*h, x.(nearestItem)) }
+
+func (h *nearestHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,201 @@
+package demo11_interface
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func randomShape(r *rand.Rand) Shaper {
+	c := Pt(r.Float64()*1000, r.Float64()*1000)
+	size := 1 + r.Float64()*20
+	angle := r.Float64() * math.Pi
+	switch r.IntN(5) {
+	case 0:
+		return &Square{Side: size, Center: c, Angle: angle}
+	case 1:
+		return Rectangle{Length: size, Width: size / 2, Center: c, Angle: angle}
+	case 2:
+		return Circle{Center: c, Radius: size / 2}
+	case 3:
+		return Ellipse{Center: c, RX: size / 2, RY: size / 4, Angle: angle}
+	}
+	return Triangle{c, c.Add(Pt(size, 0)), c.Add(Pt(0, size)).Rotate(angle, c)}
+}
+
+// bruteForce 对照用的线性扫描实现
+type bruteForce map[ShapeID]Shaper
+
+func (bf bruteForce) search(window Box) []ShapeID {
+	var ids []ShapeID
+	for id, s := range bf {
+		if s.Bounds().Intersects(window) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (bf bruteForce) pairs() []ShapePair {
+	var pairs []ShapePair
+	for a, sa := range bf {
+		for b, sb := range bf {
+			if a < b && ShapesIntersect(sa, sb) {
+				pairs = append(pairs, ShapePair{a, b})
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(x, y ShapePair) int {
+		if x.A != y.A {
+			return int(x.A - y.A)
+		}
+		return int(x.B - y.B)
+	})
+	return pairs
+}
+
+func TestSpatialIndexAgainstBruteForce(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	idx := NewSpatialIndex()
+	bf := bruteForce{}
+	for i := 0; i < 2000; i++ {
+		s := randomShape(r)
+		bf[idx.Insert(s)] = s
+	}
+	// 删除和更新一部分，覆盖节点合并、重新插入和就地更新
+	for id := ShapeID(1); id <= 2000; id++ {
+		switch id % 4 {
+		case 0:
+			if !idx.Remove(id) {
+				t.Fatalf("Remove(%d) = false", id)
+			}
+			delete(bf, id)
+		case 1:
+			s := bf[id].Translate(r.Float64()*100-50, r.Float64()*100-50)
+			idx.Update(id, s)
+			bf[id] = s
+		case 2:
+			s := bf[id].Scale(0.9, bf[id].Bounds().Center())
+			idx.Update(id, s)
+			bf[id] = s
+		}
+	}
+	if idx.Remove(4) || idx.Update(4, Circle{}) {
+		t.Error("removed id still present")
+	}
+	if idx.Len() != len(bf) {
+		t.Fatalf("Len = %d, want %d", idx.Len(), len(bf))
+	}
+	checkRTree(t, idx)
+
+	for i := 0; i < 50; i++ {
+		min := Pt(r.Float64()*1000, r.Float64()*1000)
+		window := Box{min, min.Add(Pt(r.Float64()*150, r.Float64()*150))}
+		if got, want := idx.Search(window), bf.search(window); !slices.Equal(got, want) {
+			t.Fatalf("Search(%v) = %v, want %v", window, got, want)
+		}
+
+		p := Pt(r.Float64()*1000, r.Float64()*1000)
+		got := idx.Nearest(p, 5)
+		var all []Neighbor
+		for id, s := range bf {
+			all = append(all, Neighbor{ID: id, Shape: s, Dist: ShapeDistance(s, p)})
+		}
+		slices.SortFunc(all, func(a, b Neighbor) int {
+			if a.Dist != b.Dist {
+				return int(math.Copysign(1, a.Dist-b.Dist))
+			}
+			return int(a.ID - b.ID)
+		})
+		for j := range got {
+			if got[j].ID != all[j].ID || got[j].Dist != all[j].Dist {
+				t.Fatalf("Nearest(%v)[%d] = %d (%v), want %d (%v)", p, j, got[j].ID, got[j].Dist, all[j].ID, all[j].Dist)
+			}
+		}
+		if len(got) != 5 {
+			t.Fatalf("Nearest returned %d shapes", len(got))
+		}
+	}
+
+	if got, want := idx.IntersectingPairs(), bf.pairs(); !slices.Equal(got, want) {
+		t.Errorf("IntersectingPairs: got %d pairs, want %d", len(got), len(want))
+	}
+
+	for id := range bf {
+		idx.Remove(id)
+	}
+	if idx.Len() != 0 || idx.Search(Box{Pt(-1e9, -1e9), Pt(1e9, 1e9)}) != nil || idx.Nearest(Point{}, 1) != nil {
+		t.Error("index not empty after removing everything")
+	}
+}
+
+// checkRTree 检查父指针、包围盒和节点项数
+func checkRTree(t *testing.T, idx *SpatialIndex) {
+	t.Helper()
+	var walk func(n *rtreeNode, depth int) int
+	leafDepth := -1
+	walk = func(n *rtreeNode, depth int) int {
+		if n != idx.root && (n.size() < rtreeMinEntries || n.size() > rtreeMaxEntries) {
+			t.Fatalf("node with %d entries", n.size())
+		}
+		for _, b := range n.boxes() {
+			if !containsBox(n.box, b) {
+				t.Fatalf("node box %v does not contain %v", n.box, b)
+			}
+		}
+		if n.leaf {
+			if leafDepth >= 0 && depth != leafDepth {
+				t.Fatal("leaves at different depths")
+			}
+			leafDepth = depth
+			for _, e := range n.entries {
+				if e.leaf != n {
+					t.Fatal("stale leaf pointer")
+				}
+			}
+			return len(n.entries)
+		}
+		count := 0
+		for _, c := range n.children {
+			if c.parent != n {
+				t.Fatal("stale parent pointer")
+			}
+			count += walk(c, depth+1)
+		}
+		return count
+	}
+	if n := walk(idx.root, 0); n != idx.Len() {
+		t.Fatalf("tree holds %d shapes, index has %d", n, idx.Len())
+	}
+}
+
+func TestSpatialIndexQueries(t *testing.T) {
+	idx := NewSpatialIndex(
+		&Square{Side: 2, Angle: math.Pi / 4},
+		Rectangle{Length: 4, Width: 1, Center: Pt(5, 0)},
+		Circle{Center: Pt(1.5, 1.5), Radius: 0.5},
+		Triangle{Pt(10, 10), Pt(12, 10), Pt(10, 12)},
+	)
+	// 包围盒相交，精确检测排除掉
+	window := Box{Pt(1, 1), Pt(1.2, 1.2)}
+	if got := idx.Search(window); !slices.Equal(got, []ShapeID{1, 3}) {
+		t.Errorf("Search = %v", got)
+	}
+	if got := idx.Intersecting(window); !slices.Equal(got, []ShapeID{3}) {
+		t.Errorf("Intersecting = %v", got)
+	}
+	if got := idx.IntersectingPairs(); len(got) != 0 {
+		t.Errorf("IntersectingPairs = %v", got)
+	}
+	idx.Update(3, Circle{Center: Pt(1, 1), Radius: 0.5})
+	if got := idx.IntersectingPairs(); !slices.Equal(got, []ShapePair{{1, 3}}) {
+		t.Errorf("IntersectingPairs after update = %v", got)
+	}
+
+	got := idx.Nearest(Pt(9, 9), 2)
+	if len(got) != 2 || got[0].ID != 4 || !near(got[0].Dist, math.Sqrt2) || got[1].ID != 2 {
+		t.Errorf("Nearest = %+v", got)
+	}
+}
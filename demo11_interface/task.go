@@ -0,0 +1,302 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Demo11:接口的继承
+/*
+1:Task 嵌入 *log.Logger，Printf、Println 等方法被提升为 Task 的方法，Run 的输出也写到这个 Logger。
+2:Command 按 shell 的规则切分成程序和参数（支持单双引号和反斜杠转义），但是不经过 shell，没有变量展开和管道；
+  需要 shell 时写成 sh -c '...'。Args 不为 nil 时 Command 就是程序本身，不再切分。
+3:每次尝试受 Timeout 限制，失败（非零退出码、被信号杀掉或者超时）后按 Retry 指数退避并加上随机抖动再试；
+  程序无法启动或者 ctx 被取消时不重试，被取消的尝试返回的错误包含 ctx.Err()。
+4:stdout 和 stderr 按行写到 Logger，每行带上 [名字] stdout: 或者 [名字] stderr: 前缀，
+  一行超过 maxLogLine 字节时先写出前 maxLogLine 字节，不会因为没有换行的输出一直占用内存。
+*/
+
+// ErrTaskTimeout 一次尝试超过了 Timeout
+var ErrTaskTimeout = errors.New("task timed out")
+
+// RetryPolicy 重试策略，零值表示不重试
+type RetryPolicy struct {
+	// MaxAttempts 最多尝试的次数，包括第一次，小于 1 时按 1 处理
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间
+	InitialBackoff time.Duration
+	// MaxBackoff 等待时间的上限，0 表示没有上限
+	MaxBackoff time.Duration
+	// Multiplier 每次重试等待时间的倍数，小于 1 时按 2 处理
+	Multiplier float64
+	// Jitter 0 到 1 之间，等待时间在 [d*(1-Jitter), d] 中随机选取，避免多个任务同时重试
+	Jitter float64
+}
+
+// Backoff 第 retry 次重试（从 1 开始）之前等待的时间
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// Task 要执行的命令
+type Task struct {
+	Command string
+	*log.Logger
+	// Name 日志前缀中的名字，为空时使用 Command
+	Name string
+	// Args 不为 nil 时 Command 是程序，Args 是它的参数
+	Args []string
+	// Dir 工作目录，为空时使用当前目录
+	Dir string
+	// Env 追加到当前进程环境变量后面的 KEY=VALUE，同名时以这里为准
+	Env []string
+	// Timeout 每次尝试的时间上限，0 表示不限制
+	Timeout time.Duration
+	Retry   RetryPolicy
+}
+
+func NewTask(command string, logger *log.Logger) *Task {
+	return &Task{Command: command, Logger: logger}
+}
+
+// Attempt 一次尝试的结果
+type Attempt struct {
+	Start    time.Time
+	Duration time.Duration
+	// ExitCode 进程的退出码，没有启动或者被信号终止时是 -1
+	ExitCode int
+	TimedOut bool
+	Err      error
+}
+
+// TaskResult Run 的结果
+type TaskResult struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	// ExitCode 最后一次尝试的退出码
+	ExitCode int
+	Attempts []Attempt
+}
+
+// Succeeded 最后一次尝试是否成功
+func (r *TaskResult) Succeeded() bool {
+	return len(r.Attempts) > 0 && r.Attempts[len(r.Attempts)-1].Err == nil
+}
+
+// Run 执行命令，必要时重试，返回所有尝试的结果；失败时 error 是最后一次尝试的错误
+func (t *Task) Run(ctx context.Context) (*TaskResult, error) {
+	name, args, err := t.argv()
+	if err != nil {
+		return nil, fmt.Errorf("task %s: %w", t.name(), err)
+	}
+	logger := t.logger()
+	res := &TaskResult{Name: t.name(), Start: time.Now(), ExitCode: -1}
+	defer func() { res.Duration = time.Since(res.Start) }()
+
+	maxAttempts := max(t.Retry.MaxAttempts, 1)
+	for i := 1; ; i++ {
+		a := t.attempt(ctx, logger, name, args)
+		res.Attempts = append(res.Attempts, a)
+		res.ExitCode = a.ExitCode
+		if a.Err == nil {
+			return res, nil
+		}
+		err := fmt.Errorf("task %s: attempt %d/%d: %w", t.name(), i, maxAttempts, a.Err)
+		// 程序无法启动时重试也没有用；被信号杀掉的进程退出码也是 -1，但是有 ExitError，照常重试
+		var exitErr *exec.ExitError
+		launched := a.TimedOut || a.ExitCode != -1 || errors.As(a.Err, &exitErr)
+		if i == maxAttempts || ctx.Err() != nil || !launched {
+			return res, err
+		}
+		wait := t.Retry.Backoff(i)
+		logger.Printf("[%s] %v; retrying in %v", t.name(), err, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, fmt.Errorf("task %s: %w", t.name(), ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *Task) attempt(ctx context.Context, logger *log.Logger, name string, args []string) Attempt {
+	a := Attempt{Start: time.Now(), ExitCode: -1}
+	parent := ctx
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, t.Timeout, ErrTaskTimeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = t.Dir
+	if len(t.Env) > 0 {
+		cmd.Env = append(os.Environ(), t.Env...)
+	}
+	stdout := &lineLogger{logger: logger, prefix: fmt.Sprintf("[%s] stdout: ", t.name())}
+	stderr := &lineLogger{logger: logger, prefix: fmt.Sprintf("[%s] stderr: ", t.name())}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// 子进程把管道传给了孙进程时，不会一直等下去
+	cmd.WaitDelay = time.Second
+
+	a.Err = cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	a.Duration = time.Since(a.Start)
+	if cmd.ProcessState != nil {
+		a.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if a.Err != nil && errors.Is(context.Cause(ctx), ErrTaskTimeout) {
+		a.TimedOut = true
+		a.Err = fmt.Errorf("%w after %v", ErrTaskTimeout, t.Timeout)
+	} else if a.Err != nil && parent.Err() != nil && a.ExitCode == -1 {
+		// 进程是因为 ctx 被取消而被杀掉的
+		a.Err = fmt.Errorf("%w: %v", parent.Err(), a.Err)
+	}
+	return a
+}
+
+func (t *Task) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Command
+}
+
+func (t *Task) logger() *log.Logger {
+	if t.Logger == nil {
+		return log.New(io.Discard, "", 0)
+	}
+	return t.Logger
+}
+
+func (t *Task) argv() (string, []string, error) {
+	if t.Args != nil {
+		return t.Command, t.Args, nil
+	}
+	words, err := splitCommand(t.Command)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(words) == 0 {
+		return "", nil, errors.New("empty command")
+	}
+	return words[0], words[1:], nil
+}
+
+// splitCommand 按 POSIX shell 的引号规则切分命令行：单引号内原样保留，双引号和引号外可以用反斜杠转义
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			// 双引号内的反斜杠只转义 $ ` " \ 和换行
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				cur.WriteByte('\\')
+			}
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// maxLogLine 一行日志最多的字节数
+const maxLogLine = 64 << 10
+
+// lineLogger 把写入的内容按行加上前缀写到 logger，最后不完整的一行由 Flush 写出
+type lineLogger struct {
+	mu     sync.Mutex
+	logger *log.Logger
+	prefix string
+	buf    []byte
+}
+
+func (w *lineLogger) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 || i > maxLogLine {
+			if len(w.buf) < maxLogLine {
+				break
+			}
+			w.logger.Print(w.prefix + string(w.buf[:maxLogLine]))
+			w.buf = w.buf[maxLogLine:]
+			continue
+		}
+		w.logger.Print(w.prefix + strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineLogger) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.logger.Print(w.prefix + string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,226 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 可以被多个 goroutine 同时写的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"echo hi", []string{"echo", "hi"}},
+		{"  sh   -c  'echo a; echo b'  ", []string{"sh", "-c", "echo a; echo b"}},
+		{`printf "%s\n" "a \"b\""`, []string{"printf", `%s\n`, `a "b"`}},
+		{`a\ b 'it''s' ""`, []string{"a b", "its", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.in)
+		if err != nil {
+			t.Errorf("splitCommand(%q): %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{`echo 'x`, `echo "x`, `echo x\`} {
+		if _, err := splitCommand(in); err == nil {
+			t.Errorf("splitCommand(%q): want error", in)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.Backoff(2); got < 150*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("Backoff(2) with jitter = %v, want within [150ms, 300ms]", got)
+		}
+	}
+}
+
+func TestTaskRunOutput(t *testing.T) {
+	requireShell(t)
+	var out syncBuffer
+	task := NewTask(`sh -c 'echo "$GREETING"; echo oops >&2; printf tail'`, log.New(&out, "", 0))
+	task.Name = "greet"
+	task.Env = []string{"GREETING=hello"}
+
+	res, err := task.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Succeeded() || res.ExitCode != 0 || len(res.Attempts) != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if res.Duration < res.Attempts[0].Duration {
+		t.Errorf("total duration %v shorter than attempt %v", res.Duration, res.Attempts[0].Duration)
+	}
+	for _, line := range []string{"[greet] stdout: hello\n", "[greet] stderr: oops\n", "[greet] stdout: tail\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("log missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestTaskRunExitCode(t *testing.T) {
+	requireShell(t)
+	task := &Task{Command: "sh", Args: []string{"-c", "exit 3"}}
+	res, err := task.Run(context.Background())
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("err = %v, want *exec.ExitError", err)
+	}
+	if res.Succeeded() || res.ExitCode != 3 || len(res.Attempts) != 1 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestTaskRunRetry(t *testing.T) {
+	requireShell(t)
+	var out syncBuffer
+	// 第一次运行创建 marker 并失败，第二次成功
+	task := NewTask(`sh -c 'if [ -f marker ]; then echo ok; else touch marker; exit 1; fi'`, log.New(&out, "", 0))
+	task.Dir = t.TempDir()
+	task.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}
+
+	res, err := task.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Attempts) != 2 || res.Attempts[0].ExitCode != 1 || res.Attempts[1].ExitCode != 0 {
+		t.Errorf("attempts = %+v", res.Attempts)
+	}
+	if !strings.Contains(out.String(), "retrying in 10ms") || !strings.Contains(out.String(), "stdout: ok") {
+		t.Errorf("log:\n%s", out.String())
+	}
+}
+
+// TestTaskRunSignal 被信号杀掉的进程退出码是 -1，仍然按失败重试
+func TestTaskRunSignal(t *testing.T) {
+	requireShell(t)
+	task := NewTask(`sh -c 'if [ -f marker ]; then exit 0; else touch marker; kill -9 $$; fi'`, nil)
+	task.Dir = t.TempDir()
+	task.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	res, err := task.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Attempts) != 2 || res.Attempts[0].ExitCode != -1 || res.Attempts[0].TimedOut {
+		t.Errorf("attempts = %+v", res.Attempts)
+	}
+}
+
+// 没有换行的输出每 maxLogLine 字节写出一次
+func TestLineLoggerLongLine(t *testing.T) {
+	var out bytes.Buffer
+	w := &lineLogger{logger: log.New(&out, "", 0), prefix: "> "}
+	chunk := strings.Repeat("x", 1000)
+	for n := 0; n < maxLogLine*2+500; n += len(chunk) {
+		w.Write([]byte(chunk))
+		if len(w.buf) >= maxLogLine {
+			t.Fatalf("buffered %d bytes", len(w.buf))
+		}
+	}
+	w.Write([]byte("\nend"))
+	w.Flush()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 || len(lines[0]) != 2+maxLogLine || len(lines[1]) != 2+maxLogLine || lines[3] != "> end" {
+		t.Errorf("got %d lines", len(lines))
+	}
+}
+
+func TestTaskRunTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found")
+	}
+	task := NewTask("sleep 5", nil)
+	task.Timeout = 50 * time.Millisecond
+	task.Retry = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	start := time.Now()
+	res, err := task.Run(context.Background())
+	if !errors.Is(err, ErrTaskTimeout) {
+		t.Fatalf("err = %v, want ErrTaskTimeout", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("timeout not enforced: took %v", time.Since(start))
+	}
+	if len(res.Attempts) != 2 || !res.Attempts[0].TimedOut || !res.Attempts[1].TimedOut {
+		t.Errorf("attempts = %+v", res.Attempts)
+	}
+}
+
+func TestTaskRunNoRetry(t *testing.T) {
+	task := NewTask("definitely-not-a-command-xyz", nil)
+	task.Retry = RetryPolicy{MaxAttempts: 5}
+	res, err := task.Run(context.Background())
+	var execErr *exec.Error
+	if !errors.As(err, &execErr) {
+		t.Fatalf("err = %v, want *exec.Error", err)
+	}
+	if len(res.Attempts) != 1 || res.ExitCode != -1 {
+		t.Errorf("result = %+v", res)
+	}
+
+	requireShell(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	task = NewTask("sh -c 'exit 1'", nil)
+	task.Retry = RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	res, err = task.Run(ctx)
+	if !errors.Is(err, context.Canceled) || len(res.Attempts) != 1 {
+		t.Errorf("err = %v, attempts = %d", err, len(res.Attempts))
+	}
+
+	if _, err := NewTask("  ", nil).Run(context.Background()); err == nil {
+		t.Error("empty command: want error")
+	}
+}
@@ -0,0 +1,247 @@
+package demo11_interface
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// 网格拓扑
+/*
+1:欧拉示性数 χ = V - E + F，其中 V 只统计被面引用的顶点。
+2:每个连通分量是一个带边界的曲面，边界由只属于一个面的边组成，按顶点连通性分为若干个边界分量 b：
+	a:可定向曲面 χ = 2 - 2g - b，g 是亏格
+	b:不可定向曲面 χ = 2 - k - b，k 是非定向亏格（交叉帽的个数）
+3:可定向性通过在相邻面之间传播朝向判断：共享一条边的两个面必须以相反的方向经过这条边，
+  否则翻转其中一个面，翻转后仍然冲突说明曲面不可定向。
+4:以上公式只对流形网格成立，属于三个以上面的边或者周围的面不能连成一片的顶点会返回 *NonManifoldError。
+*/
+
+// ErrNonManifold 网格不是流形
+var ErrNonManifold = errors.New("non-manifold mesh")
+
+// NonManifoldError 描述不是流形的位置
+type NonManifoldError struct {
+	// Edge 属于三个以上面的边，不是这种情况时为 nil
+	Edge *[2]int
+	// Vertex 周围的面不连通的顶点，Edge 不为 nil 时无意义
+	Vertex int
+}
+
+func (e *NonManifoldError) Error() string {
+	if e.Edge != nil {
+		return fmt.Sprintf("mesh: edge %d-%d is shared by more than two faces", e.Edge[0], e.Edge[1])
+	}
+	return fmt.Sprintf("mesh: faces around vertex %d are not connected", e.Vertex)
+}
+
+func (e *NonManifoldError) Unwrap() error {
+	return ErrNonManifold
+}
+
+// Surface 一个连通分量的拓扑信息
+type Surface struct {
+	Vertices, Edges, Faces int
+	EulerCharacteristic    int
+	BoundaryComponents     int
+	Orientable             bool
+	// Genus 可定向时是亏格，不可定向时是非定向亏格
+	Genus int
+}
+
+// Topology 整个网格的拓扑信息，数量是各个连通分量的和
+type Topology struct {
+	Vertices, Edges, Faces int
+	EulerCharacteristic    int
+	BoundaryComponents     int
+	// Orientable 所有连通分量都可定向
+	Orientable bool
+	// Genus 各个连通分量的 Genus 之和
+	Genus    int
+	Surfaces []Surface
+}
+
+// Topology 计算网格的拓扑信息
+func (m *Mesh) Topology() (Topology, error) {
+	if err := m.Validate(); err != nil {
+		return Topology{}, err
+	}
+
+	// 每条边经过它的面，以及面是否按索引从小到大的方向经过
+	type edgeUse struct {
+		face    int
+		forward bool
+	}
+	uses := make(map[[2]int][]edgeUse)
+	for fi, f := range m.Faces {
+		for i, a := range f {
+			b := f[(i+1)%len(f)]
+			key := edgeKey(a, b)
+			uses[key] = append(uses[key], edgeUse{fi, a < b})
+			if len(uses[key]) > 2 {
+				return Topology{}, &NonManifoldError{Edge: &key}
+			}
+		}
+	}
+	if v, ok := m.nonManifoldVertex(); ok {
+		return Topology{}, &NonManifoldError{Vertex: v}
+	}
+
+	// 用广度优先搜索划分连通分量并传播朝向
+	component := make([]int, len(m.Faces))
+	flipped := make([]bool, len(m.Faces))
+	for i := range component {
+		component[i] = -1
+	}
+	// flip 表示两个面以相同方向经过共享边，其中一个需要翻转
+	type faceLink struct {
+		face int
+		flip bool
+	}
+	adjacent := make([][]faceLink, len(m.Faces))
+	for _, us := range uses {
+		if len(us) == 2 {
+			flip := us[0].forward == us[1].forward
+			adjacent[us[0].face] = append(adjacent[us[0].face], faceLink{us[1].face, flip})
+			adjacent[us[1].face] = append(adjacent[us[1].face], faceLink{us[0].face, flip})
+		}
+	}
+	var surfaces []Surface
+	for start := range m.Faces {
+		if component[start] >= 0 {
+			continue
+		}
+		c := len(surfaces)
+		surfaces = append(surfaces, Surface{Orientable: true})
+		component[start] = c
+		queue := []int{start}
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+			for _, n := range adjacent[f] {
+				want := flipped[f] != n.flip
+				if component[n.face] < 0 {
+					component[n.face] = c
+					flipped[n.face] = want
+					queue = append(queue, n.face)
+				} else if flipped[n.face] != want {
+					surfaces[c].Orientable = false
+				}
+			}
+		}
+	}
+
+	// 统计每个分量的顶点、边、面和边界
+	vertexComponent := make(map[int]int)
+	for fi, f := range m.Faces {
+		surfaces[component[fi]].Faces++
+		for _, v := range f {
+			vertexComponent[v] = component[fi]
+		}
+	}
+	for _, c := range vertexComponent {
+		surfaces[c].Vertices++
+	}
+	boundary := newUnionFind()
+	for key, us := range uses {
+		surfaces[component[us[0].face]].Edges++
+		if len(us) == 1 {
+			boundary.union(key[0], key[1])
+		}
+	}
+	for _, root := range boundary.roots() {
+		surfaces[vertexComponent[root]].BoundaryComponents++
+	}
+
+	topo := Topology{Orientable: true, Surfaces: surfaces}
+	for i := range surfaces {
+		s := &surfaces[i]
+		s.EulerCharacteristic = s.Vertices - s.Edges + s.Faces
+		if s.Orientable {
+			s.Genus = (2 - s.EulerCharacteristic - s.BoundaryComponents) / 2
+		} else {
+			s.Genus = 2 - s.EulerCharacteristic - s.BoundaryComponents
+		}
+		topo.Vertices += s.Vertices
+		topo.Edges += s.Edges
+		topo.Faces += s.Faces
+		topo.EulerCharacteristic += s.EulerCharacteristic
+		topo.BoundaryComponents += s.BoundaryComponents
+		topo.Orientable = topo.Orientable && s.Orientable
+		topo.Genus += s.Genus
+	}
+	return topo, nil
+}
+
+// Rank 实现 TopologicalGenus，返回网格的亏格，网格无效或者不是流形时返回 -1
+func (m *Mesh) Rank() int {
+	topo, err := m.Topology()
+	if err != nil {
+		return -1
+	}
+	return topo.Genus
+}
+
+// nonManifoldVertex 找出周围的面不能通过共享边连成一片的顶点
+func (m *Mesh) nonManifoldVertex() (int, bool) {
+	// 每个顶点的每个面贡献两条以该顶点为端点的边，同一个面的两条边连在一起
+	fans := make(map[int]*unionFind)
+	for _, f := range m.Faces {
+		for i, v := range f {
+			prev, next := f[(i+len(f)-1)%len(f)], f[(i+1)%len(f)]
+			uf, ok := fans[v]
+			if !ok {
+				uf = newUnionFind()
+				fans[v] = uf
+			}
+			uf.union(prev, next)
+		}
+	}
+	for _, v := range slices.Sorted(maps.Keys(fans)) {
+		if len(fans[v].roots()) > 1 {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// unionFind 整数集合上的并查集
+type unionFind struct {
+	parent map[int]int
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[int]int)}
+}
+
+func (u *unionFind) find(x int) int {
+	p, ok := u.parent[x]
+	if !ok {
+		u.parent[x] = x
+		return x
+	}
+	if p != x {
+		p = u.find(p)
+		u.parent[x] = p
+	}
+	return p
+}
+
+func (u *unionFind) union(a, b int) {
+	ra, rb := u.find(a), u.find(b)
+	if ra != rb {
+		u.parent[ra] = rb
+	}
+}
+
+// roots 每个集合的代表元素
+func (u *unionFind) roots() []int {
+	var roots []int
+	for x := range u.parent {
+		if u.find(x) == x {
+			roots = append(roots, x)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,76 @@
+package demo11_interface
+
+import (
+	"fmt"
+	"io"
+)
+
+// Demo2: 所有实现了 valuable 接口的类型都可以用 showValue，Portfolio（见 portfolio.go）组合多个 valuable
+// 金额都是 Decimal（见 decimal.go），不会因为浮点数丢失精度，估值超出 Decimal 的范围时返回错误
+type valuable interface {
+	getValue() (Decimal, error)
+}
+
+// classifier 可选接口，资产自己报告所属的资产类别
+type classifier interface {
+	assetClass() string
+}
+
+type stockPosition struct {
+	ticker     string
+	sharePrice Decimal
+	count      Decimal
+}
+
+// getValue 股数可以是小数（例如回放时取持仓的单位数），结果最多保留 amountScale 位小数（见 portfolio.go），按 RoundHalfEven 舍入
+func (sp stockPosition) getValue() (Decimal, error) {
+	return mulAmount(sp.sharePrice, sp.count)
+}
+
+func (sp stockPosition) assetClass() string {
+	return "equity"
+}
+
+// stockPosition 的字段都未导出，反射无法设置，所以通过 xmlWriter/xmlReader 自定义编解码
+type stockPositionXML struct {
+	XMLName    struct{} `xml:"stockPosition"`
+	Ticker     string   `xml:"ticker,attr"`
+	SharePrice Decimal  `xml:"sharePrice"`
+	Count      Decimal  `xml:"count"`
+}
+
+func (sp stockPosition) WriteXML(w io.Writer) error {
+	return EncodeToXML(stockPositionXML{Ticker: sp.ticker, SharePrice: sp.sharePrice, Count: sp.count}, w)
+}
+
+func (sp *stockPosition) ReadXML(r io.Reader) error {
+	var x stockPositionXML
+	if err := DecodeXML(r, &x); err != nil {
+		return err
+	}
+	*sp = stockPosition{ticker: x.Ticker, sharePrice: x.SharePrice, count: x.Count}
+	return nil
+}
+
+type car struct {
+	make  string
+	model string
+	price Decimal
+}
+
+func (c car) getValue() (Decimal, error) {
+	return c.price, nil
+}
+
+func (c car) assetClass() string {
+	return "vehicle"
+}
+
+func showValue(asset valuable) {
+	v, err := asset.getValue()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(v)
+}
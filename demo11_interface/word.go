@@ -0,0 +1,576 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// Word:基于 piece table 的文档
+/*
+1:文档由两块缓冲区拼成：打开时的原始内容（只读）和追加写入的 add 缓冲区，pieces 按顺序指向它们中的片段。
+  插入只追加 add 并切分片段，删除只调整片段，不会复制整个文档，适合编辑几 MB 的大文件。
+2:每个片段记录自己的换行数，按行列定位时只扫描片段表和一个片段的内容。
+3:每次修改记录为"在 off 处用 added 替换 removed"，两块缓冲区都不会被改写，撤销和重做只需要交换片段，没有次数限制。
+  BeginGroup/EndGroup 之间的修改（可以嵌套）合成一步撤销，ReplaceAll 自己就是一步。
+4:Word 同时实现 File：Write 在偏移量处覆盖写入，越过末尾时中间补零，每次 Write 是一步撤销。
+*/
+
+// ErrOutOfRange 偏移量、行号或者列号超出文档范围
+var ErrOutOfRange = errors.New("position out of range")
+
+// piece 指向 original 或者 add 中的一段
+type piece struct {
+	add   bool
+	start int64
+	n     int64
+	// lines 其中的换行数
+	lines int
+}
+
+// edit 在 off 处用 added 替换了 removed
+type edit struct {
+	off     int64
+	removed []piece
+	added   []piece
+}
+
+// Position 文档中的位置，Line 和 Column 从 1 开始，Column 按字节计算
+type Position struct {
+	Offset int64
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Word 内存中的文档，零值是空文档
+type Word struct {
+	lockOnce sync.Once
+	lock     *RWLock
+	// mu 保护下面的字段，和 lock 分开，持有 Lock 的时候仍然可以读写
+	mu     sync.RWMutex
+	orig   []byte
+	add    []byte
+	pieces []piece
+	size   int64
+	off    int64
+	closed bool
+	// undo、redo 每个元素是一步撤销，包含一组修改
+	undo, redo [][]edit
+	// group BeginGroup 的嵌套层数
+	group int
+}
+
+// NewWord 以 data 为原始内容创建文档，之后调用方不能再修改 data
+func NewWord(data []byte) *Word {
+	w := &Word{orig: data, size: int64(len(data))}
+	if len(data) > 0 {
+		w.pieces = []piece{{start: 0, n: int64(len(data)), lines: bytes.Count(data, []byte{'\n'})}}
+	}
+	return w
+}
+
+func (w *Word) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	n, err := w.read(p, w.off)
+	w.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Write 从偏移量处覆盖写入
+func (w *Word) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	off, text := w.off, p
+	if off > w.size {
+		text = append(make([]byte, off-w.size), p...)
+		off = w.size
+	}
+	w.replace(off, min(int64(len(p)), w.size-off), text)
+	w.off += int64(len(p))
+	return len(p), nil
+}
+
+func (w *Word) Seek(offset int64, whence int) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	off, err := seek(w.off, w.size, offset, whence)
+	if err != nil {
+		return 0, err
+	}
+	w.off = off
+	return off, nil
+}
+
+func (w *Word) ReadAt(p []byte, off int64) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	return w.read(p, off)
+}
+
+func (w *Word) Lock() {
+	w.FileLock().Lock()
+}
+
+func (w *Word) Unlock() {
+	w.FileLock().Unlock()
+}
+
+// FileLock 第一次调用时创建，Close 之后仍然可以使用
+func (w *Word) FileLock() *RWLock {
+	w.lockOnce.Do(func() { w.lock = NewRWLock("word") })
+	return w.lock
+}
+
+// Close 关闭后所有读写和编辑都返回 fs.ErrClosed
+func (w *Word) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return nil
+}
+
+// Len 文档的字节数
+func (w *Word) Len() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.size
+}
+
+// Bytes 文档内容的副本
+func (w *Word) Bytes() []byte {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.bytes()
+}
+
+// Insert 在 off 处插入 text
+func (w *Word) Insert(off int64, text []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.check("insert", off, 0); err != nil {
+		return err
+	}
+	w.replace(off, 0, text)
+	return nil
+}
+
+// Delete 删除 off 开始的 n 个字节
+func (w *Word) Delete(off, n int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.check("delete", off, n); err != nil {
+		return err
+	}
+	w.replace(off, n, nil)
+	return nil
+}
+
+// Replace 用 text 替换 off 开始的 n 个字节，是一步撤销
+func (w *Word) Replace(off, n int64, text []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.check("replace", off, n); err != nil {
+		return err
+	}
+	w.replace(off, n, text)
+	return nil
+}
+
+// BeginGroup 开始一组修改，和 EndGroup 成对调用，可以嵌套
+func (w *Word) BeginGroup() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.beginGroup()
+}
+
+// EndGroup 结束一组修改，最外层结束时整组成为一步撤销，没有 BeginGroup 时 panic
+func (w *Word) EndGroup() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.endGroup()
+}
+
+// Undo 撤销最近一步修改，没有可以撤销的修改或者分组还没有结束时返回 false
+func (w *Word) Undo() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.group > 0 || len(w.undo) == 0 {
+		return false
+	}
+	step := w.undo[len(w.undo)-1]
+	w.undo = w.undo[:len(w.undo)-1]
+	for i := len(step) - 1; i >= 0; i-- {
+		e := step[i]
+		w.splice(e.off, piecesLen(e.added), e.removed)
+	}
+	w.redo = append(w.redo, step)
+	return true
+}
+
+// Redo 重做最近一步撤销，之后有新的修改时不能再重做
+func (w *Word) Redo() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.group > 0 || len(w.redo) == 0 {
+		return false
+	}
+	step := w.redo[len(w.redo)-1]
+	w.redo = w.redo[:len(w.redo)-1]
+	for _, e := range step {
+		w.splice(e.off, piecesLen(e.removed), e.added)
+	}
+	w.undo = append(w.undo, step)
+	return true
+}
+
+// LineCount 行数，最后一个换行之后即使没有内容也算一行
+func (w *Word) LineCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	lines := 1
+	for _, p := range w.pieces {
+		lines += p.lines
+	}
+	return lines
+}
+
+// Position 偏移量 off 所在的行列，off 可以等于文档长度
+func (w *Word) Position(off int64) (Position, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if off < 0 || off > w.size {
+		return Position{}, fmt.Errorf("position %d: %w", off, ErrOutOfRange)
+	}
+	// 完整的片段只累加换行数，记下最后一个有换行的片段，最后只在这个片段里找换行的位置
+	line, lastNL := 1, int64(-1)
+	last, lastPos := -1, int64(0)
+	var pos int64
+	for i, p := range w.pieces {
+		if pos >= off {
+			break
+		}
+		if pos+p.n > off {
+			b := w.bytesOf(p)[:off-pos]
+			line += bytes.Count(b, []byte{'\n'})
+			if j := bytes.LastIndexByte(b, '\n'); j >= 0 {
+				lastNL, last = pos+int64(j), -1
+			}
+			break
+		}
+		line += p.lines
+		if p.lines > 0 {
+			last, lastPos = i, pos
+		}
+		pos += p.n
+	}
+	if last >= 0 {
+		lastNL = lastPos + int64(bytes.LastIndexByte(w.bytesOf(w.pieces[last]), '\n'))
+	}
+	return Position{Offset: off, Line: line, Column: int(off - lastNL)}, nil
+}
+
+// Offset 第 line 行第 column 列的偏移量，column 可以指向行尾（换行符或者文档末尾）
+func (w *Word) Offset(line, column int) (int64, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	start, end, ok := w.lineBounds(line)
+	if !ok || column < 1 || start+int64(column-1) > end {
+		return 0, fmt.Errorf("offset %d:%d: %w", line, column, ErrOutOfRange)
+	}
+	return start + int64(column-1), nil
+}
+
+// Line 第 line 行的内容，不含换行符
+func (w *Word) Line(line int) ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	start, end, ok := w.lineBounds(line)
+	if !ok {
+		return nil, fmt.Errorf("line %d: %w", line, ErrOutOfRange)
+	}
+	b := make([]byte, end-start)
+	w.read(b, start)
+	return b, nil
+}
+
+// Find 从 from 开始查找 re 的第一个匹配，直接扫描片段不复制文档；^ 和 \A 匹配 from 处
+func (w *Word) Find(re *regexp.Regexp, from int64) (start, end int64, ok bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if from < 0 || from > w.size {
+		return 0, 0, false
+	}
+	r := bufio.NewReader(io.NewSectionReader(wordReaderAt{w}, from, w.size-from))
+	loc := re.FindReaderIndex(r)
+	if loc == nil {
+		return 0, 0, false
+	}
+	return from + int64(loc[0]), from + int64(loc[1]), true
+}
+
+// FindAll 所有不重叠的匹配，n < 0 表示不限个数；需要复制一次文档
+func (w *Word) FindAll(re *regexp.Regexp, n int) [][2]int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var spans [][2]int64
+	for _, m := range re.FindAllIndex(w.bytes(), n) {
+		spans = append(spans, [2]int64{int64(m[0]), int64(m[1])})
+	}
+	return spans
+}
+
+// ReplaceAll 把 re 的所有匹配替换为 repl（支持 $1、${name}），返回替换的个数，整体是一步撤销；需要复制一次文档
+func (w *Word) ReplaceAll(re *regexp.Regexp, repl string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	src := w.bytes()
+	matches := re.FindAllSubmatchIndex(src, -1)
+	if len(matches) == 0 {
+		return 0, nil
+	}
+	w.beginGroup()
+	// 从后往前替换，前面匹配的偏移量不变
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		text := re.Expand(nil, []byte(repl), src, m)
+		w.replace(int64(m[0]), int64(m[1]-m[0]), text)
+	}
+	w.endGroup()
+	return len(matches), nil
+}
+
+// check 检查 [off, off+n) 在文档范围内，调用方持有 w.mu
+func (w *Word) check(op string, off, n int64) error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	if off < 0 || n < 0 || off+n > w.size {
+		return fmt.Errorf("%s %d+%d: %w", op, off, n, ErrOutOfRange)
+	}
+	return nil
+}
+
+// replace 记录一次修改，调用方持有 w.mu 并且已经检查过范围
+func (w *Word) replace(off, n int64, text []byte) {
+	if n == 0 && len(text) == 0 {
+		return
+	}
+	var added []piece
+	if len(text) > 0 {
+		p := piece{add: true, start: int64(len(w.add)), n: int64(len(text)), lines: bytes.Count(text, []byte{'\n'})}
+		w.add = append(w.add, text...)
+		added = []piece{p}
+	}
+	e := edit{off: off, removed: w.splice(off, n, added), added: added}
+	w.redo = nil
+	if w.group > 0 {
+		last := &w.undo[len(w.undo)-1]
+		*last = append(*last, e)
+	} else {
+		w.undo = append(w.undo, []edit{e})
+	}
+}
+
+func (w *Word) beginGroup() {
+	if w.group == 0 {
+		w.undo = append(w.undo, nil)
+	}
+	w.group++
+}
+
+func (w *Word) endGroup() {
+	if w.group == 0 {
+		panic("word: EndGroup without BeginGroup")
+	}
+	w.group--
+	if w.group == 0 && len(w.undo[len(w.undo)-1]) == 0 {
+		w.undo = w.undo[:len(w.undo)-1]
+	}
+}
+
+// splice 用 ins 替换 [off, off+n) 的片段，返回被替换的片段
+func (w *Word) splice(off, n int64, ins []piece) []piece {
+	i := w.split(off)
+	j := w.split(off + n)
+	removed := slices.Clone(w.pieces[i:j])
+	w.size += piecesLen(ins) - piecesLen(removed)
+	// 连续输入时新片段紧接着前一个 add 片段，合并成一个
+	if len(ins) == 1 && i > 0 && i == j {
+		prev := &w.pieces[i-1]
+		if prev.add && ins[0].add && prev.start+prev.n == ins[0].start {
+			prev.n += ins[0].n
+			prev.lines += ins[0].lines
+			ins = nil
+		}
+	}
+	w.pieces = slices.Replace(w.pieces, i, j, ins...)
+	return removed
+}
+
+// split 在 off 处切开片段，返回 off 之后第一个片段的下标
+func (w *Word) split(off int64) int {
+	var pos int64
+	for i, p := range w.pieces {
+		if pos == off {
+			return i
+		}
+		if off < pos+p.n {
+			left := piece{add: p.add, start: p.start, n: off - pos}
+			right := piece{add: p.add, start: p.start + left.n, n: p.n - left.n}
+			// 只数较短的一半
+			if left.n <= right.n {
+				left.lines = bytes.Count(w.bytesOf(left), []byte{'\n'})
+				right.lines = p.lines - left.lines
+			} else {
+				right.lines = bytes.Count(w.bytesOf(right), []byte{'\n'})
+				left.lines = p.lines - right.lines
+			}
+			w.pieces = slices.Replace(w.pieces, i, i+1, left, right)
+			return i + 1
+		}
+		pos += p.n
+	}
+	return len(w.pieces)
+}
+
+func (w *Word) bytesOf(p piece) []byte {
+	if p.add {
+		return w.add[p.start : p.start+p.n]
+	}
+	return w.orig[p.start : p.start+p.n]
+}
+
+func (w *Word) bytes() []byte {
+	b := make([]byte, 0, w.size)
+	for _, p := range w.pieces {
+		b = append(b, w.bytesOf(p)...)
+	}
+	return b
+}
+
+// read 从 off 处读取，读不满 p 时返回 io.EOF，调用方持有 w.mu
+func (w *Word) read(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= w.size {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := 0
+	var pos int64
+	for _, pc := range w.pieces {
+		if n == len(p) {
+			break
+		}
+		if off < pos+pc.n {
+			b := w.bytesOf(pc)
+			if off > pos {
+				b = b[off-pos:]
+			}
+			n += copy(p[n:], b)
+			off = pos + pc.n
+		}
+		pos += pc.n
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// lineBounds 第 line 行的起止偏移量，end 是换行符或者文档末尾的位置
+func (w *Word) lineBounds(line int) (start, end int64, ok bool) {
+	if line < 1 {
+		return 0, 0, false
+	}
+	start, ok = w.nthNewline(line - 1)
+	if !ok {
+		return 0, 0, false
+	}
+	end, ok = w.nthNewline(line)
+	if !ok {
+		end = w.size
+	} else {
+		end--
+	}
+	return start, end, true
+}
+
+// nthNewline 第 k 个换行符之后的偏移量，k 为 0 时是文档开头
+func (w *Word) nthNewline(k int) (int64, bool) {
+	if k == 0 {
+		return 0, true
+	}
+	var pos int64
+	for _, p := range w.pieces {
+		if k > p.lines {
+			k -= p.lines
+			pos += p.n
+			continue
+		}
+		b := w.bytesOf(p)
+		for i := 0; ; i++ {
+			j := bytes.IndexByte(b[i:], '\n')
+			i += j
+			if k--; k == 0 {
+				return pos + int64(i) + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func piecesLen(ps []piece) int64 {
+	var n int64
+	for _, p := range ps {
+		n += p.n
+	}
+	return n
+}
+
+// wordReaderAt 不加锁的 ReaderAt，调用方持有 w.mu
+type wordReaderAt struct {
+	w *Word
+}
+
+func (r wordReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.w.read(p, off)
+}
@@ -0,0 +1,217 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWordEdit(t *testing.T) {
+	w := NewWord([]byte("hello world\nsecond line\n"))
+	steps := []struct {
+		op   func() error
+		want string
+	}{
+		{func() error { return w.Insert(5, []byte(",")) }, "hello, world\nsecond line\n"},
+		{func() error { return w.Delete(0, 7) }, "world\nsecond line\n"},
+		{func() error { return w.Insert(w.Len(), []byte("third")) }, "world\nsecond line\nthird"},
+		{func() error { return w.Replace(6, 6, []byte("2nd")) }, "world\n2nd line\nthird"},
+		{func() error { return w.Insert(0, nil) }, "world\n2nd line\nthird"},
+	}
+	for i, s := range steps {
+		if err := s.op(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got := string(w.Bytes()); got != s.want {
+			t.Fatalf("step %d: got %q, want %q", i, got, s.want)
+		}
+	}
+
+	for _, err := range []error{
+		w.Insert(-1, []byte("x")),
+		w.Insert(w.Len()+1, []byte("x")),
+		w.Delete(3, w.Len()),
+		w.Replace(0, -1, nil),
+	} {
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("got %v, want ErrOutOfRange", err)
+		}
+	}
+
+	// File 的 Write 是覆盖写入
+	w.Seek(0, io.SeekStart)
+	io.WriteString(w, "WORLD")
+	w.Seek(-5, io.SeekEnd)
+	io.WriteString(w, "THIRD!")
+	if got := string(w.Bytes()); got != "WORLD\n2nd line\nTHIRD!" {
+		t.Errorf("after Write: %q", got)
+	}
+	w.Close()
+	if err := w.Insert(0, []byte("x")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("insert after close: %v", err)
+	}
+}
+
+func TestWordUndo(t *testing.T) {
+	var w Word
+	// 逐字输入，每个字符一步
+	for i, c := range "abc" {
+		w.Insert(int64(i), []byte{byte(c)})
+	}
+	w.BeginGroup()
+	w.Insert(3, []byte(" def"))
+	w.BeginGroup()
+	w.Delete(0, 1)
+	w.EndGroup()
+	if w.Undo() {
+		t.Error("undo inside a group")
+	}
+	w.EndGroup()
+	if got := string(w.Bytes()); got != "bc def" {
+		t.Fatalf("content = %q", got)
+	}
+
+	history := []string{"abc", "ab", "a", ""}
+	for _, want := range history {
+		if !w.Undo() {
+			t.Fatal("nothing to undo")
+		}
+		if got := string(w.Bytes()); got != want {
+			t.Errorf("undo: got %q, want %q", got, want)
+		}
+	}
+	if w.Undo() {
+		t.Error("undo past the beginning")
+	}
+	for _, want := range []string{"a", "ab"} {
+		w.Redo()
+		if got := string(w.Bytes()); got != want {
+			t.Errorf("redo: got %q, want %q", got, want)
+		}
+	}
+	// 新的修改清空重做
+	w.Insert(2, []byte("!"))
+	if w.Redo() {
+		t.Error("redo after a new edit")
+	}
+	w.BeginGroup()
+	w.EndGroup()
+	w.Undo()
+	if got := string(w.Bytes()); got != "ab" {
+		t.Errorf("empty group became an undo step: %q", got)
+	}
+}
+
+func TestWordLines(t *testing.T) {
+	w := NewWord([]byte("one\ntwo\n\nfour"))
+	// 让行跨越多个片段
+	w.Insert(5, []byte("w\no"))
+	w.Delete(6, 3)
+	const want = "one\ntwo\n\nfour"
+	if got := string(w.Bytes()); got != want {
+		t.Fatalf("content = %q", got)
+	}
+	if n := w.LineCount(); n != 4 {
+		t.Errorf("LineCount = %d", n)
+	}
+	for off := int64(0); off <= w.Len(); off++ {
+		pos, err := w.Position(off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := w.Offset(pos.Line, pos.Column)
+		if err != nil || back != off {
+			t.Errorf("Position(%d) = %v, Offset = %d, %v", off, pos, back, err)
+		}
+	}
+	// 最后一个换行之后跟着几个没有换行的片段
+	w2 := NewWord([]byte("ab\ncd"))
+	w2.Insert(5, []byte("ef"))
+	w2.Insert(3, []byte("gh"))
+	w2.Insert(0, []byte("\n"))
+	data := w2.Bytes()
+	for off := int64(0); off <= w2.Len(); off++ {
+		line := 1 + bytes.Count(data[:off], []byte{'\n'})
+		column := int(off) - bytes.LastIndexByte(data[:off], '\n')
+		if pos, _ := w2.Position(off); pos.Line != line || pos.Column != column {
+			t.Errorf("Position(%d) in %q = %v, want %d:%d", off, data, pos, line, column)
+		}
+	}
+	if pos, _ := w.Position(8); pos.String() != "3:1" {
+		t.Errorf("Position(8) = %v", pos)
+	}
+	if pos, _ := w.Position(w.Len()); pos.String() != "4:5" {
+		t.Errorf("Position(end) = %v", pos)
+	}
+	for i, want := range strings.Split(want, "\n") {
+		if got, err := w.Line(i + 1); err != nil || string(got) != want {
+			t.Errorf("Line(%d) = %q, %v", i+1, got, err)
+		}
+	}
+	for _, lc := range [][2]int{{0, 1}, {1, 0}, {1, 5}, {5, 1}, {3, 2}} {
+		if _, err := w.Offset(lc[0], lc[1]); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Offset(%d, %d): %v", lc[0], lc[1], err)
+		}
+	}
+	if _, err := w.Position(w.Len() + 1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Position past end: %v", err)
+	}
+}
+
+func TestWordSearch(t *testing.T) {
+	w := NewWord([]byte("price: 10 USD, tax: 2 USD"))
+	w.Insert(7, []byte("1"))
+	re := regexp.MustCompile(`(\d+) USD`)
+	if start, end, ok := w.Find(re, 0); !ok || start != 7 || end != 14 {
+		t.Errorf("Find = %d, %d, %v", start, end, ok)
+	}
+	if start, _, ok := w.Find(re, 8); !ok || start != 8 {
+		t.Errorf("Find from 8 = %d, %v", start, ok)
+	}
+	if _, _, ok := w.Find(regexp.MustCompile("EUR"), 0); ok {
+		t.Error("Find EUR")
+	}
+	if spans := w.FindAll(re, -1); len(spans) != 2 || spans[1] != [2]int64{21, 26} {
+		t.Errorf("FindAll = %v", spans)
+	}
+
+	n, err := w.ReplaceAll(re, "€$1")
+	if err != nil || n != 2 || string(w.Bytes()) != "price: €110, tax: €2" {
+		t.Errorf("ReplaceAll = %d, %v: %q", n, err, w.Bytes())
+	}
+	// 一次 ReplaceAll 是一步撤销
+	w.Undo()
+	if got := string(w.Bytes()); got != "price: 110 USD, tax: 2 USD" {
+		t.Errorf("undo ReplaceAll: %q", got)
+	}
+}
+
+// 在几 MB 的文档中连续输入，片段数只和编辑位置的个数有关
+func TestWordLargeDocument(t *testing.T) {
+	line := []byte(strings.Repeat("x", 79) + "\n")
+	orig := bytes.Repeat(line, 50000)
+	w := NewWord(orig)
+	mid := int64(len(orig) / 2)
+	for i := int64(0); i < 1000; i++ {
+		w.Insert(mid+i, []byte("y"))
+	}
+	if len(w.pieces) != 3 {
+		t.Errorf("%d pieces after typing in one place", len(w.pieces))
+	}
+	if &w.orig[0] != &orig[0] {
+		t.Error("original buffer was copied")
+	}
+	pos, _ := w.Position(mid + 1000)
+	if pos.Line != 25001 || pos.Column != 1001 {
+		t.Errorf("Position = %v", pos)
+	}
+	for w.Undo() {
+	}
+	if !bytes.Equal(w.Bytes(), orig) {
+		t.Error("undo did not restore the original")
+	}
+}
@@ -0,0 +1,491 @@
+package demo11_interface
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 由 Task 组成的工作流
+/*
+1:每个任务有一个 id 和它依赖的任务（needs），依赖必须存在并且不能成环，Validate 在运行前检查。
+2:依赖全部成功的任务可以并行运行，同时运行的任务数不超过 Concurrency。
+3:默认 fail-fast：一个任务失败后取消正在运行的任务，不再启动新任务；
+  ContinueOnFailure 为 true 时只跳过（直接或间接）依赖失败任务的任务，其他任务照常运行。
+4:设置了 StateFile 时每个任务结束后把状态写进这个 JSON 文件。Resume 读取它，跳过上次已经成功、
+  定义没有变化并且依赖也都被跳过的任务，从失败或者没有运行的任务继续。
+5:Workflow 和 Task 一样嵌入 *log.Logger，没有自己 Logger 的任务使用工作流的 Logger。
+*/
+
+// TaskStatus 工作流中任务的状态
+type TaskStatus string
+
+const (
+	StatusPending   TaskStatus = "pending"
+	StatusSucceeded TaskStatus = "succeeded"
+	StatusFailed    TaskStatus = "failed"
+	// StatusCanceled 运行中被 fail-fast 或者 ctx 取消
+	StatusCanceled TaskStatus = "canceled"
+	// StatusSkipped 依赖没有成功，或者停止后没有启动
+	StatusSkipped TaskStatus = "skipped"
+	// StatusReused 上次运行已经成功，Resume 时没有再运行
+	StatusReused TaskStatus = "reused"
+
+	statusRunning TaskStatus = "running"
+)
+
+// CycleError 任务之间的依赖成环
+type CycleError struct {
+	// Path 环上的任务，首尾相同
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "workflow: dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// WorkflowError 有任务没有成功
+type WorkflowError struct {
+	Workflow string
+	// Failed 失败或者被取消的任务
+	Failed []string
+	Errs   []error
+}
+
+func (e *WorkflowError) Error() string {
+	return fmt.Sprintf("workflow %s: %d task(s) failed: %s", e.Workflow, len(e.Failed), strings.Join(e.Failed, ", "))
+}
+
+func (e *WorkflowError) Unwrap() []error {
+	return e.Errs
+}
+
+type workflowNode struct {
+	id    string
+	task  *Task
+	needs []string
+}
+
+// fingerprint 影响任务结果的定义，变化后 Resume 会重新运行这个任务
+func (n *workflowNode) fingerprint() string {
+	h := sha256.New()
+	for _, s := range [][]string{{n.task.Command}, n.task.Args, {n.task.Dir}, n.task.Env, n.needs} {
+		for _, v := range s {
+			io.WriteString(h, v)
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Workflow 由有依赖关系的 Task 组成的有向无环图
+type Workflow struct {
+	Name string
+	*log.Logger
+	// Concurrency 同时运行的任务数上限，小于 1 时使用 GOMAXPROCS
+	Concurrency int
+	// ContinueOnFailure 任务失败后继续运行不依赖它的任务
+	ContinueOnFailure bool
+	// StateFile 保存任务状态的文件，为空时不保存
+	StateFile string
+
+	nodes map[string]*workflowNode
+	ids   []string
+}
+
+func NewWorkflow(name string, logger *log.Logger) *Workflow {
+	return &Workflow{Name: name, Logger: logger, nodes: make(map[string]*workflowNode)}
+}
+
+// Add 添加任务，needs 可以引用之后才添加的任务
+func (w *Workflow) Add(id string, task *Task, needs ...string) error {
+	switch {
+	case id == "":
+		return errors.New("workflow: empty task id")
+	case task == nil:
+		return fmt.Errorf("workflow: task %s is nil", id)
+	case w.nodes[id] != nil:
+		return fmt.Errorf("workflow: duplicate task %s", id)
+	}
+	if w.nodes == nil {
+		w.nodes = make(map[string]*workflowNode)
+	}
+	w.nodes[id] = &workflowNode{id: id, task: task, needs: needs}
+	w.ids = append(w.ids, id)
+	return nil
+}
+
+// Validate 检查依赖是否存在、是否成环
+func (w *Workflow) Validate() error {
+	_, err := w.order()
+	return err
+}
+
+// order 按依赖排序的任务 id，依赖排在前面，其他情况保持添加的顺序
+func (w *Workflow) order() ([]string, error) {
+	for _, id := range w.ids {
+		for _, dep := range w.nodes[id].needs {
+			if w.nodes[dep] == nil {
+				return nil, fmt.Errorf("workflow: task %s needs unknown task %s", id, dep)
+			}
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	color := make(map[string]int, len(w.ids))
+	order := make([]string, 0, len(w.ids))
+	var stack []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch color[id] {
+		case visited:
+			return nil
+		case visiting:
+			i := len(stack) - 1
+			for stack[i] != id {
+				i--
+			}
+			return &CycleError{Path: append(append([]string(nil), stack[i:]...), id)}
+		}
+		color[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range w.nodes[id].needs {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[id] = visited
+		order = append(order, id)
+		return nil
+	}
+	for _, id := range w.ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// NodeResult 一个任务在工作流中的结果
+type NodeResult struct {
+	ID     string
+	Status TaskStatus
+	// Result 任务运行的结果，没有运行时为 nil
+	Result *TaskResult
+	Err    error
+}
+
+// WorkflowResult Run 和 Resume 的结果
+type WorkflowResult struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	// Nodes 按依赖排序
+	Nodes []NodeResult
+}
+
+// Node 返回 id 对应的结果
+func (r *WorkflowResult) Node(id string) (NodeResult, bool) {
+	for _, n := range r.Nodes {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	return NodeResult{}, false
+}
+
+// Count 状态是 status 的任务数
+func (r *WorkflowResult) Count(status TaskStatus) int {
+	n := 0
+	for _, node := range r.Nodes {
+		if node.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteSummary 输出一行总计和每个任务的状态、尝试次数、退出码和耗时
+func (r *WorkflowResult) WriteSummary(w io.Writer) error {
+	var counts []string
+	for _, s := range []TaskStatus{StatusSucceeded, StatusReused, StatusFailed, StatusCanceled, StatusSkipped} {
+		if n := r.Count(s); n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, s))
+		}
+	}
+	fmt.Fprintf(w, "workflow %s: %s in %v\n", r.Name, strings.Join(counts, ", "), r.Duration.Round(time.Millisecond))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tSTATUS\tATTEMPTS\tEXIT\tDURATION\tERROR")
+	for _, n := range r.Nodes {
+		attempts, exit, dur := "-", "-", "-"
+		if n.Result != nil {
+			attempts = fmt.Sprint(len(n.Result.Attempts))
+			exit = fmt.Sprint(n.Result.ExitCode)
+			dur = n.Result.Duration.Round(time.Millisecond).String()
+		}
+		msg := ""
+		if n.Err != nil {
+			msg = n.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", n.ID, n.Status, attempts, exit, dur, msg)
+	}
+	return tw.Flush()
+}
+
+// Run 运行所有任务，有任务失败时返回 *WorkflowError
+func (w *Workflow) Run(ctx context.Context) (*WorkflowResult, error) {
+	return w.run(ctx, nil)
+}
+
+// Resume 读取 StateFile，从上次失败或者没有运行的任务继续；状态文件不存在时运行所有任务
+func (w *Workflow) Resume(ctx context.Context) (*WorkflowResult, error) {
+	if w.StateFile == "" {
+		return nil, fmt.Errorf("workflow %s: resume needs a state file", w.Name)
+	}
+	prev, err := readWorkflowState(w.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		w.logger().Printf("workflow %s: no state in %s, running all tasks", w.Name, w.StateFile)
+		return w.run(ctx, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prev.Workflow != w.Name {
+		return nil, fmt.Errorf("workflow %s: state file %s belongs to workflow %s", w.Name, w.StateFile, prev.Workflow)
+	}
+	return w.run(ctx, prev)
+}
+
+func (w *Workflow) logger() *log.Logger {
+	if w.Logger == nil {
+		return log.New(io.Discard, "", 0)
+	}
+	return w.Logger
+}
+
+type nodeDone struct {
+	i   int
+	res *TaskResult
+	err error
+}
+
+func (w *Workflow) run(ctx context.Context, prev *workflowState) (*WorkflowResult, error) {
+	order, err := w.order()
+	if err != nil {
+		return nil, err
+	}
+	logger := w.logger()
+	res := &WorkflowResult{Name: w.Name, Start: time.Now(), Nodes: make([]NodeResult, len(order))}
+	index := make(map[string]int, len(order))
+	state := &workflowState{Workflow: w.Name, Tasks: make(map[string]taskState, len(order))}
+	for i, id := range order {
+		index[id] = i
+		res.Nodes[i] = NodeResult{ID: id, Status: StatusPending}
+		if prev == nil {
+			continue
+		}
+		node := w.nodes[id]
+		s, ok := prev.Tasks[id]
+		if !ok || s.Status != StatusSucceeded || s.Fingerprint != node.fingerprint() {
+			continue
+		}
+		reuse := true
+		for _, dep := range node.needs {
+			reuse = reuse && res.Nodes[index[dep]].Status == StatusReused
+		}
+		if reuse {
+			res.Nodes[i].Status = StatusReused
+			state.Tasks[id] = s
+		}
+	}
+	if prev != nil {
+		logger.Printf("workflow %s: resuming, %d of %d task(s) reused", w.Name, len(state.Tasks), len(order))
+	}
+
+	limit := w.Concurrency
+	if limit < 1 {
+		limit = runtime.GOMAXPROCS(0)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan nodeDone)
+	running := 0
+	stopped := false
+	var failed []string
+	var errs []error
+	for {
+		// 按依赖顺序遍历，失败会一路传给后面依赖它的任务
+		for i := range res.Nodes {
+			n := &res.Nodes[i]
+			if n.Status != StatusPending {
+				continue
+			}
+			ready := true
+			for _, dep := range w.nodes[n.ID].needs {
+				switch s := res.Nodes[index[dep]].Status; s {
+				case StatusSucceeded, StatusReused:
+				case StatusFailed, StatusCanceled, StatusSkipped:
+					if n.Status == StatusPending {
+						n.Status = StatusSkipped
+						n.Err = fmt.Errorf("dependency %s %s", dep, s)
+					}
+					ready = false
+				default:
+					ready = false
+				}
+			}
+			if n.Status == StatusSkipped {
+				state.Tasks[n.ID] = taskState{Status: StatusSkipped, Fingerprint: w.nodes[n.ID].fingerprint(), Error: n.Err.Error()}
+				continue
+			}
+			if !ready || stopped || running == limit {
+				continue
+			}
+			n.Status = statusRunning
+			running++
+			task := *w.nodes[n.ID].task
+			if task.Name == "" {
+				task.Name = n.ID
+			}
+			if task.Logger == nil {
+				task.Logger = w.Logger
+			}
+			logger.Printf("workflow %s: start %s", w.Name, n.ID)
+			go func(i int) {
+				r, err := task.Run(runCtx)
+				done <- nodeDone{i, r, err}
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+
+		d := <-done
+		running--
+		n := &res.Nodes[d.i]
+		n.Result, n.Err = d.res, d.err
+		switch {
+		case d.err == nil:
+			n.Status = StatusSucceeded
+		case errors.Is(d.err, context.Canceled):
+			n.Status = StatusCanceled
+		default:
+			n.Status = StatusFailed
+		}
+		if d.err != nil {
+			failed = append(failed, n.ID)
+			errs = append(errs, d.err)
+			if !w.ContinueOnFailure && !stopped {
+				stopped = true
+				cancel()
+			}
+		}
+		if ctx.Err() != nil {
+			stopped = true
+		}
+		logger.Printf("workflow %s: %s %s", w.Name, n.ID, n.Status)
+		s := taskState{Status: n.Status, Fingerprint: w.nodes[n.ID].fingerprint(), Finished: time.Now()}
+		if d.res != nil {
+			s.ExitCode, s.Attempts = d.res.ExitCode, len(d.res.Attempts)
+		}
+		if d.err != nil {
+			s.Error = d.err.Error()
+		}
+		state.Tasks[n.ID] = s
+		if err := w.saveState(state); err != nil {
+			logger.Printf("workflow %s: %v", w.Name, err)
+		}
+	}
+	for i := range res.Nodes {
+		if n := &res.Nodes[i]; n.Status == StatusPending {
+			n.Status = StatusSkipped
+			n.Err = errors.New("not started")
+		}
+	}
+	if err := w.saveState(state); err != nil {
+		logger.Printf("workflow %s: %v", w.Name, err)
+	}
+	res.Duration = time.Since(res.Start)
+	logger.Printf("workflow %s: %d succeeded, %d reused, %d failed, %d canceled, %d skipped in %v", w.Name,
+		res.Count(StatusSucceeded), res.Count(StatusReused), res.Count(StatusFailed), res.Count(StatusCanceled),
+		res.Count(StatusSkipped), res.Duration.Round(time.Millisecond))
+
+	if err := ctx.Err(); err != nil {
+		return res, fmt.Errorf("workflow %s: %w", w.Name, err)
+	}
+	if len(failed) > 0 {
+		return res, &WorkflowError{Workflow: w.Name, Failed: failed, Errs: errs}
+	}
+	return res, nil
+}
+
+// taskState 状态文件中一个任务的记录
+type taskState struct {
+	Status      TaskStatus `json:"status"`
+	Fingerprint string     `json:"fingerprint"`
+	ExitCode    int        `json:"exitCode"`
+	Attempts    int        `json:"attempts"`
+	Finished    time.Time  `json:"finished"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type workflowState struct {
+	Workflow string               `json:"workflow"`
+	Tasks    map[string]taskState `json:"tasks"`
+}
+
+func readWorkflowState(path string) (*workflowState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s workflowState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("workflow state %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// saveState 先写临时文件再改名，中途退出也不会留下不完整的状态文件
+func (w *Workflow) saveState(s *workflowState) error {
+	if w.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(w.StateFile), filepath.Base(w.StateFile)+".*")
+	if err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.StateFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("save state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 工作流文件
+/*
+1:JSON 或者 YAML 的一个子集，第一个非空白字符是 { 时按 JSON 解析。两种格式的字段相同：
+
+	name: build
+	concurrency: 2
+	continueOnFailure: false
+	tasks:
+	  - id: test
+	    command: go test ./...
+	    needs: [vet]
+	    dir: ..
+	    env:
+	      - CGO_ENABLED=0
+	    timeout: 5m
+	    retries: 2
+	    backoff: 1s
+	    maxBackoff: 10s
+	    jitter: 0.2
+
+2:YAML 子集只支持缩进的映射和列表、[a, b] 形式的行内列表、单双引号字符串和 # 注释，
+  不支持多行字符串、锚点和 {} 形式的行内映射。未加引号的数字、true、false 不是字符串。
+3:retries 是失败后重试的次数，timeout、backoff、maxBackoff 是 time.ParseDuration 的格式。
+4:LoadWorkflow 把相对的 dir（包括空的 dir）解释为相对于工作流文件所在的目录。
+*/
+
+type workflowSpec struct {
+	Name              string     `json:"name"`
+	Concurrency       int        `json:"concurrency"`
+	ContinueOnFailure bool       `json:"continueOnFailure"`
+	Tasks             []taskSpec `json:"tasks"`
+}
+
+type taskSpec struct {
+	ID         string       `json:"id"`
+	Command    string       `json:"command"`
+	Args       []string     `json:"args"`
+	Needs      []string     `json:"needs"`
+	Dir        string       `json:"dir"`
+	Env        []string     `json:"env"`
+	Timeout    specDuration `json:"timeout"`
+	Retries    int          `json:"retries"`
+	Backoff    specDuration `json:"backoff"`
+	MaxBackoff specDuration `json:"maxBackoff"`
+	Jitter     float64      `json:"jitter"`
+}
+
+// specDuration 写成 "1m30s" 这样字符串的 time.Duration
+type specDuration time.Duration
+
+func (d *specDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = specDuration(v)
+	return nil
+}
+
+// LoadWorkflow 读取工作流文件
+func LoadWorkflow(path string, logger *log.Logger) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := ParseWorkflow(data, logger)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// 使用绝对路径，从别的目录运行时任务的定义（以及 Resume 用的指纹）不变
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range w.nodes {
+		if !filepath.IsAbs(n.task.Dir) {
+			n.task.Dir = filepath.Join(base, n.task.Dir)
+		}
+	}
+	return w, nil
+}
+
+// ParseWorkflow 解析 JSON 或者 YAML 子集格式的工作流，并检查依赖
+func ParseWorkflow(data []byte, logger *log.Logger) (*Workflow, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		v, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("workflow: %w", err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var spec workflowSpec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("workflow: %w", err)
+	}
+	if spec.Name == "" {
+		return nil, errors.New("workflow: missing name")
+	}
+
+	w := NewWorkflow(spec.Name, logger)
+	w.Concurrency = spec.Concurrency
+	w.ContinueOnFailure = spec.ContinueOnFailure
+	for _, ts := range spec.Tasks {
+		task := &Task{
+			Command: ts.Command,
+			Name:    ts.ID,
+			Args:    ts.Args,
+			Dir:     ts.Dir,
+			Env:     ts.Env,
+			Timeout: time.Duration(ts.Timeout),
+			Retry: RetryPolicy{
+				MaxAttempts:    ts.Retries + 1,
+				InitialBackoff: time.Duration(ts.Backoff),
+				MaxBackoff:     time.Duration(ts.MaxBackoff),
+				Jitter:         ts.Jitter,
+			},
+		}
+		if ts.Command == "" {
+			return nil, fmt.Errorf("workflow: task %s: missing command", ts.ID)
+		}
+		if err := w.Add(ts.ID, task, ts.Needs...); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// yamlLine 去掉注释后的一个非空行
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+// parseYAML 把 YAML 子集解析成 map[string]any、[]any 和标量
+func parseYAML(data []byte) (any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("workflow: line %d: tabs are not allowed for indentation", i+1)
+		}
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		if text == "" {
+			continue
+		}
+		lines = append(lines, yamlLine{no: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(lines) {
+		return nil, fmt.Errorf("workflow: line %d: unexpected indentation", lines[p.i].no)
+	}
+	return v, nil
+}
+
+// stripYAMLComment 去掉引号外面、行首或者空白之后的 # 注释
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block 解析从当前行开始、缩进为 indent 的映射或者列表
+func (p *yamlParser) block(indent int) (any, error) {
+	if isListItem(p.lines[p.i].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+// nested 解析 key: 或者 - 后面换行的值，没有更深缩进的行时值为 null
+func (p *yamlParser) nested(indent int, allowSameIndentList bool) (any, error) {
+	if p.i == len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.i]
+	switch {
+	case next.indent > indent:
+		return p.block(next.indent)
+	case next.indent == indent && allowSameIndentList && isListItem(next.text):
+		// tasks:
+		// - id: a
+		return p.list(indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("workflow: line %d: unexpected indentation", line.no)
+		}
+		if isListItem(line.text) {
+			return nil, fmt.Errorf("workflow: line %d: list item in a mapping", line.no)
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("workflow: line %d: expected key: value", line.no)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("workflow: line %d: duplicate key %s", line.no, key)
+		}
+		p.i++
+		var v any
+		var err error
+		if rest == "" {
+			v, err = p.nested(indent, true)
+		} else {
+			v, err = yamlValue(rest)
+		}
+		if err != nil {
+			return nil, annotateYAML(err, line.no)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (p *yamlParser) list(indent int) ([]any, error) {
+	l := []any{}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent < indent || line.indent == indent && !isListItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("workflow: line %d: unexpected indentation", line.no)
+		}
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var v any
+		var err error
+		switch _, _, isKey := splitYAMLKey(item); {
+		case item == "":
+			p.i++
+			v, err = p.nested(indent, false)
+		case isKey && item[0] != '[' && item[0] != '{':
+			// - id: a 开始一个映射，后面的键和 id 对齐
+			p.lines[p.i] = yamlLine{no: line.no, indent: indent + len(line.text) - len(item), text: item}
+			v, err = p.mapping(p.lines[p.i].indent)
+		default:
+			p.i++
+			v, err = yamlValue(item)
+		}
+		if err != nil {
+			return nil, annotateYAML(err, line.no)
+		}
+		l = append(l, v)
+	}
+	return l, nil
+}
+
+// splitYAMLKey 按第一个引号外的 ": "（或者行尾的 :）切分键和值
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '"' || text[0] == '\'' {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// yamlValue 解析一行里的值：行内列表或者标量
+func yamlValue(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, "{"):
+		return nil, errors.New("flow mappings are not supported")
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated list %s", s)
+		}
+		l := []any{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return l, nil
+		}
+		for _, item := range splitYAMLFlow(inner) {
+			v, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	}
+	return yamlScalar(s)
+}
+
+// splitYAMLFlow 按引号外的逗号切分行内列表
+func splitYAMLFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+func yamlScalar(s string) (any, error) {
+	switch {
+	case s == "":
+		return "", nil
+	case s[0] == '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double-quoted string %s", s)
+		}
+		return v, nil
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' || strings.Contains(strings.ReplaceAll(s[1:len(s)-1], "''", ""), "'") {
+			return nil, fmt.Errorf("invalid single-quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s == "true", s == "false":
+		return s == "true", nil
+	case s == "null", s == "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	// inf、nan 这样的写法保留为字符串
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f, nil
+	}
+	return s, nil
+}
+
+// annotateYAML 给没有行号的错误加上行号
+func annotateYAML(err error, line int) error {
+	if strings.HasPrefix(err.Error(), "workflow: ") {
+		return err
+	}
+	return fmt.Errorf("workflow: line %d: %w", line, err)
+}
@@ -0,0 +1,119 @@
+package demo11_interface
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"", map[string]any{}},
+		{"a: 1\nb: x y # comment\nc: 'it''s'\nd: \"a#b\\n\"\ne: true\nf: 0.5\ng:\nh: inf",
+			map[string]any{"a": int64(1), "b": "x y", "c": "it's", "d": "a#b\n", "e": true, "f": 0.5, "g": nil, "h": "inf"}},
+		{"- a\n- [b, 'c, d', \"\"]\n-\n  - e\n- []",
+			[]any{"a", []any{"b", "c, d", ""}, []any{"e"}, []any{}}},
+		{"list:\n- k: v\n  n: 2\n-   k: w\nafter: url: http://x",
+			map[string]any{"list": []any{map[string]any{"k": "v", "n": int64(2)}, map[string]any{"k": "w"}}, "after": "url: http://x"}},
+		{"outer:\n  inner:\n    - x\n  other: y\n",
+			map[string]any{"outer": map[string]any{"inner": []any{"x"}, "other": "y"}}},
+		// 引号里的 ": " 不是键值分隔符
+		{"args:\n  - -c\n  - echo \"key: value\"\n  - 'a: b'\nk: echo 'x: y' # c: d",
+			map[string]any{"args": []any{"-c", `echo "key: value"`, "a: b"}, "k": "echo 'x: y'"}},
+	}
+	for _, tt := range tests {
+		got, err := parseYAML([]byte(tt.in))
+		if err != nil {
+			t.Errorf("parseYAML(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseYAML(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"a: 1\na: 2", "line 2: duplicate key a"},
+		{"a: 1\n- b", "line 2: list item in a mapping"},
+		{"a: 1\njust text", "line 2: expected key: value"},
+		{"a: {b: 1}", "line 1: flow mappings are not supported"},
+		{"a: [b", "line 1: unterminated list"},
+		{"a: 'b", "line 1: invalid single-quoted string"},
+		{"a:\n\t- b", "line 2: tabs are not allowed"},
+	}
+	for _, tt := range tests {
+		_, err := parseYAML([]byte(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseYAML(%q) = %v, want error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestLoadWorkflow(t *testing.T) {
+	yml, err := LoadWorkflow(filepath.Join("testdata", "workflow.yaml"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := LoadWorkflow(filepath.Join("testdata", "workflow.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []*Workflow{yml, js} {
+		if w.Name != "build" || w.Concurrency != 2 || w.ContinueOnFailure || !reflect.DeepEqual(w.ids, []string{"vet", "test", "report"}) {
+			t.Fatalf("workflow = %+v", w)
+		}
+		test := w.nodes["test"].task
+		want := &Task{
+			Command: "go test -count=1 ./...",
+			Name:    "test",
+			Dir:     filepath.Dir(base),
+			Env:     []string{"CGO_ENABLED=0", "GOFLAGS=-mod=mod"},
+			Timeout: 5 * time.Minute,
+			Retry:   RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Jitter: 0.2},
+		}
+		if !reflect.DeepEqual(test, want) {
+			t.Errorf("test task = %+v, want %+v", test, want)
+		}
+		if dir := w.nodes["vet"].task.Dir; dir != base {
+			t.Errorf("vet dir = %q, want %q", dir, base)
+		}
+		report := w.nodes["report"]
+		if !reflect.DeepEqual(report.task.Args, []string{"-c", `echo "tests #passed"`}) || !reflect.DeepEqual(report.needs, []string{"test", "vet"}) {
+			t.Errorf("report = %+v %v", report.task, report.needs)
+		}
+	}
+}
+
+func TestParseWorkflowErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"tasks: []", "missing name"},
+		{"name: x\nconcurrency: 2\nretries: 1", `unknown field "retries"`},
+		{"name: x\ntasks:\n  - id: a\n    timeout: 5", "duration must be a string"},
+		{"name: x\ntasks:\n  - id: a", "task a: missing command"},
+		{"name: x\ntasks:\n  - id: a\n    command: echo\n  - id: a\n    command: echo", "duplicate task a"},
+		{`{"name": "x", "tasks": [{"id": "a", "command": "true", "needs": ["b"]}]}`, "needs unknown task b"},
+		{"name: x\ntasks:\n  - {id: a}", "flow mappings are not supported"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWorkflow([]byte(tt.in), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseWorkflow(%q) = %v, want error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
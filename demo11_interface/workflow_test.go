@@ -0,0 +1,235 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shTask 在 dir 中用 sh -c 运行 script
+func shTask(dir, script string) *Task {
+	return &Task{Command: "sh", Args: []string{"-c", script}, Dir: dir}
+}
+
+func mustAdd(t *testing.T, w *Workflow, id string, task *Task, needs ...string) {
+	t.Helper()
+	if err := w.Add(id, task, needs...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func statuses(res *WorkflowResult) map[string]TaskStatus {
+	m := make(map[string]TaskStatus, len(res.Nodes))
+	for _, n := range res.Nodes {
+		m[n.ID] = n.Status
+	}
+	return m
+}
+
+func checkStatuses(t *testing.T, res *WorkflowResult, want map[string]TaskStatus) {
+	t.Helper()
+	got := statuses(res)
+	for id, s := range want {
+		if got[id] != s {
+			t.Errorf("task %s: status %s, want %s", id, got[id], s)
+		}
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	w := NewWorkflow("v", nil)
+	mustAdd(t, w, "a", NewTask("true", nil), "c")
+	mustAdd(t, w, "b", NewTask("true", nil), "a")
+	mustAdd(t, w, "c", NewTask("true", nil), "b")
+	mustAdd(t, w, "d", NewTask("true", nil))
+	var cycle *CycleError
+	if err := w.Validate(); !errors.As(err, &cycle) || !slices.Equal(cycle.Path, []string{"a", "c", "b", "a"}) {
+		t.Errorf("Validate = %v, want cycle a -> c -> b -> a", err)
+	}
+	if _, err := w.Run(context.Background()); !errors.As(err, &cycle) {
+		t.Errorf("Run on cyclic workflow = %v", err)
+	}
+
+	w = NewWorkflow("v", nil)
+	mustAdd(t, w, "a", NewTask("true", nil), "missing")
+	if err := w.Validate(); err == nil || !strings.Contains(err.Error(), "unknown task missing") {
+		t.Errorf("Validate = %v, want unknown task", err)
+	}
+	if err := w.Add("a", NewTask("true", nil)); err == nil {
+		t.Error("duplicate id: want error")
+	}
+	if err := w.Add("", NewTask("true", nil)); err == nil {
+		t.Error("empty id: want error")
+	}
+
+	w = NewWorkflow("v", nil)
+	mustAdd(t, w, "self", NewTask("true", nil), "self")
+	if err := w.Validate(); !errors.As(err, &cycle) || !slices.Equal(cycle.Path, []string{"self", "self"}) {
+		t.Errorf("Validate = %v, want self cycle", err)
+	}
+}
+
+func TestWorkflowParallel(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	// b 和 c 互相等待对方创建的文件，只有并行运行才能成功
+	wait := func(self, other string) string {
+		return "touch " + self + "; i=0; while [ ! -f " + other + " ]; do sleep 0.01; i=$((i+1)); [ $i -gt 300 ] && exit 1; done; exit 0"
+	}
+	var out syncBuffer
+	w := NewWorkflow("diamond", nil)
+	w.Concurrency = 2
+	mustAdd(t, w, "d", shTask(dir, "test -f b -a -f c && echo done"), "b", "c")
+	mustAdd(t, w, "a", shTask(dir, "echo start"))
+	mustAdd(t, w, "b", shTask(dir, wait("b", "c")), "a")
+	mustAdd(t, w, "c", shTask(dir, wait("c", "b")), "a")
+	w.nodes["d"].task.Logger = log.New(&out, "", 0)
+
+	res, err := w.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := nodeIDs(res); !slices.Equal(ids, []string{"a", "b", "c", "d"}) {
+		t.Errorf("order = %v", ids)
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"a": StatusSucceeded, "b": StatusSucceeded, "c": StatusSucceeded, "d": StatusSucceeded})
+	if !strings.Contains(out.String(), "[d] stdout: done") {
+		t.Errorf("task d log:\n%s", out.String())
+	}
+}
+
+func TestWorkflowFailFast(t *testing.T) {
+	requireShell(t)
+	w := NewWorkflow("ff", nil)
+	w.Concurrency = 2
+	mustAdd(t, w, "fail", shTask("", "sleep 0.1; exit 4"))
+	mustAdd(t, w, "slow", shTask("", "sleep 5"))
+	mustAdd(t, w, "after", shTask("", "true"), "fail")
+	mustAdd(t, w, "queued", shTask("", "true"))
+
+	start := time.Now()
+	res, err := w.Run(context.Background())
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("slow task not canceled: took %v", time.Since(start))
+	}
+	var werr *WorkflowError
+	if !errors.As(err, &werr) || !slices.Contains(werr.Failed, "fail") {
+		t.Fatalf("err = %v, want WorkflowError with fail", err)
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"fail": StatusFailed, "slow": StatusCanceled, "after": StatusSkipped, "queued": StatusSkipped})
+	if n, _ := res.Node("fail"); n.Result == nil || n.Result.ExitCode != 4 {
+		t.Errorf("fail result = %+v", n.Result)
+	}
+}
+
+func TestWorkflowContinueOnFailure(t *testing.T) {
+	requireShell(t)
+	w := NewWorkflow("cont", nil)
+	w.Concurrency = 1
+	w.ContinueOnFailure = true
+	mustAdd(t, w, "a", shTask("", "exit 1"))
+	mustAdd(t, w, "b", shTask("", "true"))
+	mustAdd(t, w, "c", shTask("", "true"), "a")
+	mustAdd(t, w, "d", shTask("", "true"), "c", "b")
+
+	res, err := w.Run(context.Background())
+	var werr *WorkflowError
+	if !errors.As(err, &werr) || !slices.Equal(werr.Failed, []string{"a"}) {
+		t.Fatalf("err = %v", err)
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"a": StatusFailed, "b": StatusSucceeded, "c": StatusSkipped, "d": StatusSkipped})
+	if n, _ := res.Node("d"); n.Err == nil || n.Err.Error() != "dependency c skipped" {
+		t.Errorf("d err = %v", n.Err)
+	}
+
+	var buf bytes.Buffer
+	if err := res.WriteSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	summary := buf.String()
+	for _, want := range []string{"workflow cont: 1 succeeded, 1 failed, 2 skipped in ", "TASK", "dependency a failed"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestWorkflowResume(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	state := filepath.Join(dir, "state.json")
+	build := func(prepare string) *Workflow {
+		w := NewWorkflow("resume", nil)
+		w.StateFile = state
+		mustAdd(t, w, "prepare", shTask(dir, prepare))
+		mustAdd(t, w, "flaky", shTask(dir, "test -f ok"), "prepare")
+		mustAdd(t, w, "report", shTask(dir, "echo report >> runs"), "flaky")
+		return w
+	}
+
+	res, err := build("echo prepare >> runs").Resume(context.Background())
+	if err == nil {
+		t.Fatal("first run: want error")
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"prepare": StatusSucceeded, "flaky": StatusFailed, "report": StatusSkipped})
+	prev, err := readWorkflowState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev.Tasks["flaky"].Status != StatusFailed || prev.Tasks["flaky"].ExitCode != 1 {
+		t.Errorf("state = %+v", prev.Tasks)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "ok"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = build("echo prepare >> runs").Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"prepare": StatusReused, "flaky": StatusSucceeded, "report": StatusSucceeded})
+	checkRuns(t, dir, "prepare\nreport\n")
+
+	// 定义变化后重新运行这个任务和依赖它的任务
+	res, err = build("echo prepare2 >> runs").Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, res, map[string]TaskStatus{"prepare": StatusSucceeded, "flaky": StatusSucceeded, "report": StatusSucceeded})
+	checkRuns(t, dir, "prepare\nreport\nprepare2\nreport\n")
+
+	other := NewWorkflow("other", nil)
+	other.StateFile = state
+	if _, err := other.Resume(context.Background()); err == nil {
+		t.Error("resume with another workflow's state: want error")
+	}
+	if _, err := NewWorkflow("nostate", nil).Resume(context.Background()); err == nil {
+		t.Error("resume without state file: want error")
+	}
+}
+
+func checkRuns(t *testing.T, dir, want string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("runs = %q, want %q", data, want)
+	}
+}
+
+func nodeIDs(res *WorkflowResult) []string {
+	var ids []string
+	for _, n := range res.Nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
@@ -0,0 +1,498 @@
+package demo11_interface
+
+import (
+	"bufio"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Demo10:动态方法调用
+/*
+StreamXml 先通过类型断言检查 v 是否实现了 xmlWriter，实现了就交给类型自己编码，否则回退到基于反射的 EncodeToXML。
+EncodeToXML 支持的结构体标签格式与 encoding/xml 相近：
+	`xml:"name"`            元素名
+	`xml:"name,attr"`       编码为属性
+	`xml:",chardata"`       编码为元素的文本内容
+	`xml:"name,omitempty"`  零值时省略
+	`xml:"-"`               忽略该字段
+结构体中名为 XMLName 的字段只用来通过标签指定根元素名，本身不会被编码。
+DecodeXML 是对应的解码端（见 xml_decode.go），同样先检查 xmlReader，再回退到反射。
+*/
+
+// xmlWriter 可以自行编码为 XML 的类型
+type xmlWriter interface {
+	WriteXML(w io.Writer) error
+}
+
+// StreamXml 把 v 以 XML 形式写入 w，优先使用 v 自己的 WriteXML
+func StreamXml(v any, w io.Writer) error {
+	if xw, ok := v.(xmlWriter); ok {
+		return xw.WriteXML(w)
+	}
+	return EncodeToXML(v, w)
+}
+
+// EncodeToXML 通过反射把 v 编码为 XML 写入 w。
+// 嵌套的字段如果实现了 xmlWriter 会调用其 WriteXML；根值本身不会，
+// 这样类型可以在自己的 WriteXML 里调用 EncodeToXML 而不会无限递归。
+func EncodeToXML(v any, w io.Writer) error {
+	if v == nil {
+		return nil
+	}
+	e := &xmlEncoder{w: bufio.NewWriter(w), visiting: make(map[uintptr]bool)}
+	if err := e.encodeRoot(reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// UnsupportedTypeError 无法编码为 XML 的类型
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "xml: unsupported type: " + e.Type.String()
+}
+
+var (
+	xmlWriterType     = reflect.TypeOf((*xmlWriter)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type xmlEncoder struct {
+	w *bufio.Writer
+	// visiting 记录当前递归路径上的指针，用于发现循环引用
+	visiting map[uintptr]bool
+}
+
+func (e *xmlEncoder) encodeRoot(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if k := v.Kind(); (k == reflect.Slice || k == reflect.Array) && !isByteSlice(v.Type()) {
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encodeRoot(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	name := rootName(v.Type())
+	if name == "" {
+		return fmt.Errorf("xml: cannot determine element name for unnamed type %s", v.Type())
+	}
+	return e.encodeValue(name, v, false)
+}
+
+// rootName 根元素名：XMLName 字段的标签优先，其次是类型名
+func rootName(t reflect.Type) string {
+	if t.Kind() == reflect.Struct {
+		if f, ok := t.FieldByName("XMLName"); ok {
+			if name, _ := parseXMLTag(f.Tag.Get("xml")); name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return t.Name()
+}
+
+// encodeValue 把 v 编码为名为 name 的元素，nil 指针和 nil 接口不输出任何内容
+func (e *xmlEncoder) encodeValue(name string, v reflect.Value, allowCustom bool) error {
+	if !v.IsValid() {
+		return nil
+	}
+	if allowCustom {
+		if xw, ok := asInterface(v, xmlWriterType); ok {
+			return xw.(xmlWriter).WriteXML(e.w)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			ptr := v.Pointer()
+			if e.visiting[ptr] {
+				return fmt.Errorf("xml: encountered a cycle via %s", v.Type())
+			}
+			e.visiting[ptr] = true
+			defer delete(e.visiting, ptr)
+		}
+		return e.encodeValue(name, v.Elem(), true)
+	}
+
+	if tm, ok := asInterface(v, textMarshalerType); ok {
+		text, err := tm.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		return e.writeTextElement(name, string(text))
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return e.encodeStruct(name, v)
+	case reflect.Slice, reflect.Array:
+		if isByteSlice(v.Type()) {
+			return e.writeTextElement(name, string(v.Bytes()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encodeValue(name, v.Index(i), true); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		return e.encodeMap(name, v)
+	}
+
+	text, err := simpleText(v)
+	if err != nil {
+		return err
+	}
+	return e.writeTextElement(name, text)
+}
+
+func (e *xmlEncoder) encodeStruct(name string, v reflect.Value) error {
+	fields := cachedXMLFields(v.Type())
+
+	e.w.WriteByte('<')
+	e.w.WriteString(name)
+	for _, f := range fields {
+		if !f.attr {
+			continue
+		}
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		text, ok, err := attrText(fv)
+		if err != nil {
+			return fmt.Errorf("xml: attribute %s of %s: %w", f.name, v.Type(), err)
+		}
+		if !ok {
+			continue
+		}
+		e.w.WriteByte(' ')
+		e.w.WriteString(f.name)
+		e.w.WriteString(`="`)
+		escapeXML(e.w, text, true)
+		e.w.WriteByte('"')
+	}
+	e.w.WriteByte('>')
+
+	for _, f := range fields {
+		if f.attr {
+			continue
+		}
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		if f.chardata {
+			text, ok, err := attrText(fv)
+			if err != nil {
+				return fmt.Errorf("xml: chardata of %s: %w", v.Type(), err)
+			}
+			if ok {
+				escapeXML(e.w, text, false)
+			}
+			continue
+		}
+		if err := e.encodeValue(f.name, fv, true); err != nil {
+			return err
+		}
+	}
+
+	e.writeEnd(name)
+	return nil
+}
+
+// encodeMap 每个键值对编码为 <entry key="k">v</entry>，按键排序保证输出稳定
+func (e *xmlEncoder) encodeMap(name string, v reflect.Value) error {
+	if v.IsNil() {
+		return nil
+	}
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, ok, err := attrText(iter.Key())
+		if err != nil {
+			return fmt.Errorf("xml: map key of %s: %w", v.Type(), err)
+		}
+		if !ok {
+			return &UnsupportedTypeError{Type: v.Type()}
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	e.writeStart(name)
+	for _, en := range entries {
+		e.w.WriteString(`<entry key="`)
+		escapeXML(e.w, en.key, true)
+		e.w.WriteString(`">`)
+		text, ok, err := attrText(en.val)
+		if err != nil {
+			return err
+		}
+		if ok {
+			escapeXML(e.w, text, false)
+		} else if err := e.encodeEntryValue(en.val); err != nil {
+			return err
+		}
+		e.writeEnd("entry")
+	}
+	e.writeEnd(name)
+	return nil
+}
+
+// encodeEntryValue 复合类型的 map 值在 entry 内部用它的类型名再包一层
+func (e *xmlEncoder) encodeEntryValue(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	name := rootName(v.Type())
+	if name == "" {
+		name = "value"
+	}
+	return e.encodeValue(name, v, true)
+}
+
+func (e *xmlEncoder) writeTextElement(name, text string) error {
+	e.writeStart(name)
+	escapeXML(e.w, text, false)
+	e.writeEnd(name)
+	return nil
+}
+
+func (e *xmlEncoder) writeStart(name string) {
+	e.w.WriteByte('<')
+	e.w.WriteString(name)
+	e.w.WriteByte('>')
+}
+
+func (e *xmlEncoder) writeEnd(name string) {
+	e.w.WriteString("</")
+	e.w.WriteString(name)
+	e.w.WriteByte('>')
+}
+
+// attrText 把可以用一段文本表示的值转换为文本，ok 为 false 表示值为 nil 或者是复合类型
+func attrText(v reflect.Value) (text string, ok bool, err error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+	if tm, is := asInterface(v, textMarshalerType); is {
+		b, err := tm.(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil, err
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if isByteSlice(v.Type()) {
+			return string(v.Bytes()), true, nil
+		}
+		return "", false, nil
+	}
+	text, err = simpleText(v)
+	return text, err == nil, err
+}
+
+// simpleText 基本类型的文本形式
+func simpleText(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", &UnsupportedTypeError{Type: v.Type()}
+}
+
+// asInterface 检查 v 或 &v 是否实现了接口 it
+func asInterface(v reflect.Value, it reflect.Type) (any, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if v.Type().Implements(it) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(it) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// fieldByIndex 沿着内嵌字段取值，途中遇到 nil 指针时 ok 为 false
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// xmlField 结构体字段的 XML 编码信息
+type xmlField struct {
+	name      string
+	index     []int
+	attr      bool
+	chardata  bool
+	omitEmpty bool
+}
+
+var xmlFieldCache sync.Map // map[reflect.Type][]xmlField
+
+func cachedXMLFields(t reflect.Type) []xmlField {
+	if f, ok := xmlFieldCache.Load(t); ok {
+		return f.([]xmlField)
+	}
+	f, _ := xmlFieldCache.LoadOrStore(t, typeXMLFields(t, nil))
+	return f.([]xmlField)
+}
+
+// typeXMLFields 解析结构体的字段标签，没有标签的内嵌结构体会被展开
+func typeXMLFields(t reflect.Type, index []int) []xmlField {
+	var fields []xmlField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Name == "XMLName" {
+			continue
+		}
+		tag := sf.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+		name, opts := parseXMLTag(tag)
+		if sf.Anonymous && name == "" && opts == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, typeXMLFields(ft, idx)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := xmlField{name: name, index: idx}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "attr":
+				f.attr = true
+			case "chardata":
+				f.chardata = true
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func parseXMLTag(tag string) (name, opts string) {
+	name, opts, _ = strings.Cut(tag, ",")
+	return name, opts
+}
+
+// escapeXML 转义文本，attr 为 true 时额外转义引号和空白字符，保证属性值解析后不变
+func escapeXML(w *bufio.Writer, s string, attr bool) {
+	last := 0
+	for i := 0; i < len(s); {
+		r, width := utf8.DecodeRuneInString(s[i:])
+		var esc string
+		switch {
+		case r == '&':
+			esc = "&amp;"
+		case r == '<':
+			esc = "&lt;"
+		case r == '>':
+			esc = "&gt;"
+		case r == '"' && attr:
+			esc = "&quot;"
+		case r == '\'' && attr:
+			esc = "&apos;"
+		case r == '\t' && attr:
+			esc = "&#x9;"
+		case r == '\n' && attr:
+			esc = "&#xA;"
+		case r == '\r':
+			esc = "&#xD;"
+		case !isXMLChar(r) || (r == utf8.RuneError && width == 1):
+			esc = "\uFFFD"
+		}
+		if esc != "" {
+			w.WriteString(s[last:i])
+			w.WriteString(esc)
+			last = i + width
+		}
+		i += width
+	}
+	w.WriteString(s[last:])
+}
+
+// isXMLChar XML 1.0 允许出现的字符
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
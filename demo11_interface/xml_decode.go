@@ -0,0 +1,512 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// xmlReader 可以自行从 XML 解码的类型，与 xmlWriter 对应
+type xmlReader interface {
+	ReadXML(r io.Reader) error
+}
+
+// XMLDecodeError 解码错误，Line 和 Column 从 1 开始
+type XMLDecodeError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *XMLDecodeError) Error() string {
+	return fmt.Sprintf("xml: line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+var (
+	xmlReaderType       = reflect.TypeOf((*xmlReader)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeXML 从 r 中读取 XML 填充 v，v 必须是非 nil 指针。
+// 如果 v 实现了 xmlReader 就交给它自己解码，否则按 EncodeToXML 的规则通过反射解码，
+// 所以 EncodeToXML 的输出都可以原样解码回来。嵌套字段同样会优先使用 xmlReader。
+func DecodeXML(r io.Reader, v any) error {
+	if xr, ok := v.(xmlReader); ok {
+		return xr.ReadXML(r)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("xml: DecodeXML requires a non-nil pointer, got %T", v)
+	}
+	d := &xmlDecoder{d: xml.NewDecoder(r)}
+	return d.decodeRoot(rv.Elem())
+}
+
+type xmlDecoder struct {
+	d *xml.Decoder
+	// line, col 是最近一次读取的 token 的起始位置
+	line, col int
+}
+
+// token 读取下一个 token，语法错误会被转换为带位置的 XMLDecodeError
+func (d *xmlDecoder) token() (xml.Token, error) {
+	d.line, d.col = d.d.InputPos()
+	tok, err := d.d.RawToken()
+	if err != nil {
+		var se *xml.SyntaxError
+		if errors.As(err, &se) {
+			line, col := d.d.InputPos()
+			return nil, &XMLDecodeError{Line: line, Column: col, Msg: se.Msg}
+		}
+		return nil, err
+	}
+	return xml.CopyToken(tok), nil
+}
+
+func (d *xmlDecoder) errorf(format string, args ...any) error {
+	return &XMLDecodeError{Line: d.line, Column: d.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// nextStart 跳过空白、注释和处理指令，返回下一个开始标签；输入结束时返回 io.EOF
+func (d *xmlDecoder) nextStart() (xml.StartElement, error) {
+	for {
+		tok, err := d.token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, d.errorf("unexpected end element </%s>", t.Name.Local)
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return xml.StartElement{}, d.errorf("unexpected text %q outside of element", t)
+			}
+		}
+	}
+}
+
+func (d *xmlDecoder) decodeRoot(v reflect.Value) error {
+	if v.Kind() == reflect.Slice && !isByteSlice(v.Type()) {
+		for {
+			start, err := d.nextStart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := d.checkRootName(start, v.Type().Elem()); err != nil {
+				return err
+			}
+			if err := d.decodeElement(start, v, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	start, err := d.nextStart()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if err := d.checkRootName(start, v.Type()); err != nil {
+		return err
+	}
+	return d.decodeElement(start, v, false)
+}
+
+// checkRootName 根元素名必须与 EncodeToXML 为该类型生成的名字一致
+func (d *xmlDecoder) checkRootName(start xml.StartElement, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name := rootName(t); name != "" && name != start.Name.Local {
+		return d.errorf("expected element <%s> but found <%s>", name, start.Name.Local)
+	}
+	return nil
+}
+
+// decodeElement 把以 start 开头的元素解码到 v，返回时已经读完对应的结束标签
+func (d *xmlDecoder) decodeElement(start xml.StartElement, v reflect.Value, allowCustom bool) error {
+	if allowCustom {
+		if xr, ok := asInterface(v, xmlReaderType); ok {
+			raw, err := d.captureElement(start)
+			if err != nil {
+				return err
+			}
+			return xr.(xmlReader).ReadXML(bytes.NewReader(raw))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeElement(start, v.Elem(), true)
+	case reflect.Interface:
+		if v.IsNil() || v.Elem().Kind() != reflect.Pointer {
+			return d.skip(start)
+		}
+		return d.decodeElement(start, v.Elem(), true)
+	case reflect.Slice:
+		if !isByteSlice(v.Type()) {
+			n := v.Len()
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			return d.decodeElement(start, v.Index(n), true)
+		}
+	}
+
+	if isTextType(v.Type()) {
+		text, err := d.readText(start)
+		if err != nil {
+			return err
+		}
+		return d.setText(v, text)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return d.decodeStruct(start, v)
+	case reflect.Map:
+		return d.decodeMap(start, v)
+	}
+	return &UnsupportedTypeError{Type: v.Type()}
+}
+
+func (d *xmlDecoder) decodeStruct(start xml.StartElement, v reflect.Value) error {
+	fields := cachedXMLFields(v.Type())
+
+	for _, a := range start.Attr {
+		for _, f := range fields {
+			if f.attr && f.name == a.Name.Local {
+				fv := allocFieldByIndex(v, f.index)
+				if err := d.setText(fv, a.Value); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	var chardata *xmlField
+	for i := range fields {
+		if fields[i].chardata {
+			chardata = &fields[i]
+			break
+		}
+	}
+
+	var text strings.Builder
+	arrayPos := make(map[int]int)
+	for {
+		tok, err := d.token()
+		if err != nil {
+			return d.unexpectedEOF(start, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			fi := -1
+			for i, f := range fields {
+				if !f.attr && !f.chardata && f.name == t.Name.Local {
+					fi = i
+					break
+				}
+			}
+			if fi < 0 {
+				if err := d.skip(t); err != nil {
+					return err
+				}
+				continue
+			}
+			fv := allocFieldByIndex(v, fields[fi].index)
+			if fv.Kind() == reflect.Array {
+				pos := arrayPos[fi]
+				if pos >= fv.Len() {
+					return d.errorf("too many <%s> elements for %s", t.Name.Local, fv.Type())
+				}
+				arrayPos[fi] = pos + 1
+				fv = fv.Index(pos)
+			}
+			if err := d.decodeElement(t, fv, true); err != nil {
+				return err
+			}
+		case xml.CharData:
+			if chardata != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if err := d.checkEnd(start, t); err != nil {
+				return err
+			}
+			if chardata != nil {
+				return d.setText(allocFieldByIndex(v, chardata.index), text.String())
+			}
+			return nil
+		}
+	}
+}
+
+// decodeMap 解码 encodeMap 输出的 <entry key="k">v</entry> 列表
+func (d *xmlDecoder) decodeMap(start xml.StartElement, v reflect.Value) error {
+	t := v.Type()
+	if !isTextType(t.Key()) {
+		return &UnsupportedTypeError{Type: t}
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for {
+		tok, err := d.token()
+		if err != nil {
+			return d.unexpectedEOF(start, err)
+		}
+		switch e := tok.(type) {
+		case xml.StartElement:
+			if e.Name.Local != "entry" {
+				return d.errorf("expected <entry> in <%s> but found <%s>", start.Name.Local, e.Name.Local)
+			}
+			key := reflect.New(t.Key()).Elem()
+			keyText, ok := attrValue(e, "key")
+			if !ok {
+				return d.errorf("<entry> in <%s> has no key attribute", start.Name.Local)
+			}
+			if err := d.setText(key, keyText); err != nil {
+				return err
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := d.decodeEntryValue(e, val); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, val)
+		case xml.CharData:
+			if len(bytes.TrimSpace(e)) != 0 {
+				return d.errorf("unexpected text %q in <%s>", e, start.Name.Local)
+			}
+		case xml.EndElement:
+			return d.checkEnd(start, e)
+		}
+	}
+}
+
+// decodeEntryValue 文本类型直接读取内容，复合类型读取 entry 内部的子元素
+func (d *xmlDecoder) decodeEntryValue(entry xml.StartElement, v reflect.Value) error {
+	if isTextType(v.Type()) {
+		text, err := d.readText(entry)
+		if err != nil {
+			return err
+		}
+		return d.setText(v, text)
+	}
+	for {
+		tok, err := d.token()
+		if err != nil {
+			return d.unexpectedEOF(entry, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := d.decodeElement(t, v, true); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return d.checkEnd(entry, t)
+		}
+	}
+}
+
+// readText 读取元素的文本内容，文本元素中不允许出现子元素
+func (d *xmlDecoder) readText(start xml.StartElement) (string, error) {
+	var text strings.Builder
+	for {
+		tok, err := d.token()
+		if err != nil {
+			return "", d.unexpectedEOF(start, err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			return "", d.errorf("unexpected element <%s> inside text element <%s>", t.Name.Local, start.Name.Local)
+		case xml.EndElement:
+			return text.String(), d.checkEnd(start, t)
+		}
+	}
+}
+
+// skip 跳过一个不认识的元素及其所有子元素
+func (d *xmlDecoder) skip(start xml.StartElement) error {
+	return d.collect(start, nil)
+}
+
+// captureElement 把整个元素重新序列化出来，交给 xmlReader 处理
+func (d *xmlDecoder) captureElement(start xml.StartElement) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := d.collect(start, enc); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// collect 读取到与 start 匹配的结束标签为止，enc 不为 nil 时同时把 token 写入 enc
+func (d *xmlDecoder) collect(start xml.StartElement, enc *xml.Encoder) error {
+	stack := []xml.StartElement{start}
+	emit := func(tok xml.Token) error {
+		if enc == nil {
+			return nil
+		}
+		return enc.EncodeToken(tok)
+	}
+	if err := emit(stripPrefix(start)); err != nil {
+		return err
+	}
+	for len(stack) > 0 {
+		tok, err := d.token()
+		if err != nil {
+			return d.unexpectedEOF(stack[len(stack)-1], err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t)
+			tok = stripPrefix(t)
+		case xml.EndElement:
+			if err := d.checkEnd(stack[len(stack)-1], t); err != nil {
+				return err
+			}
+			stack = stack[:len(stack)-1]
+			tok = xml.EndElement{Name: xml.Name{Local: t.Name.Local}}
+		case xml.ProcInst, xml.Directive:
+			continue
+		}
+		if err := emit(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripPrefix 去掉命名空间前缀，避免 xml.Encoder 把前缀当作命名空间 URL
+func stripPrefix(s xml.StartElement) xml.StartElement {
+	s.Name.Space = ""
+	attrs := make([]xml.Attr, len(s.Attr))
+	for i, a := range s.Attr {
+		attrs[i] = xml.Attr{Name: xml.Name{Local: a.Name.Local}, Value: a.Value}
+	}
+	s.Attr = attrs
+	return s
+}
+
+func (d *xmlDecoder) checkEnd(start xml.StartElement, end xml.EndElement) error {
+	if end.Name != start.Name {
+		return d.errorf("element <%s> closed by </%s>", start.Name.Local, end.Name.Local)
+	}
+	return nil
+}
+
+func (d *xmlDecoder) unexpectedEOF(start xml.StartElement, err error) error {
+	if err == io.EOF {
+		return d.errorf("unexpected EOF: element <%s> is not closed", start.Name.Local)
+	}
+	return err
+}
+
+// setText 把文本写入基本类型或实现了 encoding.TextUnmarshaler 的值
+func (d *xmlDecoder) setText(v reflect.Value, text string) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if tu, ok := asInterface(v, textUnmarshalerType); ok {
+		if err := tu.(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return d.errorf("%v", err)
+		}
+		return nil
+	}
+	if isByteSlice(v.Type()) {
+		v.SetBytes([]byte(text))
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(strings.TrimSpace(text))
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(strings.TrimSpace(text), 10, v.Type().Bits())
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		u, err = strconv.ParseUint(strings.TrimSpace(text), 10, v.Type().Bits())
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(strings.TrimSpace(text), v.Type().Bits())
+		v.SetFloat(f)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	if err != nil {
+		return d.errorf("cannot parse %q as %s", text, v.Type())
+	}
+	return nil
+}
+
+// isTextType 是否可以由一段文本表示，和编码时的 attrText 对应
+func isTextType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) || isByteSlice(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// allocFieldByIndex 沿着内嵌字段取值，途中遇到 nil 指针时分配新值
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func attrValue(s xml.StartElement, name string) (string, bool) {
+	for _, a := range s.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,160 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeXMLCar(t *testing.T) {
+	in := `<?xml version="1.0"?>
+<Car>
+	<Module>1</Module>
+	<Manufacturer>BYD</Manufacturer>
+	<BuildYear> 2024 </BuildYear>
+	<Unknown><Nested/></Unknown>
+</Car>`
+	var c Car
+	if err := DecodeXML(strings.NewReader(in), &c); err != nil {
+		t.Fatal(err)
+	}
+	want := Car{Module: "1", Manufacturer: "BYD", BuildYear: 2024}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestDecodeXMLStockPosition(t *testing.T) {
+	sp := stockPosition{ticker: "ESOP", sharePrice: MustParseDecimal("88.99"), count: NewDecimal(10, 0)}
+	var buf bytes.Buffer
+	if err := StreamXml(sp, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var got stockPosition
+	if err := DecodeXML(&buf, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != sp {
+		t.Errorf("got %+v, want %+v", got, sp)
+	}
+}
+
+type portfolioXML struct {
+	Owner     string          `xml:"owner,attr"`
+	Positions []stockPosition `xml:"stockPosition"`
+	Best      *stockPosition  `xml:"best"`
+}
+
+func TestDecodeXMLNestedReader(t *testing.T) {
+	p := portfolioXML{
+		Owner: "chen",
+		Positions: []stockPosition{
+			{ticker: "ESOP", sharePrice: MustParseDecimal("88.99"), count: NewDecimal(10, 0)},
+			{ticker: "BYD", sharePrice: MustParseDecimal("230.5"), count: NewDecimal(3, 0)},
+		},
+	}
+	var buf bytes.Buffer
+	if err := EncodeToXML(p, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var got portfolioXML
+	if err := DecodeXML(&buf, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got %+v, want %+v", got, p)
+	}
+}
+
+type inventory struct {
+	Name    string            `xml:"name,attr"`
+	Label   label             `xml:"label"`
+	Cars    Cars              `xml:"car"`
+	Counts  map[int]uint8     `xml:"counts"`
+	ByMaker map[string]*Car   `xml:"byMaker"`
+	Years   map[string][]int  `xml:"years"`
+	Corners [2]float64        `xml:"corner"`
+	Raw     []byte            `xml:"raw"`
+	Flags   []bool            `xml:"flag"`
+	Since   *time.Time        `xml:"since,omitempty"`
+	Extra   map[string]string `xml:"extra,omitempty"`
+	Owner   *string           `xml:"owner,attr,omitempty"`
+}
+
+func TestDecodeXMLRoundTrip(t *testing.T) {
+	since := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	owner := "yun & co"
+	in := inventory{
+		Name:  "<main>",
+		Label: label{Lang: "zh", Text: "  库存\n"},
+		Cars: Cars{
+			&Car{Module: "1", Manufacturer: "BMW", BuildYear: 2024},
+			&Car{Module: "2", Manufacturer: "BYD", BuildYear: 2021},
+		},
+		Counts:  map[int]uint8{1: 2, 30: 4},
+		ByMaker: map[string]*Car{"bmw": {Module: "1", Manufacturer: "BMW", BuildYear: 2024}},
+		Years:   map[string][]int{"a": {2020, 2021}},
+		Corners: [2]float64{1.5, -2.25},
+		Raw:     []byte("bytes"),
+		Flags:   []bool{true, false},
+		Since:   &since,
+		Owner:   &owner,
+	}
+	var buf bytes.Buffer
+	if err := EncodeToXML(in, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var out inventory
+	if err := DecodeXML(bytes.NewReader(buf.Bytes()), &out); err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch\n in %+v\nout %+v", in, out)
+	}
+
+	var cars Cars
+	buf.Reset()
+	if err := EncodeToXML(in.Cars, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeXML(&buf, &cars); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cars, in.Cars) {
+		t.Errorf("got %v, want %v", cars, in.Cars)
+	}
+}
+
+func TestDecodeXMLErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		line, col int
+	}{
+		{"mismatched end", "<Car>\n  <Module>1</Manufacturer>\n</Car>", 2, 12},
+		{"wrong root", "\n<Truck></Truck>", 2, 1},
+		{"bad number", "<Car><BuildYear>abc</BuildYear></Car>", 1, 20},
+		{"element in text", "<Car><Module><x/></Module></Car>", 1, 14},
+		{"unclosed", "<Car><Module>1</Module>", 1, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Car
+			err := DecodeXML(strings.NewReader(tt.in), &c)
+			var de *XMLDecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("want XMLDecodeError, got %v", err)
+			}
+			if de.Line != tt.line || de.Column != tt.col {
+				t.Errorf("got position %d:%d, want %d:%d (%v)", de.Line, de.Column, tt.line, tt.col, err)
+			}
+		})
+	}
+
+	if err := DecodeXML(strings.NewReader("<Car></Car>"), Car{}); err == nil {
+		t.Error("want error for non-pointer")
+	}
+}
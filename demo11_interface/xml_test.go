@@ -0,0 +1,139 @@
+package demo11_interface
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type garage struct {
+	XMLName  struct{}          `xml:"garage"`
+	ID       int               `xml:"id,attr"`
+	Owner    string            `xml:"owner,attr,omitempty"`
+	Cars     []*Car            `xml:"car"`
+	Note     string            `xml:"note,omitempty"`
+	Tags     map[string]string `xml:"tags"`
+	Opened   time.Time         `xml:"opened"`
+	internal string
+	Skip     string `xml:"-"`
+}
+
+func TestEncodeToXML(t *testing.T) {
+	g := garage{
+		ID: 7,
+		Cars: []*Car{
+			{Module: "1", Manufacturer: "BMW", BuildYear: 2024},
+			nil,
+			{Module: "2", Manufacturer: "B&Y<D>", BuildYear: 2020},
+		},
+		Tags:     map[string]string{"b": "2", "a": `"1"`},
+		Opened:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		internal: "secret",
+		Skip:     "skip",
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeToXML(&g, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `<garage id="7">` +
+		`<car><Module>1</Module><Manufacturer>BMW</Manufacturer><BuildYear>2024</BuildYear></car>` +
+		`<car><Module>2</Module><Manufacturer>B&amp;Y&lt;D&gt;</Manufacturer><BuildYear>2020</BuildYear></car>` +
+		`<tags><entry key="a">"1"</entry><entry key="b">2</entry></tags>` +
+		`<opened>2024-01-02T03:04:05Z</opened>` +
+		`</garage>`
+	if got := buf.String(); got != want {
+		t.Errorf("EncodeToXML:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestEncodeToXMLRootSlice(t *testing.T) {
+	cars := Cars{
+		&Car{Module: "1", Manufacturer: "BMW", BuildYear: 2024},
+		&Car{Module: "1", Manufacturer: "BYD", BuildYear: 2024},
+	}
+	var buf bytes.Buffer
+	if err := EncodeToXML(cars, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<Car>"); n != 2 {
+		t.Errorf("want 2 <Car> elements, got %d in %s", n, buf.String())
+	}
+}
+
+type label struct {
+	Lang string `xml:"lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+func TestEncodeToXMLEscape(t *testing.T) {
+	var buf bytes.Buffer
+	v := label{Lang: "a\"b\n", Text: "x<y & \x01"}
+	if err := EncodeToXML(v, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `<label lang="a&quot;b&#xA;">x&lt;y &amp; ` + "\uFFFD" + `</label>`
+	if got := buf.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// upperName 自定义编码，验证嵌套字段会走 xmlWriter
+type upperName string
+
+func (u upperName) WriteXML(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "<name>%s</name>", strings.ToUpper(string(u)))
+	return err
+}
+
+type person struct {
+	Name upperName
+	Age  int
+}
+
+func TestStreamXml(t *testing.T) {
+	var buf bytes.Buffer
+	if err := StreamXml(person{Name: "chen", Age: 28}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "<person><name>CHEN</name><Age>28</Age></person>"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	buf.Reset()
+	if err := StreamXml(upperName("yun"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "<name>YUN</name>"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestEncodeToXMLErrors(t *testing.T) {
+	n := &node{Value: 1}
+	n.Next = n
+	if err := EncodeToXML(n, io.Discard); err == nil {
+		t.Error("want error for cyclic value")
+	}
+
+	type withChan struct {
+		C chan int
+	}
+	var ute *UnsupportedTypeError
+	if err := EncodeToXML(withChan{C: make(chan int)}, io.Discard); !errors.As(err, &ute) {
+		t.Errorf("want UnsupportedTypeError, got %v", err)
+	}
+
+	if err := EncodeToXML(map[string]int{}, io.Discard); err == nil {
+		t.Error("want error for unnamed root type")
+	}
+}
@@ -0,0 +1,238 @@
+// Package typeload 离线加载模块中的包并做类型检查
+/*
+1:不依赖 golang.org/x/tools：用 go list -json 解析包模式、找到源文件，再用 go/parser 和 go/types 做类型检查，
+  相当于 go/packages 的 LoadTypes|LoadSyntax 的一个子集。
+2:go list 时设置 GOPROXY=off，模块之外的依赖必须已经在本地的模块缓存中，不会访问网络。
+3:列出的包之间的导入使用这里检查的版本，其他依赖（标准库、其他模块等）用导出数据：再运行一次 go list -deps -export
+  让 go 命令编译依赖并给出每个包的导出数据文件，go/importer 通过 lookup 函数读取这些文件，
+  所以模块缓存和 replace 指向的目录中的依赖都能找到，不局限于 GOROOT 和 GOPATH。
+  导出数据里会带上它导入的包的类型，如果没有列出的依赖导入了列出的包，这个依赖也从源码检查（不返回），
+  否则它看到的是列出的包的另一份类型对象，跨包比较类型（types.Implements、types.Identical）会出错。
+4:Tests 为 true 时包内的 _test.go 和包本身一起检查，外部测试包（package xxx_test）作为单独的包，ImportPath 加上 _test 后缀。
+5:类型错误不会中断加载，记录在 Package.Errors 里，其余部分照常可用。
+*/
+package typeload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Config 加载的选项
+type Config struct {
+	// Dir 运行 go list 的目录，为空时使用当前目录
+	Dir string
+	// Tests 同时加载测试文件
+	Tests bool
+}
+
+// Package 类型检查过的包
+type Package struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Files      []*ast.File
+	Types      *types.Package
+	Info       *types.Info
+	// Errors go list、语法和类型错误
+	Errors []error
+}
+
+// listedPackage go list -json 输出中用到的字段
+type listedPackage struct {
+	ImportPath   string
+	Name         string
+	Dir          string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	// Imports、Export 只在列出依赖时用到，Export 是导出数据文件
+	Imports []string
+	Export  string
+	Error   *struct{ Err string }
+}
+
+const listFields = "-json=ImportPath,Name,Dir,GoFiles,TestGoFiles,XTestGoFiles,Error"
+
+// Load 加载 patterns 匹配的包，返回的包按 go list 的顺序排列
+func Load(cfg Config, patterns ...string) (*token.FileSet, []*Package, error) {
+	listed, err := goList(cfg.Dir, []string{listFields}, patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	deps, err := listDeps(cfg, patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := &loader{
+		fset:     token.NewFileSet(),
+		tests:    cfg.Tests,
+		listed:   make(map[string]*listedPackage),
+		checked:  make(map[string]*Package),
+		checking: make(map[string]bool),
+	}
+	for _, lp := range listed {
+		l.listed[lp.ImportPath] = lp
+	}
+	// go list -deps 先输出依赖再输出导入它的包，一遍就能找出所有直接或间接导入了列出的包的依赖
+	exports := make(map[string]string, len(deps))
+	for _, lp := range deps {
+		if _, ok := l.listed[lp.ImportPath]; ok {
+			continue
+		}
+		if slices.ContainsFunc(lp.Imports, func(path string) bool { return l.listed[path] != nil }) {
+			l.listed[lp.ImportPath] = lp
+		} else if lp.Export != "" {
+			exports[lp.ImportPath] = lp.Export
+		}
+	}
+	l.fallback = importer.ForCompiler(l.fset, "gc", func(path string) (io.ReadCloser, error) {
+		file, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("typeload: no export data for %s", path)
+		}
+		return os.Open(file)
+	}).(types.ImporterFrom)
+
+	var pkgs []*Package
+	for _, lp := range listed {
+		pkgs = append(pkgs, l.check(lp.ImportPath))
+		if cfg.Tests && len(lp.XTestGoFiles) > 0 {
+			pkgs = append(pkgs, l.checkFiles(lp.ImportPath+"_test", lp.Name+"_test", lp.Dir, lp.XTestGoFiles))
+		}
+	}
+	return l.fset, pkgs, nil
+}
+
+// listDeps patterns 的所有依赖（包括测试的依赖），带上导出数据文件
+func listDeps(cfg Config, patterns []string) ([]*listedPackage, error) {
+	flags := []string{"-deps", "-export", "-json=ImportPath,Name,Dir,GoFiles,Imports,Export,Error"}
+	if cfg.Tests {
+		flags = append(flags, "-test")
+	}
+	deps, err := goList(cfg.Dir, flags, patterns)
+	if err != nil {
+		return nil, err
+	}
+	// -test 时导入了被测包的依赖会为测试重新编译，输出为 "p [q.test]"，这些依赖都会从源码检查，按普通的导入路径处理
+	seen := make(map[string]bool, len(deps))
+	return slices.DeleteFunc(deps, func(lp *listedPackage) bool {
+		lp.ImportPath, _, _ = strings.Cut(lp.ImportPath, " ")
+		for i, path := range lp.Imports {
+			lp.Imports[i], _, _ = strings.Cut(path, " ")
+		}
+		if seen[lp.ImportPath] {
+			return true
+		}
+		seen[lp.ImportPath] = true
+		return false
+	}), nil
+}
+
+// goList 运行 go list -e，解析连续输出的 JSON 对象
+func goList(dir string, flags, patterns []string) ([]*listedPackage, error) {
+	args := append(append([]string{"list", "-e"}, flags...), patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var pkgs []*listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var lp listedPackage
+		if err := dec.Decode(&lp); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+		pkgs = append(pkgs, &lp)
+	}
+	return pkgs, nil
+}
+
+type loader struct {
+	fset     *token.FileSet
+	tests    bool
+	listed   map[string]*listedPackage
+	checked  map[string]*Package
+	checking map[string]bool
+	fallback types.ImporterFrom
+}
+
+// check 检查列出的包，同一个包只检查一次
+func (l *loader) check(path string) *Package {
+	if p, ok := l.checked[path]; ok {
+		return p
+	}
+	lp := l.listed[path]
+	files := lp.GoFiles
+	if l.tests {
+		files = append(files[:len(files):len(files)], lp.TestGoFiles...)
+	}
+	l.checking[path] = true
+	p := l.checkFiles(path, lp.Name, lp.Dir, files)
+	delete(l.checking, path)
+	if lp.Error != nil {
+		p.Errors = append([]error{errors.New(lp.Error.Err)}, p.Errors...)
+	}
+	l.checked[path] = p
+	return p
+}
+
+func (l *loader) checkFiles(path, name, dir string, filenames []string) *Package {
+	p := &Package{ImportPath: path, Name: name, Dir: dir}
+	for _, fn := range filenames {
+		f, err := parser.ParseFile(l.fset, filepath.Join(dir, fn), nil, parser.ParseComments)
+		if err != nil {
+			p.Errors = append(p.Errors, err)
+		}
+		if f != nil {
+			p.Files = append(p.Files, f)
+		}
+	}
+	p.Info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{
+		Importer: l,
+		Error:    func(err error) { p.Errors = append(p.Errors, err) },
+	}
+	p.Types, _ = conf.Check(path, l.fset, p.Files, p.Info)
+	return p
+}
+
+func (l *loader) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+// ImportFrom 实现 types.ImporterFrom，列出的包用这里检查的版本
+func (l *loader) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if _, ok := l.listed[path]; ok {
+		if l.checking[path] {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		return l.check(path).Types, nil
+	}
+	return l.fallback.ImportFrom(path, dir, mode)
+}
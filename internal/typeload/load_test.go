@@ -0,0 +1,122 @@
+package typeload
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTests(t *testing.T) {
+	for _, tests := range []bool{false, true} {
+		_, pkgs, err := Load(Config{Dir: "../..", Tests: tests}, "./demo11_interface")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pkgs) != 1 || len(pkgs[0].Errors) != 0 {
+			t.Fatalf("tests=%v: %d packages, errors %v", tests, len(pkgs), pkgs[0].Errors)
+		}
+		// IDuck 只在 interface_test.go 里声明
+		if got := pkgs[0].Types.Scope().Lookup("IDuck") != nil; got != tests {
+			t.Errorf("tests=%v: IDuck declared = %v", tests, got)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":     "module example.com/broken\n\ngo 1.24\n",
+		"broken.go":  "package broken\n\nvar X int = \"x\"\n\nfunc F() int { return 1 }\n",
+		"x_test.go":  "package broken_test\n\nimport \"example.com/broken\"\n\nvar _ = broken.F()\n",
+		"in_test.go": "package broken\n\nvar Y = F()\n",
+		"sub/sub.go": "package sub\n\nimport \"example.com/broken\"\n\nvar Z = broken.F\n",
+	})
+	_, pkgs, err := Load(Config{Dir: dir, Tests: true}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range pkgs {
+		paths = append(paths, p.ImportPath)
+	}
+	if strings.Join(paths, " ") != "example.com/broken example.com/broken_test example.com/broken/sub" {
+		t.Errorf("packages %v", paths)
+	}
+	// 类型错误记录下来，包的其余部分照常可用；其他包导入的是同一个 *types.Package
+	if len(pkgs[0].Errors) != 1 || pkgs[0].Types.Scope().Lookup("Y") == nil {
+		t.Errorf("errors %v", pkgs[0].Errors)
+	}
+	if imp := pkgs[2].Types.Imports(); len(imp) != 1 || imp[0] != pkgs[0].Types {
+		t.Errorf("sub imports %v", imp)
+	}
+	if _, _, err := Load(Config{Dir: filepath.Join(dir, "missing")}, "./..."); err == nil {
+		t.Error("missing dir: want error")
+	}
+}
+
+// TestLoadUnlistedDependency 没有列出的 b 导入了列出的 a，c 通过 b 拿到的 a.T 和直接检查的 a.T 是同一个类型
+func TestLoadUnlistedDependency(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":       "module example.com/m\n\ngo 1.23\n",
+		"a/a.go":       "package a\n\ntype T struct{}\n",
+		"b/b.go":       "package b\n\nimport \"example.com/m/a\"\n\nfunc New() a.T { return a.T{} }\n",
+		"c/c.go":       "package c\n\nimport \"example.com/m/b\"\n\nvar X = b.New()\n",
+		"c/c_test.go":  "package c_test\n\nimport \"example.com/m/b\"\n\nvar Y = b.New()\n",
+		"a/a_test.go":  "package a\n\nvar Z T\n",
+		"a/ax_test.go": "package a_test\n\nimport \"example.com/m/b\"\n\nvar W = b.New()\n",
+	})
+	for _, tests := range []bool{false, true} {
+		_, pkgs, err := Load(Config{Dir: dir, Tests: tests}, "./a", "./c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a types.Type
+		for _, p := range pkgs {
+			if len(p.Errors) != 0 {
+				t.Fatalf("tests=%v: %s: %v", tests, p.ImportPath, p.Errors)
+			}
+			if p.ImportPath == "example.com/m/a" {
+				a = p.Types.Scope().Lookup("T").Type()
+			}
+		}
+		for _, p := range pkgs {
+			for _, name := range []string{"X", "Y", "W"} {
+				if obj := p.Types.Scope().Lookup(name); obj != nil && !types.Identical(obj.Type(), a) {
+					t.Errorf("tests=%v: %s.%s has type %v from a different a", tests, p.Name, name, obj.Type())
+				}
+			}
+		}
+	}
+}
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// testdata/app 通过 replace 依赖主模块之外的 testdata/dep，依赖的导出数据来自 go list -export
+func TestLoadModuleDependency(t *testing.T) {
+	_, pkgs, err := Load(Config{Dir: filepath.Join("testdata", "app")}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) != 0 {
+		t.Fatalf("%d packages, errors %v", len(pkgs), pkgs[0].Errors)
+	}
+	imp := pkgs[0].Types.Imports()
+	if len(imp) != 1 || imp[0].Path() != "example.com/dep" {
+		t.Fatalf("imports %v", imp)
+	}
+	greeter := imp[0].Scope().Lookup("Greeter").Type().Underlying().(*types.Interface)
+	if english := pkgs[0].Types.Scope().Lookup("English").Type(); !types.Implements(english, greeter) {
+		t.Errorf("%v does not implement %v", english, greeter)
+	}
+}
@@ -0,0 +1,9 @@
+package app
+
+import "example.com/dep"
+
+type English struct{}
+
+func (English) Greet(name string) string { return "hello " + name }
+
+var _ dep.Greeter = English{}
@@ -0,0 +1,6 @@
+// Package dep 在主模块之外，app 通过 replace 引用它
+package dep
+
+type Greeter interface {
+	Greet(name string) string
+}